@@ -1,10 +1,36 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	_ "time/tzdata"
+
 	"github.com/azaviyalov/null3/backend/internal/app"
 )
 
+var commands = map[string]func(context.Context, []string, io.Writer) error{
+	"migrate": app.Migrate,
+	"backup":  app.Backup,
+	"restore": app.Restore,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(context.Background(), os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				if errors.Is(err, app.ErrMigrateUsage) || errors.Is(err, app.ErrBackupUsage) || errors.Is(err, app.ErrRestoreUsage) {
+					os.Exit(2)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	app := app.New()
 	app.Start()
 }
@@ -2,24 +2,35 @@ package app
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"strconv"
 
+	"github.com/azaviyalov/null3/backend/internal/core/backup"
 	"github.com/azaviyalov/null3/backend/internal/core/db"
 	"github.com/azaviyalov/null3/backend/internal/core/frontend"
+	"github.com/azaviyalov/null3/backend/internal/core/health"
 	"github.com/azaviyalov/null3/backend/internal/core/logging"
+	"github.com/azaviyalov/null3/backend/internal/core/metrics"
+	"github.com/azaviyalov/null3/backend/internal/core/ratelimit"
 	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
 	"github.com/azaviyalov/null3/backend/internal/domain/account"
 	"github.com/azaviyalov/null3/backend/internal/domain/admin"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/journal"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 type App struct {
+	database       *gorm.DB
+	shutdownTraces func(context.Context) error
 	sessionService *session.Service
+	journalService *journal.Service
 	echo           *echo.Echo
 	config         Config
 }
@@ -39,36 +50,54 @@ func New() *App {
 		os.Exit(1)
 	}
 
+	shutdownTraces, err := tracing.Setup(context.Background(), config.Tracing)
+	if err != nil {
+		slog.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
 	database, err := db.Connect(config.DB)
 	if err != nil {
 		slog.Error("failed to connect to database", "error", err)
 		os.Exit(1)
 	}
+	if err := database.Use(metrics.GormPlugin()); err != nil {
+		slog.Error("failed to register database metrics", "error", err)
+		os.Exit(1)
+	}
+	if err := database.Use(tracing.GormPlugin()); err != nil {
+		slog.Error("failed to register database tracing", "error", err)
+		os.Exit(1)
+	}
 
-	err = db.AutoMigrate(database,
-		&journal.MoodRecord{},
-		&journal.DiaryEntry{},
-		&account.User{},
-		&session.RefreshToken{},
-		&account.PasswordResetToken{},
-		&account.Invite{},
-	)
-	if err != nil {
-		slog.Error("database migration failed", "error", err)
+	if err := db.Migrate(context.Background(), database); err != nil {
+		if errors.Is(err, db.ErrSchemaTooNew) {
+			slog.Error("refusing to start against a newer database schema", "error", err)
+		} else {
+			slog.Error("database migration failed", "error", err)
+		}
 		os.Exit(1)
 	}
 
 	e := server.NewEchoServer(config.Server)
 
 	frontend.RegisterRoutes(e, config.Frontend)
+	health.RegisterRoutes(e, health.NewHandler(database, config.Frontend))
+	metrics.RegisterRoutes(e, config.Metrics)
+
+	auditService := audit.NewService(audit.NewRepository(database))
+	var limiter *ratelimit.Limiter
+	if config.RateLimit.Enabled {
+		limiter = ratelimit.New(ratelimit.NewMemoryStore(), config.RateLimit)
+	}
 
 	sessionRepository := session.NewRepository(database)
-	sessionService := session.NewService(sessionRepository, config.Session)
+	sessionService := session.NewService(sessionRepository, auditService, config.Session)
 
 	accountRepository := account.NewRepository(database)
-	accountService := account.NewService(accountRepository, sessionService, config.Account)
+	accountService := account.NewService(accountRepository, sessionService, auditService, limiter, config.Account)
 	accountHandler := account.NewHandler(accountService, sessionService, config.Account, config.Session)
-	adminService := admin.NewService(config.Admin.Password, sessionService)
+	adminService := admin.NewService(config.Admin.Password, sessionService, auditService, limiter)
 	adminHandler := admin.NewHandler(accountService, adminService, config.Admin, config.Session)
 
 	validateUser := func(ctx context.Context, userID uint) error {
@@ -83,13 +112,17 @@ func New() *App {
 	admin.RegisterRoutes(e, adminHandler, adminJWTMiddleware)
 
 	journalRepository := journal.NewRepository(database)
-	journalService := journal.NewService(journalRepository)
+	journalStorage := journal.NewLocalStorage(config.Journal.AttachmentDir)
+	journalService := journal.NewService(journalRepository, journalStorage, config.Journal)
 	journalHandler := journal.NewHandler(journalService)
 
 	journal.RegisterRoutes(e, journalHandler, userJWTMiddleware)
 
 	return &App{
+		database:       database,
+		shutdownTraces: shutdownTraces,
 		sessionService: sessionService,
+		journalService: journalService,
 		echo:           e,
 		config:         config,
 	}
@@ -101,7 +134,22 @@ func (a *App) Start() {
 		os.Exit(1)
 	}
 
-	if err := server.StartServer(a.echo, a.config.Server); err != nil {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.journalService.RunTrashExpiry(ctx)
+	if a.config.Backup.Interval > 0 {
+		if a.database.Dialector.Name() == db.DialectSQLite {
+			go backup.Run(ctx, a.database, a.config.Backup)
+		} else {
+			slog.Warn("scheduled backups are only supported for SQLite databases")
+		}
+	}
+
+	err := server.StartServer(a.echo, a.config.Server)
+	if shutdownErr := a.shutdownTraces(context.Background()); shutdownErr != nil {
+		slog.Warn("failed to flush traces", "error", shutdownErr)
+	}
+	if err != nil {
 		slog.Error("server stopped with an error", "error", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/azaviyalov/null3/backend/internal/core/backup"
+	"github.com/azaviyalov/null3/backend/internal/core/db"
+	"github.com/joho/godotenv"
+)
+
+var (
+	ErrBackupUsage  = errors.New("usage: server backup")
+	ErrRestoreUsage = errors.New("usage: server restore <backup file>")
+)
+
+func Backup(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) != 0 {
+		return ErrBackupUsage
+	}
+
+	_ = godotenv.Load()
+	dbConfig, err := db.GetConfig()
+	if err != nil {
+		return err
+	}
+	backupConfig, err := backup.GetConfig()
+	if err != nil {
+		return err
+	}
+	database, err := db.Connect(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer closeDatabase(database)
+
+	path, err := backup.Create(ctx, database, backupConfig)
+	if path != "" {
+		fmt.Fprintf(out, "created %s\n", path)
+	}
+	return err
+}
+
+func Restore(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return ErrRestoreUsage
+	}
+
+	_ = godotenv.Load()
+	dbConfig, err := db.GetConfig()
+	if err != nil {
+		return err
+	}
+	if err := backup.Restore(ctx, args[0], dbConfig); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "restored %s\n", args[0])
+	return nil
+}
@@ -0,0 +1,49 @@
+package app_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azaviyalov/null3/backend/internal/app"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestBackupAndRestoreCommands(t *testing.T) {
+	testutil.SkipIntegration(t)
+	dir := t.TempDir()
+	t.Setenv("DATABASE_URL", "file:"+filepath.Join(dir, "null3.sqlite")+"?_fk=1")
+	t.Setenv("BACKUP_DIR", filepath.Join(dir, "backups"))
+	t.Setenv("BACKUP_KEEP", "")
+	t.Setenv("BACKUP_COMPRESS", "")
+	t.Setenv("BACKUP_INTERVAL", "")
+
+	if err := app.Migrate(t.Context(), []string{"up"}, &strings.Builder{}); err != nil {
+		t.Fatalf("migrate up error = %v", err)
+	}
+
+	var out strings.Builder
+	if err := app.Backup(t.Context(), nil, &out); err != nil {
+		t.Fatalf("backup error = %v", err)
+	}
+	path, ok := strings.CutPrefix(strings.TrimSpace(out.String()), "created ")
+	if !ok || !strings.HasSuffix(path, ".db.gz") {
+		t.Fatalf("backup output = %q, want created <file>.db.gz", out.String())
+	}
+
+	out.Reset()
+	if err := app.Restore(t.Context(), []string{path}, &out); err != nil {
+		t.Fatalf("restore error = %v", err)
+	}
+	if want := "restored " + path; !strings.Contains(out.String(), want) {
+		t.Fatalf("restore output = %q, want %q", out.String(), want)
+	}
+
+	if err := app.Backup(t.Context(), []string{"extra"}, &strings.Builder{}); !errors.Is(err, app.ErrBackupUsage) {
+		t.Fatalf("backup extra error = %v, want ErrBackupUsage", err)
+	}
+	if err := app.Restore(t.Context(), nil, &strings.Builder{}); !errors.Is(err, app.ErrRestoreUsage) {
+		t.Fatalf("restore without file error = %v, want ErrRestoreUsage", err)
+	}
+}
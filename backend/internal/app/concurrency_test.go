@@ -0,0 +1,98 @@
+package app_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/domain/account"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/domain/session"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestConcurrentWritesDoNotLock(t *testing.T) {
+	testutil.SkipIntegration(t)
+	database := testutil.NewDatabase(t, "concurrency.sqlite")
+	ctx := t.Context()
+
+	auditService := audit.NewService(audit.NewRepository(database))
+	sessionService := session.NewService(session.NewRepository(database), auditService, session.Config{
+		JWTSecret:              "concurrency-test-signing-secret",
+		JWTExpiration:          time.Hour,
+		RefreshTokenExpiration: time.Hour,
+	})
+	accountRepository := account.NewRepository(database)
+	accountService := account.NewService(accountRepository, sessionService, auditService, nil, account.Config{PasswordResetTokenExpiration: time.Hour})
+	journalRepository := journal.NewRepository(database)
+
+	user, err := accountRepository.CreateUser(ctx, &account.User{Login: "writer", Email: "writer@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	shared, err := journalRepository.SaveDiaryEntry(ctx, &journal.DiaryEntry{UserID: user.ID, Title: "shared", Markdown: "v0", OccurredAt: time.Now()})
+	if err != nil {
+		t.Fatalf("create shared diary entry: %v", err)
+	}
+
+	const workers = 8
+	const iterations = 15
+	errs := make(chan error, workers*iterations*2)
+	var wg sync.WaitGroup
+	for worker := range workers {
+		token, err := sessionService.CreateRefreshToken(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("create refresh token: %v", err)
+		}
+		refreshToken := token.Value
+
+		wg.Go(func() {
+			for iteration := range iterations {
+				_, tokens, err := accountService.RefreshUserSession(ctx, refreshToken)
+				if err != nil {
+					errs <- fmt.Errorf("worker %d refresh %d: %w", worker, iteration, err)
+					return
+				}
+				refreshToken = tokens.RefreshToken.Value
+			}
+		})
+		wg.Go(func() {
+			for iteration := range iterations {
+				entry := &journal.DiaryEntry{
+					UserID:     user.ID,
+					Title:      fmt.Sprintf("worker %d", worker),
+					Markdown:   fmt.Sprintf("entry %d", iteration),
+					OccurredAt: time.Now(),
+				}
+				if iteration%2 == 1 {
+					latest, err := journalRepository.GetDiaryEntry(ctx, journal.NewDiaryEntryFilter().WithID(shared.ID))
+					if err != nil {
+						errs <- fmt.Errorf("worker %d load %d: %w", worker, iteration, err)
+						return
+					}
+					entry = latest
+					entry.Markdown = fmt.Sprintf("worker %d update %d", worker, iteration)
+				}
+				if _, err := journalRepository.SaveDiaryEntry(ctx, entry); err != nil {
+					errs <- fmt.Errorf("worker %d save %d: %w", worker, iteration, err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	revisions, err := journalRepository.CountDiaryEntryRevisions(ctx, shared.ID)
+	if err != nil {
+		t.Fatalf("count revisions: %v", err)
+	}
+	if want := int64(workers*(iterations/2) + 1); revisions != want {
+		t.Fatalf("shared entry revisions = %d, want %d", revisions, want)
+	}
+}
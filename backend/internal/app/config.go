@@ -1,21 +1,31 @@
 package app
 
 import (
+	"github.com/azaviyalov/null3/backend/internal/core/backup"
 	"github.com/azaviyalov/null3/backend/internal/core/db"
 	"github.com/azaviyalov/null3/backend/internal/core/frontend"
+	"github.com/azaviyalov/null3/backend/internal/core/metrics"
+	"github.com/azaviyalov/null3/backend/internal/core/ratelimit"
 	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
 	"github.com/azaviyalov/null3/backend/internal/domain/account"
 	"github.com/azaviyalov/null3/backend/internal/domain/admin"
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 )
 
 type Config struct {
-	Admin    admin.Config
-	Account  account.Config
-	DB       db.Config
-	Frontend frontend.Config
-	Session  session.Config
-	Server   server.Config
+	Admin     admin.Config
+	Account   account.Config
+	Backup    backup.Config
+	DB        db.Config
+	Frontend  frontend.Config
+	Journal   journal.Config
+	Metrics   metrics.Config
+	RateLimit ratelimit.Config
+	Session   session.Config
+	Server    server.Config
+	Tracing   tracing.Config
 }
 
 func GetConfig() (Config, error) {
@@ -29,18 +39,46 @@ func GetConfig() (Config, error) {
 		return Config{}, err
 	}
 
-	dbConfig := db.GetConfig()
+	dbConfig, err := db.GetConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
+	backupConfig, err := backup.GetConfig()
+	if err != nil {
+		return Config{}, err
+	}
 
 	frontendConfig, err := frontend.GetConfig()
 	if err != nil {
 		return Config{}, err
 	}
 
+	journalConfig, err := journal.GetConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
+	metricsConfig, err := metrics.GetConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
+	rateLimitConfig, err := ratelimit.GetConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
 	serverConfig, err := server.GetConfig()
 	if err != nil {
 		return Config{}, err
 	}
 
+	tracingConfig, err := tracing.GetConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
 	adminConfig, err := admin.GetConfig()
 	if err != nil {
 		return Config{}, err
@@ -49,11 +87,16 @@ func GetConfig() (Config, error) {
 	accountConfig.FrontendURL = serverConfig.FrontendURL
 
 	return Config{
-		Admin:    adminConfig,
-		Account:  accountConfig,
-		DB:       dbConfig,
-		Frontend: frontendConfig,
-		Session:  sessionConfig,
-		Server:   serverConfig,
+		Admin:     adminConfig,
+		Account:   accountConfig,
+		Backup:    backupConfig,
+		DB:        dbConfig,
+		Frontend:  frontendConfig,
+		Journal:   journalConfig,
+		Metrics:   metricsConfig,
+		RateLimit: rateLimitConfig,
+		Session:   sessionConfig,
+		Server:    serverConfig,
+		Tracing:   tracingConfig,
 	}, nil
 }
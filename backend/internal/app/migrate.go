@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core/db"
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+)
+
+var ErrMigrateUsage = errors.New("usage: server migrate up|down|status")
+
+func Migrate(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return ErrMigrateUsage
+	}
+
+	_ = godotenv.Load()
+	config, err := db.GetConfig()
+	if err != nil {
+		return err
+	}
+	database, err := db.Connect(config)
+	if err != nil {
+		return err
+	}
+	defer closeDatabase(database)
+
+	return runMigrate(ctx, database, args[0], out)
+}
+
+func runMigrate(ctx context.Context, database *gorm.DB, command string, out io.Writer) error {
+	migrations, err := db.Migrations(database.Dialector.Name())
+	if err != nil {
+		return err
+	}
+	migrator := db.NewMigrator(database, migrations)
+
+	switch command {
+	case "up":
+		ran, err := migrator.Up(ctx)
+		for _, migration := range ran {
+			fmt.Fprintf(out, "applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Fprintln(out, "database is up to date")
+		}
+	case "down":
+		migration, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if migration == nil {
+			fmt.Fprintln(out, "no migrations to revert")
+			return nil
+		}
+		fmt.Fprintf(out, "reverted %04d_%s\n", migration.Version, migration.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		if err := migrator.Check(ctx); err != nil {
+			return err
+		}
+	default:
+		return ErrMigrateUsage
+	}
+	return nil
+}
+
+func closeDatabase(database *gorm.DB) {
+	if sqlDB, err := database.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+}
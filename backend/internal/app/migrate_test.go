@@ -0,0 +1,47 @@
+package app_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azaviyalov/null3/backend/internal/app"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestMigrateCommand(t *testing.T) {
+	testutil.SkipIntegration(t)
+	t.Setenv("DATABASE_URL", "file:"+filepath.Join(t.TempDir(), "migrate.sqlite")+"?_fk=1")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"status"}, want: "0001_initial\tpending"},
+		{args: []string{"up"}, want: "applied 0005_journal_search"},
+		{args: []string{"up"}, want: "database is up to date"},
+		{args: []string{"status"}, want: "0005_journal_search\tapplied "},
+		{args: []string{"down"}, want: "reverted 0005_journal_search"},
+		{args: []string{"down"}, want: "reverted 0004_passkeys"},
+		{args: []string{"down"}, want: "reverted 0003_two_factor"},
+		{args: []string{"down"}, want: "reverted 0002_audit_events"},
+		{args: []string{"down"}, want: "reverted 0001_initial"},
+		{args: []string{"down"}, want: "no migrations to revert"},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		if err := app.Migrate(t.Context(), tt.args, &out); err != nil {
+			t.Fatalf("migrate %v error = %v", tt.args, err)
+		}
+		if !strings.Contains(out.String(), tt.want) {
+			t.Fatalf("migrate %v output = %q, want %q", tt.args, out.String(), tt.want)
+		}
+	}
+
+	for _, args := range [][]string{nil, {"sideways"}, {"up", "extra"}} {
+		if err := app.Migrate(t.Context(), args, &strings.Builder{}); !errors.Is(err, app.ErrMigrateUsage) {
+			t.Fatalf("migrate %v error = %v, want ErrMigrateUsage", args, err)
+		}
+	}
+}
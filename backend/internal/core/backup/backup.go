@@ -0,0 +1,275 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core/db"
+	"github.com/azaviyalov/null3/backend/internal/core/logging"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	filePrefix      = "null3-"
+	timestampLayout = "20060102T150405.000000Z"
+)
+
+var (
+	ErrUnsupportedDatabase = errors.New("backups are only supported for SQLite databases")
+	ErrInvalidBackup       = errors.New("invalid backup")
+	ErrDatabaseInUse       = errors.New("database is open in another process; stop the server before restoring")
+)
+
+func Create(ctx context.Context, database *gorm.DB, config Config) (string, error) {
+	if database.Dialector.Name() != db.DialectSQLite {
+		return "", ErrUnsupportedDatabase
+	}
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		return "", fmt.Errorf("create backup directory: %w", err)
+	}
+
+	name := filePrefix + time.Now().UTC().Format(timestampLayout) + ".db"
+	snapshot := filepath.Join(config.Dir, name+".tmp")
+	defer os.Remove(snapshot)
+	if err := database.WithContext(ctx).Exec("VACUUM INTO ?", snapshot).Error; err != nil {
+		return "", fmt.Errorf("snapshot database: %w", err)
+	}
+
+	path := filepath.Join(config.Dir, name)
+	if config.Compress {
+		path += ".gz"
+		if err := compressFile(snapshot, path); err != nil {
+			return "", err
+		}
+	} else if err := os.Rename(snapshot, path); err != nil {
+		return "", fmt.Errorf("store backup: %w", err)
+	}
+
+	if err := rotate(config.Dir, config.Keep); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+func Restore(ctx context.Context, source string, config db.Config) error {
+	dialect, err := db.Dialect(config.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	if dialect != db.DialectSQLite {
+		return ErrUnsupportedDatabase
+	}
+	target, err := db.SQLitePath(config.DatabaseURL)
+	if err != nil {
+		return err
+	}
+
+	staged := target + ".restore"
+	defer os.Remove(staged)
+	if err := extractFile(source, staged); err != nil {
+		return err
+	}
+	if err := verify(ctx, staged); err != nil {
+		return err
+	}
+	if err := checkNotInUse(ctx, target); err != nil {
+		return err
+	}
+	return swap(staged, target)
+}
+
+func Run(ctx context.Context, database *gorm.DB, config Config) {
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		path, err := Create(ctx, database, config)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			logging.FromContext(ctx).Error("scheduled database backup failed", "error", err)
+		case err == nil:
+			logging.FromContext(ctx).Info("created database backup", "path", path)
+		}
+	}
+}
+
+func verify(ctx context.Context, path string) error {
+	database, err := db.Connect(db.Config{DatabaseURL: "file:" + path + "?mode=ro&_fk=1"})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	database.Logger = logger.Default.LogMode(logger.Silent)
+	sqlDB, err := database.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	var result string
+	if err := database.WithContext(ctx).Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: integrity check failed: %s", ErrInvalidBackup, result)
+	}
+
+	migrations, err := db.Migrations(db.DialectSQLite)
+	if err != nil {
+		return err
+	}
+	migrator := db.NewMigrator(database, migrations)
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	applied := false
+	for _, status := range statuses {
+		applied = applied || status.AppliedAt != nil
+	}
+	if !applied {
+		return fmt.Errorf("%w: no schema migrations applied", ErrInvalidBackup)
+	}
+	return migrator.Check(ctx)
+}
+
+func checkNotInUse(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	database, err := db.Connect(db.Config{DatabaseURL: "file:" + path + "?_busy_timeout=0", MaxOpenConns: 1})
+	if err != nil {
+		return err
+	}
+	database.Logger = logger.Default.LogMode(logger.Silent)
+	sqlDB, err := database.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	database = database.WithContext(ctx)
+	if err := database.Exec("PRAGMA locking_mode = EXCLUSIVE").Error; err != nil {
+		return fmt.Errorf("lock current database: %w", err)
+	}
+	if err := database.Exec("BEGIN EXCLUSIVE").Error; err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseInUse, err)
+	}
+	return database.Exec("ROLLBACK").Error
+}
+
+func swap(staged, target string) error {
+	previous := target + ".before-restore"
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(target+suffix, previous+suffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("move current database aside: %w", err)
+		}
+	}
+	if err := os.Rename(staged, target); err != nil {
+		return fmt.Errorf("replace database: %w", err)
+	}
+	return nil
+}
+
+func rotate(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("list backups: %w", err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, filePrefix) && (strings.HasSuffix(name, ".db") || strings.HasSuffix(name, ".db.gz")) {
+			backups = append(backups, name)
+		}
+	}
+	for len(backups) > keep {
+		if err := os.Remove(filepath.Join(dir, backups[0])); err != nil {
+			return fmt.Errorf("remove old backup: %w", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+func compressFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+	defer in.Close()
+
+	return writeFile(target, func(out io.Writer) error {
+		writer := gzip.NewWriter(out)
+		if _, err := io.Copy(writer, in); err != nil {
+			return err
+		}
+		return writer.Close()
+	})
+}
+
+func extractFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("open backup: %w", err)
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	if strings.HasSuffix(source, ".gz") {
+		gzipReader, err := gzip.NewReader(in)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	return writeFile(target, func(out io.Writer) error {
+		_, err := io.Copy(out, reader)
+		return err
+	})
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	tmp := path + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp)
+
+	if err := write(out); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package backup_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core/backup"
+	"github.com/azaviyalov/null3/backend/internal/core/db"
+	"github.com/azaviyalov/null3/backend/internal/domain/account"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestGetConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		clearBackupEnv(t)
+
+		config, err := backup.GetConfig()
+		if err != nil {
+			t.Fatalf("GetConfig() error = %v", err)
+		}
+		want := backup.Config{Dir: "backups", Keep: 7, Compress: true}
+		if config != want {
+			t.Fatalf("GetConfig() = %+v, want %+v", config, want)
+		}
+	})
+
+	t.Run("environment overrides", func(t *testing.T) {
+		clearBackupEnv(t)
+		t.Setenv("BACKUP_DIR", "/var/backups/null3")
+		t.Setenv("BACKUP_KEEP", "3")
+		t.Setenv("BACKUP_COMPRESS", "false")
+		t.Setenv("BACKUP_INTERVAL", "6h")
+
+		config, err := backup.GetConfig()
+		if err != nil {
+			t.Fatalf("GetConfig() error = %v", err)
+		}
+		want := backup.Config{Dir: "/var/backups/null3", Keep: 3, Interval: 6 * time.Hour}
+		if config != want {
+			t.Fatalf("GetConfig() = %+v, want %+v", config, want)
+		}
+	})
+
+	for name, value := range map[string]string{
+		"BACKUP_KEEP":     "0",
+		"BACKUP_COMPRESS": "sometimes",
+		"BACKUP_INTERVAL": "-1h",
+	} {
+		t.Run("invalid "+name, func(t *testing.T) {
+			clearBackupEnv(t)
+			t.Setenv(name, value)
+
+			if _, err := backup.GetConfig(); err == nil || !strings.Contains(err.Error(), name) {
+				t.Fatalf("GetConfig() error = %v, want error mentioning %s", err, name)
+			}
+		})
+	}
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	testutil.SkipIntegration(t)
+	dir := t.TempDir()
+	dbConfig := db.Config{DatabaseURL: "file:" + filepath.Join(dir, "null3.db") + "?_fk=1", SQLiteJournalMode: "WAL"}
+	backupConfig := backup.Config{Dir: filepath.Join(dir, "backups"), Keep: 7, Compress: true}
+
+	database := openDatabase(t, dbConfig)
+	createUser(t, database, "kept")
+	path, err := backup.Create(t.Context(), database, backupConfig)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.HasSuffix(path, ".db.gz") {
+		t.Fatalf("Create() path = %q, want a .db.gz file", path)
+	}
+	createUser(t, database, "dropped")
+	if err := backup.Restore(t.Context(), path, dbConfig); !errors.Is(err, backup.ErrDatabaseInUse) {
+		t.Fatalf("Restore() while the database is open error = %v, want ErrDatabaseInUse", err)
+	}
+	closeDatabase(t, database)
+
+	if err := backup.Restore(t.Context(), path, dbConfig); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	database = openDatabase(t, dbConfig)
+	var logins []string
+	if err := database.Model(&account.User{}).Order("login").Pluck("login", &logins).Error; err != nil {
+		t.Fatalf("list users: %v", err)
+	}
+	if len(logins) != 1 || logins[0] != "kept" {
+		t.Fatalf("restored users = %v, want [kept]", logins)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "null3.db.before-restore")); err != nil {
+		t.Fatalf("previous database was not kept: %v", err)
+	}
+}
+
+func TestCreateRotatesBackups(t *testing.T) {
+	testutil.SkipIntegration(t)
+	dir := t.TempDir()
+	database := openDatabase(t, db.Config{DatabaseURL: "file:" + filepath.Join(dir, "null3.db") + "?_fk=1"})
+	backupConfig := backup.Config{Dir: filepath.Join(dir, "backups"), Keep: 2}
+
+	var paths []string
+	for range 4 {
+		path, err := backup.Create(t.Context(), database, backupConfig)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	entries, err := os.ReadDir(backupConfig.Dir)
+	if err != nil {
+		t.Fatalf("read backup directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{filepath.Base(paths[2]), filepath.Base(paths[3])}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("backups = %v, want %v", names, want)
+	}
+}
+
+func TestRestoreRejectsInvalidBackups(t *testing.T) {
+	testutil.SkipIntegration(t)
+	dir := t.TempDir()
+	dbConfig := db.Config{DatabaseURL: "file:" + filepath.Join(dir, "null3.db") + "?_fk=1"}
+	database := openDatabase(t, dbConfig)
+	createUser(t, database, "current")
+	closeDatabase(t, database)
+
+	garbage := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte(strings.Repeat("not a database ", 512)), 0o600); err != nil {
+		t.Fatalf("write garbage backup: %v", err)
+	}
+	if err := backup.Restore(t.Context(), garbage, dbConfig); !errors.Is(err, backup.ErrInvalidBackup) {
+		t.Fatalf("Restore(garbage) error = %v, want ErrInvalidBackup", err)
+	}
+
+	newer := filepath.Join(dir, "newer.db")
+	newerDatabase := openDatabase(t, db.Config{DatabaseURL: "file:" + newer + "?_fk=1"})
+	if err := newerDatabase.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'future', CURRENT_TIMESTAMP)").Error; err != nil {
+		t.Fatalf("record future migration: %v", err)
+	}
+	closeDatabase(t, newerDatabase)
+	if err := backup.Restore(t.Context(), newer, dbConfig); !errors.Is(err, db.ErrSchemaTooNew) {
+		t.Fatalf("Restore(newer) error = %v, want ErrSchemaTooNew", err)
+	}
+
+	database = openDatabase(t, dbConfig)
+	var count int64
+	if err := database.Model(&account.User{}).Where("login = ?", "current").Count(&count).Error; err != nil || count != 1 {
+		t.Fatalf("current database changed after rejected restore: count = %d, err = %v", count, err)
+	}
+}
+
+func TestRejectsPostgres(t *testing.T) {
+	config := db.Config{DatabaseURL: "postgres://localhost/null3"}
+	if err := backup.Restore(t.Context(), "backup.db", config); !errors.Is(err, backup.ErrUnsupportedDatabase) {
+		t.Fatalf("Restore() error = %v, want ErrUnsupportedDatabase", err)
+	}
+}
+
+func openDatabase(t *testing.T, config db.Config) *gorm.DB {
+	t.Helper()
+
+	database, err := db.Connect(config)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	database.Logger = logger.Default.LogMode(logger.Silent)
+	if err := db.Migrate(t.Context(), database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() { closeDatabase(t, database) })
+	return database
+}
+
+func closeDatabase(t *testing.T, database *gorm.DB) {
+	t.Helper()
+
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatalf("get SQL database: %v", err)
+	}
+	_ = sqlDB.Close()
+}
+
+func createUser(t *testing.T, database *gorm.DB, login string) {
+	t.Helper()
+
+	user := &account.User{Login: login, Email: login + "@example.com", PasswordHash: "hash"}
+	if _, err := account.NewRepository(database).CreateUser(t.Context(), user); err != nil {
+		t.Fatalf("create user %s: %v", login, err)
+	}
+}
+
+func clearBackupEnv(t *testing.T) {
+	t.Helper()
+
+	for _, name := range []string{"BACKUP_DIR", "BACKUP_KEEP", "BACKUP_COMPRESS", "BACKUP_INTERVAL"} {
+		t.Setenv(name, "")
+	}
+}
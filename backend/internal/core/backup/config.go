@@ -0,0 +1,59 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	Dir      string
+	Keep     int
+	Compress bool
+	Interval time.Duration
+}
+
+func GetConfig() (Config, error) {
+	config := Config{
+		Dir:      "backups",
+		Keep:     7,
+		Compress: true,
+	}
+
+	if dir := os.Getenv("BACKUP_DIR"); dir != "" {
+		config.Dir = dir
+	}
+
+	if keepParam := os.Getenv("BACKUP_KEEP"); keepParam != "" {
+		keep, err := strconv.Atoi(keepParam)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse BACKUP_KEEP: %w", err)
+		}
+		if keep <= 0 {
+			return Config{}, fmt.Errorf("BACKUP_KEEP must be a positive number")
+		}
+		config.Keep = keep
+	}
+
+	if compressParam := os.Getenv("BACKUP_COMPRESS"); compressParam != "" {
+		compress, err := strconv.ParseBool(compressParam)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse BACKUP_COMPRESS: %w", err)
+		}
+		config.Compress = compress
+	}
+
+	if intervalParam := os.Getenv("BACKUP_INTERVAL"); intervalParam != "" {
+		interval, err := time.ParseDuration(intervalParam)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse BACKUP_INTERVAL: %w", err)
+		}
+		if interval <= 0 {
+			return Config{}, fmt.Errorf("BACKUP_INTERVAL must be a positive duration")
+		}
+		config.Interval = interval
+	}
+
+	return config, nil
+}
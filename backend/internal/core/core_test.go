@@ -45,3 +45,31 @@ func TestPageJSONContract(t *testing.T) {
 		t.Fatalf("page JSON = %s, want %s", data, want)
 	}
 }
+
+func TestPositiveIntEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "unset uses fallback", value: "", want: 5},
+		{name: "positive value", value: "12", want: 12},
+		{name: "zero", value: "0", wantErr: true},
+		{name: "negative", value: "-3", wantErr: true},
+		{name: "not a number", value: "many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CORE_TEST_COUNT", tt.value)
+			got, err := core.PositiveIntEnv("CORE_TEST_COUNT", 5)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PositiveIntEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("PositiveIntEnv() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type StatementHook func(db *gorm.DB, operation string)
+
+type callbackRegistrar interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+func RegisterStatementHooks(database *gorm.DB, name string, before, after StatementHook) error {
+	callbacks := database.Callback()
+	operations := []struct {
+		name   string
+		before callbackRegistrar
+		after  callbackRegistrar
+	}{
+		{"create", callbacks.Create().Before("gorm:create"), callbacks.Create().After("gorm:create")},
+		{"query", callbacks.Query().Before("gorm:query"), callbacks.Query().After("gorm:query")},
+		{"update", callbacks.Update().Before("gorm:update"), callbacks.Update().After("gorm:update")},
+		{"delete", callbacks.Delete().Before("gorm:delete"), callbacks.Delete().After("gorm:delete")},
+		{"row", callbacks.Row().Before("gorm:row"), callbacks.Row().After("gorm:row")},
+		{"raw", callbacks.Raw().Before("gorm:raw"), callbacks.Raw().After("gorm:raw")},
+	}
+
+	for _, operation := range operations {
+		err := operation.before.Register(fmt.Sprintf("%s:before_%s", name, operation.name), func(db *gorm.DB) {
+			before(db, operation.name)
+		})
+		if err != nil {
+			return fmt.Errorf("register %s callbacks: %w", name, err)
+		}
+		err = operation.after.Register(fmt.Sprintf("%s:after_%s", name, operation.name), func(db *gorm.DB) {
+			after(db, operation.name)
+		})
+		if err != nil {
+			return fmt.Errorf("register %s callbacks: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -1,16 +1,94 @@
 package db
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+)
+
+var (
+	sqliteJournalModes = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+	sqliteSynchronous  = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
+)
 
 type Config struct {
-	DatabaseURL string
+	DatabaseURL       string
+	MaxOpenConns      int
+	MaxIdleConns      int
+	ConnMaxLifetime   time.Duration
+	SQLiteJournalMode string
+	SQLiteBusyTimeout time.Duration
+	SQLiteSynchronous string
 }
 
-func GetConfig() Config {
-	config := Config{DatabaseURL: "file:null3.db?_fk=1"}
+func GetConfig() (Config, error) {
+	config := Config{
+		DatabaseURL:       "file:null3.db?_fk=1",
+		MaxOpenConns:      10,
+		MaxIdleConns:      5,
+		ConnMaxLifetime:   time.Hour,
+		SQLiteJournalMode: "WAL",
+		SQLiteBusyTimeout: 5 * time.Second,
+		SQLiteSynchronous: "NORMAL",
+	}
 
 	if dbURL := os.Getenv("DATABASE_URL"); dbURL != "" {
 		config.DatabaseURL = dbURL
 	}
-	return config
+
+	maxOpenConns, err := core.PositiveIntEnv("DATABASE_MAX_OPEN_CONNS", config.MaxOpenConns)
+	if err != nil {
+		return Config{}, err
+	}
+	config.MaxOpenConns = maxOpenConns
+
+	maxIdleConns, err := core.PositiveIntEnv("DATABASE_MAX_IDLE_CONNS", config.MaxIdleConns)
+	if err != nil {
+		return Config{}, err
+	}
+	config.MaxIdleConns = min(maxIdleConns, config.MaxOpenConns)
+
+	if lifetimeParam := os.Getenv("DATABASE_CONN_MAX_LIFETIME"); lifetimeParam != "" {
+		lifetime, err := time.ParseDuration(lifetimeParam)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse DATABASE_CONN_MAX_LIFETIME: %w", err)
+		}
+		if lifetime <= 0 {
+			return Config{}, fmt.Errorf("DATABASE_CONN_MAX_LIFETIME must be a positive duration")
+		}
+		config.ConnMaxLifetime = lifetime
+	}
+
+	if journalMode := os.Getenv("SQLITE_JOURNAL_MODE"); journalMode != "" {
+		journalMode = strings.ToUpper(journalMode)
+		if !slices.Contains(sqliteJournalModes, journalMode) {
+			return Config{}, fmt.Errorf("SQLITE_JOURNAL_MODE must be one of %s", strings.Join(sqliteJournalModes, ", "))
+		}
+		config.SQLiteJournalMode = journalMode
+	}
+
+	if busyTimeoutParam := os.Getenv("SQLITE_BUSY_TIMEOUT"); busyTimeoutParam != "" {
+		busyTimeout, err := time.ParseDuration(busyTimeoutParam)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SQLITE_BUSY_TIMEOUT: %w", err)
+		}
+		if busyTimeout <= 0 {
+			return Config{}, fmt.Errorf("SQLITE_BUSY_TIMEOUT must be a positive duration")
+		}
+		config.SQLiteBusyTimeout = busyTimeout
+	}
+
+	if synchronous := os.Getenv("SQLITE_SYNCHRONOUS"); synchronous != "" {
+		synchronous = strings.ToUpper(synchronous)
+		if !slices.Contains(sqliteSynchronous, synchronous) {
+			return Config{}, fmt.Errorf("SQLITE_SYNCHRONOUS must be one of %s", strings.Join(sqliteSynchronous, ", "))
+		}
+		config.SQLiteSynchronous = synchronous
+	}
+
+	return config, nil
 }
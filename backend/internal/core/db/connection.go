@@ -1,16 +1,146 @@
 package db
 
 import (
+	"context"
 	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+var urlSchemePattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]+):`)
+
+func Dialect(databaseURL string) (string, error) {
+	match := urlSchemePattern.FindStringSubmatch(databaseURL)
+	if match == nil {
+		return DialectSQLite, nil
+	}
+	switch strings.ToLower(match[1]) {
+	case "file":
+		return DialectSQLite, nil
+	case "postgres", "postgresql":
+		return DialectPostgres, nil
+	default:
+		return "", fmt.Errorf("unsupported database URL scheme %q", match[1])
+	}
+}
+
+func SQLitePath(databaseURL string) (string, error) {
+	dialect, err := Dialect(databaseURL)
+	if err != nil {
+		return "", err
+	}
+	if dialect != DialectSQLite {
+		return "", fmt.Errorf("database URL is not a SQLite database")
+	}
+	path, _, _ := strings.Cut(databaseURL, "?")
+	path = strings.TrimPrefix(path, "file:")
+	if path == "" || path == ":memory:" {
+		return "", fmt.Errorf("SQLite database %q is not a file", databaseURL)
+	}
+	return path, nil
+}
+
 func Connect(config Config) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(config.DatabaseURL), &gorm.Config{TranslateError: true})
+	dialector, err := openDialector(config)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		TranslateError: true,
+		NowFunc:        utcNow,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("connect to database: %w", err)
 	}
+	if config.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
 	return db, nil
 }
+
+func utcNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func openDialector(config Config) (gorm.Dialector, error) {
+	dialect, err := Dialect(config.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if dialect == DialectSQLite {
+		dsn, err := sqliteDSN(config)
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.Open(dsn), nil
+	}
+
+	pgConfig, err := pgx.ParseConfig(config.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB := stdlib.OpenDB(*pgConfig, stdlib.OptionAfterConnect(func(_ context.Context, conn *pgx.Conn) error {
+		conn.TypeMap().RegisterType(&pgtype.Type{
+			Name:  "timestamptz",
+			OID:   pgtype.TimestamptzOID,
+			Codec: &pgtype.TimestamptzCodec{ScanLocation: time.UTC},
+		})
+		return nil
+	}))
+	return postgres.New(postgres.Config{Conn: sqlDB}), nil
+}
+
+func sqliteDSN(config Config) (string, error) {
+	path, rawQuery, _ := strings.Cut(config.DatabaseURL, "?")
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("parse SQLite parameters: %w", err)
+	}
+
+	setDefault := func(value string, names ...string) {
+		if value == "" {
+			return
+		}
+		for _, name := range names {
+			if params.Has(name) {
+				return
+			}
+		}
+		params.Set(names[0], value)
+	}
+	setDefault(config.SQLiteJournalMode, "_journal_mode", "_journal")
+	setDefault(config.SQLiteSynchronous, "_synchronous", "_sync")
+	if config.SQLiteBusyTimeout > 0 {
+		setDefault(strconv.FormatInt(config.SQLiteBusyTimeout.Milliseconds(), 10), "_busy_timeout", "_timeout")
+	}
+	setDefault("immediate", "_txlock")
+
+	return path + "?" + params.Encode(), nil
+}
@@ -5,34 +5,205 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/azaviyalov/null3/backend/internal/core/db"
+	"github.com/azaviyalov/null3/backend/internal/domain/account"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"github.com/azaviyalov/null3/backend/internal/testutil"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 func TestGetConfig(t *testing.T) {
-	t.Run("default database URL", func(t *testing.T) {
-		t.Setenv("DATABASE_URL", "")
+	clearDatabaseEnv := func(t *testing.T) {
+		for _, name := range []string{
+			"DATABASE_URL",
+			"DATABASE_MAX_OPEN_CONNS",
+			"DATABASE_MAX_IDLE_CONNS",
+			"DATABASE_CONN_MAX_LIFETIME",
+			"SQLITE_JOURNAL_MODE",
+			"SQLITE_BUSY_TIMEOUT",
+			"SQLITE_SYNCHRONOUS",
+		} {
+			t.Setenv(name, "")
+		}
+	}
 
-		got := db.GetConfig()
+	t.Run("defaults", func(t *testing.T) {
+		clearDatabaseEnv(t)
 
-		const want = "file:null3.db?_fk=1"
-		if got.DatabaseURL != want {
-			t.Fatalf("DatabaseURL = %q, want %q", got.DatabaseURL, want)
+		got, err := db.GetConfig()
+
+		want := db.Config{
+			DatabaseURL:       "file:null3.db?_fk=1",
+			MaxOpenConns:      10,
+			MaxIdleConns:      5,
+			ConnMaxLifetime:   time.Hour,
+			SQLiteJournalMode: "WAL",
+			SQLiteBusyTimeout: 5 * time.Second,
+			SQLiteSynchronous: "NORMAL",
+		}
+		if err != nil || got != want {
+			t.Fatalf("GetConfig() = %+v, %v, want %+v", got, err, want)
 		}
 	})
 
 	t.Run("environment override", func(t *testing.T) {
-		const want = "file:custom.db?_fk=1"
-		t.Setenv("DATABASE_URL", want)
+		clearDatabaseEnv(t)
+		t.Setenv("DATABASE_URL", "file:custom.db?_fk=1")
+		t.Setenv("DATABASE_MAX_OPEN_CONNS", "4")
+		t.Setenv("DATABASE_MAX_IDLE_CONNS", "8")
+		t.Setenv("DATABASE_CONN_MAX_LIFETIME", "15m")
+		t.Setenv("SQLITE_JOURNAL_MODE", "delete")
+		t.Setenv("SQLITE_BUSY_TIMEOUT", "250ms")
+		t.Setenv("SQLITE_SYNCHRONOUS", "full")
 
-		got := db.GetConfig()
+		got, err := db.GetConfig()
 
-		if got.DatabaseURL != want {
-			t.Fatalf("DatabaseURL = %q, want %q", got.DatabaseURL, want)
+		want := db.Config{
+			DatabaseURL:       "file:custom.db?_fk=1",
+			MaxOpenConns:      4,
+			MaxIdleConns:      4,
+			ConnMaxLifetime:   15 * time.Minute,
+			SQLiteJournalMode: "DELETE",
+			SQLiteBusyTimeout: 250 * time.Millisecond,
+			SQLiteSynchronous: "FULL",
+		}
+		if err != nil || got != want {
+			t.Fatalf("GetConfig() = %+v, %v, want %+v", got, err, want)
 		}
 	})
+
+	invalid := []struct {
+		name  string
+		value string
+	}{
+		{name: "DATABASE_MAX_OPEN_CONNS", value: "0"},
+		{name: "DATABASE_MAX_IDLE_CONNS", value: "many"},
+		{name: "DATABASE_CONN_MAX_LIFETIME", value: "-1s"},
+		{name: "SQLITE_JOURNAL_MODE", value: "fast"},
+		{name: "SQLITE_BUSY_TIMEOUT", value: "soon"},
+		{name: "SQLITE_SYNCHRONOUS", value: "sometimes"},
+	}
+	for _, tt := range invalid {
+		t.Run("invalid "+tt.name, func(t *testing.T) {
+			clearDatabaseEnv(t)
+			t.Setenv(tt.name, tt.value)
+
+			if _, err := db.GetConfig(); err == nil || !strings.Contains(err.Error(), tt.name) {
+				t.Fatalf("GetConfig() error = %v, want an error about %s", err, tt.name)
+			}
+		})
+	}
+}
+
+func TestConnectAppliesSQLiteSettings(t *testing.T) {
+	testutil.SkipIntegration(t)
+	config := db.Config{
+		DatabaseURL:       "file:" + filepath.Join(t.TempDir(), "settings.sqlite") + "?_fk=1",
+		MaxOpenConns:      3,
+		MaxIdleConns:      2,
+		ConnMaxLifetime:   time.Minute,
+		SQLiteJournalMode: "WAL",
+		SQLiteBusyTimeout: 1500 * time.Millisecond,
+		SQLiteSynchronous: "NORMAL",
+	}
+	database, err := db.Connect(config)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatalf("get SQL database: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	if got := sqlDB.Stats().MaxOpenConnections; got != config.MaxOpenConns {
+		t.Errorf("MaxOpenConnections = %d, want %d", got, config.MaxOpenConns)
+	}
+	pragmas := []struct {
+		name string
+		want string
+	}{
+		{name: "journal_mode", want: "wal"},
+		{name: "busy_timeout", want: "1500"},
+		{name: "synchronous", want: "1"},
+		{name: "foreign_keys", want: "1"},
+	}
+	for _, pragma := range pragmas {
+		var got string
+		if err := database.Raw("PRAGMA " + pragma.name).Scan(&got).Error; err != nil {
+			t.Fatalf("read %s: %v", pragma.name, err)
+		}
+		if got != pragma.want {
+			t.Errorf("PRAGMA %s = %q, want %q", pragma.name, got, pragma.want)
+		}
+	}
+}
+
+func TestConnectStoresTimestampsInUTC(t *testing.T) {
+	testutil.SkipIntegration(t)
+	local := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	type stamped struct {
+		ID        uint
+		CreatedAt time.Time
+	}
+	database := openTestDatabase(t)
+	if err := database.AutoMigrate(&stamped{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+	if err := database.Create(&stamped{}).Error; err != nil {
+		t.Fatalf("create row: %v", err)
+	}
+
+	var stored string
+	if err := database.Raw("SELECT CAST(created_at AS text) FROM stampeds").Scan(&stored).Error; err != nil {
+		t.Fatalf("read created_at: %v", err)
+	}
+	if !strings.HasSuffix(stored, "+00:00") {
+		t.Fatalf("stored created_at = %q, want UTC offset", stored)
+	}
+
+	var count int64
+	from := time.Now().Add(-time.Minute).UTC()
+	if err := database.Model(&stamped{}).Where("created_at >= ?", from).Count(&count).Error; err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("rows created after %v = %d, want 1", from, count)
+	}
+}
+
+func TestDialect(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{url: "file:null3.db?_fk=1", want: db.DialectSQLite},
+		{url: "null3.db", want: db.DialectSQLite},
+		{url: ":memory:", want: db.DialectSQLite},
+		{url: "postgres://null3@localhost/null3", want: db.DialectPostgres},
+		{url: "postgresql://null3@localhost/null3?sslmode=disable", want: db.DialectPostgres},
+		{url: "mysql://null3@localhost/null3", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := db.Dialect(tt.url)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Dialect(%q) = %q, %v, want %q", tt.url, got, err, tt.want)
+		}
+	}
+
+	if _, err := db.Connect(db.Config{DatabaseURL: "mysql://null3@localhost/null3"}); err == nil {
+		t.Fatal("Connect() with an unsupported scheme error = nil, want an error")
+	}
 }
 
 func TestConnectWrapsOpenError(t *testing.T) {
@@ -52,46 +223,256 @@ func TestConnectWrapsOpenError(t *testing.T) {
 	}
 }
 
-func TestAutoMigrate(t *testing.T) {
+func TestMigrateMatchesModels(t *testing.T) {
 	testutil.SkipIntegration(t)
-	t.Run("creates model table", func(t *testing.T) {
-		database := openTestDatabase(t)
+	migrated := openTestDatabase(t)
+	if err := db.Migrate(t.Context(), migrated); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if err := db.Migrate(t.Context(), migrated); err != nil {
+		t.Fatalf("second Migrate() error = %v", err)
+	}
 
-		if err := db.AutoMigrate(database, &migrationRecord{}); err != nil {
-			t.Fatalf("AutoMigrate() error = %v", err)
-		}
-		if !database.Migrator().HasTable(&migrationRecord{}) {
-			t.Fatal("AutoMigrate() did not create the model table")
+	models := openTestDatabase(t)
+	err := models.AutoMigrate(
+		&journal.Tag{},
+		&journal.MoodRecord{},
+		&journal.DiaryEntry{},
+		&journal.DiaryEntryRevision{},
+		&journal.Attachment{},
+		&account.User{},
+		&session.RefreshToken{},
+		&account.PasswordResetToken{},
+		&account.Invite{},
+		&account.RecoveryCode{},
+		&account.Passkey{},
+		&account.PasskeyChallenge{},
+		&audit.Event{},
+	)
+	if err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+
+	migrations, err := db.Migrations(db.DialectSQLite)
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	for _, migration := range migrations {
+		if migration.Name == "journal_search" {
+			if err := models.Exec(migration.Up).Error; err != nil {
+				t.Fatalf("create search index: %v", err)
+			}
 		}
-	})
+	}
 
-	t.Run("wraps migration error", func(t *testing.T) {
-		database := openTestDatabase(t)
-		sqlDB, err := database.DB()
-		if err != nil {
-			t.Fatalf("get SQL database: %v", err)
+	want := schemaOf(t, models)
+	if _, err := db.NewMigrator(models, migrations[:1]).Up(t.Context()); err != nil {
+		t.Fatalf("initial migration on an existing schema error = %v", err)
+	}
+	got := schemaOf(t, migrated)
+	for name, definition := range want {
+		if got[name] != definition {
+			t.Errorf("%s = %q, want %q", name, got[name], definition)
 		}
-		if err := sqlDB.Close(); err != nil {
-			t.Fatalf("close SQL database: %v", err)
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok && name != "table schema_migrations" {
+			t.Errorf("unexpected %s in migrated schema", name)
 		}
+	}
+}
 
-		err = db.AutoMigrate(database, &migrationRecord{})
+func TestMigratorUpDownStatus(t *testing.T) {
+	testutil.SkipIntegration(t)
+	database := openTestDatabase(t)
+	migrations := loadTestMigrations(t)
+	migrator := db.NewMigrator(database, migrations)
 
-		if err == nil {
-			t.Fatal("AutoMigrate() error = nil, want an error")
-		}
-		if !strings.Contains(err.Error(), "migrate database") {
-			t.Fatalf("AutoMigrate() error = %q, want migration context", err)
-		}
-		if errors.Unwrap(err) == nil {
-			t.Fatal("AutoMigrate() error does not retain its cause")
-		}
-	})
+	statuses, err := migrator.Status(t.Context())
+	if err != nil || len(statuses) != 2 || statuses[0].AppliedAt != nil {
+		t.Fatalf("Status() before Up = %+v, %v, want both migrations pending", statuses, err)
+	}
+	if database.Migrator().HasTable("schema_migrations") {
+		t.Fatal("Status() created the schema_migrations table")
+	}
+
+	ran, err := migrator.Up(t.Context())
+	if err != nil || len(ran) != 2 {
+		t.Fatalf("Up() = %d migrations, %v, want 2", len(ran), err)
+	}
+	if err := database.Exec("INSERT INTO notes (body, pinned) VALUES ('kept', 1)").Error; err != nil {
+		t.Fatalf("use migrated table: %v", err)
+	}
+
+	reverted, err := migrator.Down(t.Context())
+	if err != nil || reverted == nil || reverted.Version != 2 {
+		t.Fatalf("Down() = %+v, %v, want version 2", reverted, err)
+	}
+	if database.Migrator().HasColumn("notes", "pinned") {
+		t.Fatal("Down() kept the pinned column")
+	}
+	statuses, err = migrator.Status(t.Context())
+	if err != nil || len(statuses) != 2 || statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Fatalf("Status() = %+v, %v, want the first migration applied only", statuses, err)
+	}
+
+	if _, err := migrator.Down(t.Context()); err != nil {
+		t.Fatalf("second Down() error = %v", err)
+	}
+	if reverted, err := migrator.Down(t.Context()); err != nil || reverted != nil {
+		t.Fatalf("Down() on an empty schema = %+v, %v, want nothing", reverted, err)
+	}
+}
+
+func TestMigratorRollsBackFailedMigration(t *testing.T) {
+	testutil.SkipIntegration(t)
+	database := openTestDatabase(t)
+	migrations := loadTestMigrations(t)
+	migrations[1].Up = "ALTER TABLE notes ADD COLUMN pinned integer; INSERT INTO missing VALUES (1);"
+
+	ran, err := db.NewMigrator(database, migrations).Up(t.Context())
+	if err == nil || len(ran) != 1 {
+		t.Fatalf("Up() = %d migrations, %v, want the second one to fail", len(ran), err)
+	}
+	if database.Migrator().HasColumn("notes", "pinned") {
+		t.Fatal("failed migration was partially applied")
+	}
+}
+
+func TestMigratorRefusesNewerSchema(t *testing.T) {
+	testutil.SkipIntegration(t)
+	database := openTestDatabase(t)
+	migrations := loadTestMigrations(t)
+	if _, err := db.NewMigrator(database, migrations).Up(t.Context()); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	older := db.NewMigrator(database, migrations[:1])
+	if err := older.Check(t.Context()); !errors.Is(err, db.ErrSchemaTooNew) {
+		t.Fatalf("Check() error = %v, want ErrSchemaTooNew", err)
+	}
+	if _, err := older.Up(t.Context()); !errors.Is(err, db.ErrSchemaTooNew) {
+		t.Fatalf("Up() error = %v, want ErrSchemaTooNew", err)
+	}
+	if _, err := older.Down(t.Context()); !errors.Is(err, db.ErrSchemaTooNew) {
+		t.Fatalf("Down() error = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestSearchMigrationIndexesExistingRecords(t *testing.T) {
+	testutil.SkipIntegration(t)
+	database := openTestDatabase(t)
+	migrations, err := db.Migrations(db.DialectSQLite)
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	search := len(migrations) - 1
+	if migrations[search].Name != "journal_search" {
+		t.Fatalf("last migration = %s, want journal_search", migrations[search].Name)
+	}
+	if _, err := db.NewMigrator(database, migrations[:search]).Up(t.Context()); err != nil {
+		t.Fatalf("Up() before search error = %v", err)
+	}
+	err = database.Exec(`INSERT INTO mood_records (id, user_id, feeling, note) VALUES (3, 1, ' calm ', NULL);
+		INSERT INTO diary_entries (id, user_id, title, markdown) VALUES (3, 1, NULL, 'harbour walk');`).Error
+	if err != nil {
+		t.Fatalf("insert records: %v", err)
+	}
+	if _, err := db.NewMigrator(database, migrations).Up(t.Context()); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	var documents []struct {
+		RowID      int64
+		RecordType string
+		Title      string
+	}
+	err = database.Raw("SELECT rowid AS row_id, record_type, title FROM journal_search WHERE journal_search MATCH 'calm OR harbour' ORDER BY rowid").Scan(&documents).Error
+	if err != nil {
+		t.Fatalf("query search index: %v", err)
+	}
+	if len(documents) != 2 || documents[0].RowID != 6 || documents[0].Title != "calm" || documents[1].RowID != 7 || documents[1].RecordType != "diary_entry" {
+		t.Fatalf("search documents = %+v, want the mood record at rowid 6 and the diary entry at rowid 7", documents)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{name: "missing down file", files: fstest.MapFS{"m/0001_notes.up.sql": {Data: []byte("SELECT 1;")}}},
+		{name: "unexpected file name", files: fstest.MapFS{"m/notes.sql": {Data: []byte("SELECT 1;")}}},
+		{name: "conflicting names", files: fstest.MapFS{
+			"m/0001_notes.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/0001_other.down.sql": {Data: []byte("SELECT 1;")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := db.LoadMigrations(tt.files, "m"); err == nil {
+				t.Fatal("LoadMigrations() error = nil, want an error")
+			}
+		})
+	}
+
+	migrations := loadTestMigrations(t)
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Name != "pin_notes" {
+		t.Fatalf("LoadMigrations() = %+v, want two ordered migrations", migrations)
+	}
+}
+
+func loadTestMigrations(t *testing.T) []db.Migration {
+	t.Helper()
+
+	migrations, err := db.LoadMigrations(fstest.MapFS{
+		"m/0002_pin_notes.up.sql":      {Data: []byte("ALTER TABLE notes ADD COLUMN pinned integer;")},
+		"m/0002_pin_notes.down.sql":    {Data: []byte("ALTER TABLE notes DROP COLUMN pinned;")},
+		"m/0001_create_notes.up.sql":   {Data: []byte("CREATE TABLE notes (id integer PRIMARY KEY, body text);")},
+		"m/0001_create_notes.down.sql": {Data: []byte("DROP TABLE notes;")},
+	}, "m")
+	if err != nil {
+		t.Fatalf("load test migrations: %v", err)
+	}
+	return migrations
 }
 
-type migrationRecord struct {
-	ID   uint
-	Name string
+func schemaOf(t *testing.T, database *gorm.DB) map[string]string {
+	t.Helper()
+
+	var objects []struct {
+		Type    string
+		Name    string
+		TblName string
+	}
+	if err := database.Raw("SELECT type, name, tbl_name FROM sqlite_master WHERE name NOT LIKE 'sqlite_%'").Scan(&objects).Error; err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+
+	schema := make(map[string]string, len(objects))
+	for _, object := range objects {
+		var columns, extra string
+		var err error
+		if object.Type == "table" {
+			err = database.Raw(`SELECT coalesce(group_concat(name || ' ' || type || ' ' || "notnull" || ' ' || (pk > 0), ', '), '')
+				FROM (SELECT * FROM pragma_table_info(?) ORDER BY name)`, object.Name).Scan(&columns).Error
+			if err == nil {
+				err = database.Raw(`SELECT coalesce(group_concat("from" || ' ' || "table" || '.' || "to", ', '), '')
+					FROM (SELECT * FROM pragma_foreign_key_list(?) ORDER BY "from")`, object.Name).Scan(&extra).Error
+			}
+		} else {
+			err = database.Raw(`SELECT coalesce(group_concat(name, ', '), '')
+				FROM (SELECT * FROM pragma_index_info(?) ORDER BY seqno)`, object.Name).Scan(&columns).Error
+			if err == nil {
+				err = database.Raw(`SELECT 'unique ' || "unique" FROM pragma_index_list(?) WHERE name = ?`, object.TblName, object.Name).Scan(&extra).Error
+			}
+		}
+		if err != nil {
+			t.Fatalf("read %s %s: %v", object.Type, object.Name, err)
+		}
+		schema[object.Type+" "+object.Name] = columns + "; " + extra
+	}
+	return schema
 }
 
 func openTestDatabase(t *testing.T) *gorm.DB {
@@ -102,6 +483,7 @@ func openTestDatabase(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Fatalf("connect to test database: %v", err)
 	}
+	database.Logger = logger.Default.LogMode(logger.Silent)
 	sqlDB, err := database.DB()
 	if err != nil {
 		t.Fatalf("get SQL database: %v", err)
@@ -1,14 +1,282 @@
 package db
 
 import (
+	"context"
+	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
 
 	"gorm.io/gorm"
 )
 
-func AutoMigrate(db *gorm.DB, types ...any) error {
-	if err := db.AutoMigrate(types...); err != nil {
+const migrationTable = "schema_migrations"
+
+var ErrSchemaTooNew = errors.New("database schema is newer than this build")
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	Version   int `gorm:"primaryKey"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (appliedMigration) TableName() string {
+	return migrationTable
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func Migrate(ctx context.Context, db *gorm.DB) error {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return fmt.Errorf("migrate database: %w", err)
+	}
+	if _, err := NewMigrator(db, migrations).Up(ctx); err != nil {
 		return fmt.Errorf("migrate database: %w", err)
 	}
 	return nil
 }
+
+func SchemaVersion(ctx context.Context, db *gorm.DB) (int, error) {
+	var version int
+	err := db.WithContext(ctx).Model(&appliedMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	if err != nil {
+		return 0, fmt.Errorf("get schema version: %w", err)
+	}
+	return version, nil
+}
+
+func Migrations(dialect string) ([]Migration, error) {
+	switch dialect {
+	case DialectSQLite, DialectPostgres:
+		return LoadMigrations(migrationFiles, path.Join("migrations", dialect))
+	default:
+		return nil, fmt.Errorf("no migrations for %s databases", dialect)
+	}
+}
+
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(file.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %q", file.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", file.Name())
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", file.Name(), err)
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+	return migrations, nil
+}
+
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.createTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkApplied(applied); err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			record := appliedMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}
+			return tx.Create(&record).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		ran = append(ran, migration)
+	}
+	return ran, nil
+}
+
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	if err := m.createTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkApplied(applied); err != nil {
+		return nil, err
+	}
+
+	for _, migration := range slices.Backward(m.migrations) {
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&appliedMigration{Version: migration.Version}).Error
+		})
+		if err != nil {
+			return nil, fmt.Errorf("revert migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		return &migration, nil
+	}
+	return nil, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		if !slices.ContainsFunc(m.migrations, func(migration Migration) bool { return migration.Version == record.Version }) {
+			statuses = append(statuses, MigrationStatus{Version: record.Version, Name: record.Name, AppliedAt: &record.AppliedAt})
+		}
+	}
+	slices.SortFunc(statuses, func(a, b MigrationStatus) int {
+		return a.Version - b.Version
+	})
+	return statuses, nil
+}
+
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	return m.checkApplied(applied)
+}
+
+func (m *Migrator) checkApplied(applied map[int]appliedMigration) error {
+	latest := 0
+	if len(m.migrations) > 0 {
+		latest = m.migrations[len(m.migrations)-1].Version
+	}
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w: database is at version %d, this build knows up to %d", ErrSchemaTooNew, version, latest)
+		}
+		if !slices.ContainsFunc(m.migrations, func(migration Migration) bool { return migration.Version == version }) {
+			return fmt.Errorf("database has unknown migration %d applied", version)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) createTable(ctx context.Context) error {
+	db := m.db.WithContext(ctx)
+	timestampType := "datetime"
+	if db.Dialector.Name() == DialectPostgres {
+		timestampType = "timestamptz"
+	}
+	if err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + migrationTable + ` (
+		version integer PRIMARY KEY,
+		name text NOT NULL,
+		applied_at ` + timestampType + ` NOT NULL
+	)`).Error; err != nil {
+		return fmt.Errorf("create %s table: %w", migrationTable, err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]appliedMigration, error) {
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(migrationTable) {
+		return map[int]appliedMigration{}, nil
+	}
+
+	var records []appliedMigration
+	if err := db.Order("version").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("read applied migrations: %w", err)
+	}
+	applied := make(map[int]appliedMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
@@ -0,0 +1,136 @@
+CREATE TABLE tags (
+	id bigserial PRIMARY KEY,
+	user_id bigint,
+	name text,
+	created_at timestamptz,
+	updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_tag_user_name ON tags (user_id, name);
+
+CREATE TABLE mood_records (
+	id bigserial PRIMARY KEY,
+	user_id bigint,
+	feeling text,
+	emoji text,
+	note text,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz
+);
+CREATE INDEX idx_mood_records_deleted_at ON mood_records (deleted_at);
+
+CREATE TABLE mood_record_tags (
+	mood_record_id bigint,
+	tag_id bigint,
+	PRIMARY KEY (mood_record_id, tag_id),
+	CONSTRAINT fk_mood_record_tags_mood_record FOREIGN KEY (mood_record_id) REFERENCES mood_records (id),
+	CONSTRAINT fk_mood_record_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id)
+);
+
+CREATE TABLE diary_entries (
+	id bigserial PRIMARY KEY,
+	user_id bigint,
+	title text,
+	markdown text,
+	occurred_at timestamptz,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz
+);
+CREATE INDEX idx_diary_entries_deleted_at ON diary_entries (deleted_at);
+
+CREATE TABLE mood_record_diary_entries (
+	diary_entry_id bigint,
+	mood_record_id bigint,
+	PRIMARY KEY (diary_entry_id, mood_record_id),
+	CONSTRAINT fk_mood_record_diary_entries_mood_record FOREIGN KEY (mood_record_id) REFERENCES mood_records (id),
+	CONSTRAINT fk_mood_record_diary_entries_diary_entry FOREIGN KEY (diary_entry_id) REFERENCES diary_entries (id)
+);
+
+CREATE TABLE diary_entry_links (
+	source_id bigint,
+	target_id bigint,
+	PRIMARY KEY (source_id, target_id),
+	CONSTRAINT fk_diary_entry_links_diary_entry FOREIGN KEY (source_id) REFERENCES diary_entries (id),
+	CONSTRAINT fk_diary_entry_links_links FOREIGN KEY (target_id) REFERENCES diary_entries (id)
+);
+
+CREATE TABLE diary_entry_tags (
+	diary_entry_id bigint,
+	tag_id bigint,
+	PRIMARY KEY (diary_entry_id, tag_id),
+	CONSTRAINT fk_diary_entry_tags_diary_entry FOREIGN KEY (diary_entry_id) REFERENCES diary_entries (id),
+	CONSTRAINT fk_diary_entry_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id)
+);
+
+CREATE TABLE diary_entry_revisions (
+	id bigserial PRIMARY KEY,
+	diary_entry_id bigint,
+	revision bigint,
+	title text,
+	markdown text,
+	occurred_at timestamptz,
+	created_at timestamptz
+);
+CREATE UNIQUE INDEX idx_diary_entry_revision ON diary_entry_revisions (diary_entry_id, revision);
+
+CREATE TABLE attachments (
+	id bigserial PRIMARY KEY,
+	user_id bigint,
+	diary_entry_id bigint,
+	file_name text,
+	mime_type text,
+	size bigint,
+	sha256 text,
+	storage_key text,
+	created_at timestamptz,
+	CONSTRAINT fk_diary_entries_attachments FOREIGN KEY (diary_entry_id) REFERENCES diary_entries (id)
+);
+CREATE INDEX idx_attachments_diary_entry_id ON attachments (diary_entry_id);
+CREATE INDEX idx_attachments_user_id ON attachments (user_id);
+
+CREATE TABLE users (
+	id bigserial PRIMARY KEY,
+	login text NOT NULL,
+	email text NOT NULL,
+	password_hash text NOT NULL,
+	created_at timestamptz,
+	updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_users_email ON users (email);
+CREATE UNIQUE INDEX idx_users_login ON users (login);
+
+CREATE TABLE refresh_tokens (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	value text NOT NULL,
+	created_at timestamptz NOT NULL,
+	expires_at timestamptz NOT NULL
+);
+CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
+CREATE UNIQUE INDEX idx_refresh_tokens_value ON refresh_tokens (value);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE password_reset_tokens (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	token_hash text NOT NULL,
+	created_at timestamptz NOT NULL,
+	expires_at timestamptz NOT NULL
+);
+CREATE INDEX idx_password_reset_tokens_expires_at ON password_reset_tokens (expires_at);
+CREATE UNIQUE INDEX idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+
+CREATE TABLE invites (
+	id bigserial PRIMARY KEY,
+	token_hash text NOT NULL,
+	created_at timestamptz NOT NULL,
+	expires_at timestamptz NOT NULL,
+	used_at timestamptz,
+	registered_user_id bigint
+);
+CREATE INDEX idx_invites_registered_user_id ON invites (registered_user_id);
+CREATE INDEX idx_invites_used_at ON invites (used_at);
+CREATE INDEX idx_invites_expires_at ON invites (expires_at);
+CREATE UNIQUE INDEX idx_invites_token_hash ON invites (token_hash);
@@ -0,0 +1,22 @@
+CREATE TABLE passkeys (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL,
+	name text NOT NULL,
+	credential_id text NOT NULL,
+	credential text NOT NULL,
+	created_at timestamptz NOT NULL,
+	last_used_at timestamptz
+);
+CREATE INDEX idx_passkeys_user_id ON passkeys (user_id);
+CREATE UNIQUE INDEX idx_passkeys_credential_id ON passkeys (credential_id);
+
+CREATE TABLE passkey_challenges (
+	id bigserial PRIMARY KEY,
+	challenge text NOT NULL,
+	user_id bigint,
+	session_data text NOT NULL,
+	expires_at timestamptz NOT NULL
+);
+CREATE UNIQUE INDEX idx_passkey_challenges_challenge ON passkey_challenges (challenge);
+CREATE INDEX idx_passkey_challenges_user_id ON passkey_challenges (user_id);
+CREATE INDEX idx_passkey_challenges_expires_at ON passkey_challenges (expires_at);
@@ -0,0 +1,17 @@
+CREATE TABLE journal_search (
+	record_type text NOT NULL,
+	record_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	title text NOT NULL,
+	body text NOT NULL,
+	document tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', body), 'B')
+	) STORED,
+	PRIMARY KEY (record_type, record_id)
+);
+CREATE INDEX idx_journal_search_document ON journal_search USING gin (document);
+
+INSERT INTO journal_search (record_type, record_id, user_id, title, body)
+SELECT 'mood_record', id, user_id, coalesce(trim(feeling), ''), coalesce(trim(note), '') FROM mood_records;
+INSERT INTO journal_search (record_type, record_id, user_id, title, body)
+SELECT 'diary_entry', id, user_id, coalesce(trim(title), ''), coalesce(markdown, '') FROM diary_entries;
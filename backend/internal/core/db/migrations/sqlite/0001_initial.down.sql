@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS invites;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS diary_entry_revisions;
+DROP TABLE IF EXISTS diary_entry_tags;
+DROP TABLE IF EXISTS diary_entry_links;
+DROP TABLE IF EXISTS mood_record_diary_entries;
+DROP TABLE IF EXISTS diary_entries;
+DROP TABLE IF EXISTS mood_record_tags;
+DROP TABLE IF EXISTS mood_records;
+DROP TABLE IF EXISTS tags;
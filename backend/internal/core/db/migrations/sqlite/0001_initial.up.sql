@@ -0,0 +1,136 @@
+CREATE TABLE IF NOT EXISTS tags (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	user_id integer,
+	name text,
+	created_at datetime,
+	updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tag_user_name ON tags (user_id, name);
+
+CREATE TABLE IF NOT EXISTS mood_records (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	user_id integer,
+	feeling text,
+	emoji text,
+	note text,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_mood_records_deleted_at ON mood_records (deleted_at);
+
+CREATE TABLE IF NOT EXISTS mood_record_tags (
+	mood_record_id integer,
+	tag_id integer,
+	PRIMARY KEY (mood_record_id, tag_id),
+	CONSTRAINT fk_mood_record_tags_mood_record FOREIGN KEY (mood_record_id) REFERENCES mood_records (id),
+	CONSTRAINT fk_mood_record_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id)
+);
+
+CREATE TABLE IF NOT EXISTS diary_entries (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	user_id integer,
+	title text,
+	markdown text,
+	occurred_at datetime,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_diary_entries_deleted_at ON diary_entries (deleted_at);
+
+CREATE TABLE IF NOT EXISTS mood_record_diary_entries (
+	diary_entry_id integer,
+	mood_record_id integer,
+	PRIMARY KEY (diary_entry_id, mood_record_id),
+	CONSTRAINT fk_mood_record_diary_entries_mood_record FOREIGN KEY (mood_record_id) REFERENCES mood_records (id),
+	CONSTRAINT fk_mood_record_diary_entries_diary_entry FOREIGN KEY (diary_entry_id) REFERENCES diary_entries (id)
+);
+
+CREATE TABLE IF NOT EXISTS diary_entry_links (
+	source_id integer,
+	target_id integer,
+	PRIMARY KEY (source_id, target_id),
+	CONSTRAINT fk_diary_entry_links_diary_entry FOREIGN KEY (source_id) REFERENCES diary_entries (id),
+	CONSTRAINT fk_diary_entry_links_links FOREIGN KEY (target_id) REFERENCES diary_entries (id)
+);
+
+CREATE TABLE IF NOT EXISTS diary_entry_tags (
+	diary_entry_id integer,
+	tag_id integer,
+	PRIMARY KEY (diary_entry_id, tag_id),
+	CONSTRAINT fk_diary_entry_tags_diary_entry FOREIGN KEY (diary_entry_id) REFERENCES diary_entries (id),
+	CONSTRAINT fk_diary_entry_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id)
+);
+
+CREATE TABLE IF NOT EXISTS diary_entry_revisions (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	diary_entry_id integer,
+	revision integer,
+	title text,
+	markdown text,
+	occurred_at datetime,
+	created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_diary_entry_revision ON diary_entry_revisions (diary_entry_id, revision);
+
+CREATE TABLE IF NOT EXISTS attachments (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	user_id integer,
+	diary_entry_id integer,
+	file_name text,
+	mime_type text,
+	size integer,
+	sha256 text,
+	storage_key text,
+	created_at datetime,
+	CONSTRAINT fk_diary_entries_attachments FOREIGN KEY (diary_entry_id) REFERENCES diary_entries (id)
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_diary_entry_id ON attachments (diary_entry_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_user_id ON attachments (user_id);
+
+CREATE TABLE IF NOT EXISTS users (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	login text NOT NULL,
+	email text NOT NULL,
+	password_hash text NOT NULL,
+	created_at datetime,
+	updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_login ON users (login);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	user_id integer NOT NULL,
+	value text NOT NULL,
+	created_at datetime NOT NULL,
+	expires_at datetime NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_value ON refresh_tokens (value);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	user_id integer NOT NULL,
+	token_hash text NOT NULL,
+	created_at datetime NOT NULL,
+	expires_at datetime NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_expires_at ON password_reset_tokens (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS invites (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	token_hash text NOT NULL,
+	created_at datetime NOT NULL,
+	expires_at datetime NOT NULL,
+	used_at datetime,
+	registered_user_id integer
+);
+CREATE INDEX IF NOT EXISTS idx_invites_registered_user_id ON invites (registered_user_id);
+CREATE INDEX IF NOT EXISTS idx_invites_used_at ON invites (used_at);
+CREATE INDEX IF NOT EXISTS idx_invites_expires_at ON invites (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invites_token_hash ON invites (token_hash);
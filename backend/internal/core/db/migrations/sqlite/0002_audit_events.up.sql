@@ -0,0 +1,14 @@
+CREATE TABLE audit_events (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime NOT NULL,
+	type text NOT NULL,
+	outcome text NOT NULL,
+	actor text NOT NULL,
+	user_id integer,
+	ip text NOT NULL,
+	user_agent text NOT NULL,
+	detail text NOT NULL
+);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+CREATE INDEX idx_audit_events_type ON audit_events (type);
+CREATE INDEX idx_audit_events_user_id ON audit_events (user_id);
@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN totp_secret text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN totp_enabled_at datetime;
+ALTER TABLE users ADD COLUMN totp_last_step integer NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_codes (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	user_id integer NOT NULL,
+	code_hash text NOT NULL,
+	created_at datetime NOT NULL
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
+CREATE UNIQUE INDEX idx_recovery_codes_code_hash ON recovery_codes (code_hash);
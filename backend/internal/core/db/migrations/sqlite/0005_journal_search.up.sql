@@ -0,0 +1,13 @@
+CREATE VIRTUAL TABLE journal_search USING fts5(
+	record_type UNINDEXED,
+	record_id UNINDEXED,
+	user_id UNINDEXED,
+	title,
+	body,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO journal_search (rowid, record_type, record_id, user_id, title, body)
+SELECT id * 2, 'mood_record', id, user_id, coalesce(trim(feeling), ''), coalesce(trim(note), '') FROM mood_records;
+INSERT INTO journal_search (rowid, record_type, record_id, user_id, title, body)
+SELECT id * 2 + 1, 'diary_entry', id, user_id, coalesce(trim(title), ''), coalesce(markdown, '') FROM diary_entries;
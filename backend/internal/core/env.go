@@ -0,0 +1,23 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+func PositiveIntEnv(name string, fallback int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	if parsed <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number", name)
+	}
+	return parsed, nil
+}
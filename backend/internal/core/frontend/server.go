@@ -2,6 +2,7 @@ package frontend
 
 import (
 	"bytes"
+	"fmt"
 	"io/fs"
 	"log/slog"
 	"net/http"
@@ -24,6 +25,16 @@ func RegisterRoutes(e *echo.Echo, config Config) {
 	registerStaticRoutes(e, frontendFS, config.APIURL)
 }
 
+func Ready(config Config) error {
+	if !config.EnableFrontendDist {
+		return nil
+	}
+	if _, err := fs.Stat(FrontendFS, "fs/index.html"); err != nil {
+		return fmt.Errorf("frontend dist is not embedded: %w", err)
+	}
+	return nil
+}
+
 func registerStaticRoutes(e *echo.Echo, frontendFS fs.FS, apiURL string) {
 	patchedFiles := make(map[string][]byte)
 	err := fs.WalkDir(frontendFS, ".", func(path string, entry fs.DirEntry, err error) error {
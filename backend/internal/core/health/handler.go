@@ -0,0 +1,119 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core/db"
+	"github.com/azaviyalov/null3/backend/internal/core/frontend"
+	"github.com/azaviyalov/null3/backend/internal/core/logging"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const readinessTimeout = 2 * time.Second
+
+func RegisterRoutes(e *echo.Echo, handler *Handler) {
+	e.GET("/healthz", handler.Healthz, logging.QuietRequest)
+	e.GET("/readyz", handler.Readyz, logging.QuietRequest)
+	e.GET("/api/version", handler.Version, logging.QuietRequest)
+}
+
+type Handler struct {
+	db             *gorm.DB
+	frontendConfig frontend.Config
+}
+
+func NewHandler(db *gorm.DB, frontendConfig frontend.Config) *Handler {
+	return &Handler{
+		db:             db,
+		frontendConfig: frontendConfig,
+	}
+}
+
+func (h *Handler) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, StatusResponse{Status: "ok"})
+}
+
+func (h *Handler) Readyz(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]error{
+		"database":   h.pingDatabase(ctx),
+		"migrations": h.checkMigrations(ctx),
+		"frontend":   frontend.Ready(h.frontendConfig),
+	}
+
+	resp := ReadinessResponse{Status: "ok", Checks: make(map[string]string, len(checks))}
+	status := http.StatusOK
+	for name, err := range checks {
+		if err != nil {
+			logging.FromContext(ctx).Warn("readiness check failed", "check", name, "error", err)
+			resp.Checks[name] = err.Error()
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[name] = "ok"
+	}
+	return c.JSON(status, resp)
+}
+
+func (h *Handler) Version(c echo.Context) error {
+	resp := VersionResponse{Version: "(unknown)"}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		resp.Version = info.Main.Version
+		resp.GoVersion = info.GoVersion
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				resp.Revision = setting.Value
+			case "vcs.time":
+				if revisionTime, err := time.Parse(time.RFC3339, setting.Value); err == nil {
+					resp.RevisionTime = &revisionTime
+				}
+			case "vcs.modified":
+				resp.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	schemaVersion, err := db.SchemaVersion(c.Request().Context(), h.db)
+	if err != nil {
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	resp.SchemaVersion = schemaVersion
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) pingDatabase(ctx context.Context) error {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (h *Handler) checkMigrations(ctx context.Context) error {
+	migrations, err := db.Migrations(h.db.Dialector.Name())
+	if err != nil {
+		return err
+	}
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+
+	version, err := db.SchemaVersion(ctx, h.db)
+	if err != nil {
+		return err
+	}
+	if version != latest {
+		return fmt.Errorf("schema is at version %d, want %d", version, latest)
+	}
+	return nil
+}
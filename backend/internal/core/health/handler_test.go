@@ -0,0 +1,79 @@
+package health_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/azaviyalov/null3/backend/internal/core/db"
+	"github.com/azaviyalov/null3/backend/internal/core/frontend"
+	"github.com/azaviyalov/null3/backend/internal/core/health"
+	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	testutil.SkipIntegration(t)
+	testutil.DiscardLogs(t)
+	database := testutil.NewDatabase(t, "health.sqlite")
+	e := server.NewEchoServer(server.Config{})
+	health.RegisterRoutes(e, health.NewHandler(database, frontend.Config{}))
+
+	response := testutil.JSONRequest(t, e, http.MethodGet, "/healthz", nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("healthz status = %d, want %d", response.Code, http.StatusOK)
+	}
+
+	response = testutil.JSONRequest(t, e, http.MethodGet, "/readyz", nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("readyz status = %d, want %d: %s", response.Code, http.StatusOK, response.Body)
+	}
+	var readiness health.ReadinessResponse
+	testutil.DecodeJSON(t, response, &readiness)
+	for _, check := range []string{"database", "migrations", "frontend"} {
+		if readiness.Checks[check] != "ok" {
+			t.Errorf("readiness check %s = %q, want ok", check, readiness.Checks[check])
+		}
+	}
+
+	response = testutil.JSONRequest(t, e, http.MethodGet, "/api/version", nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("version status = %d, want %d", response.Code, http.StatusOK)
+	}
+	migrations, err := db.Migrations(database.Dialector.Name())
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	latest := migrations[len(migrations)-1].Version
+	var version health.VersionResponse
+	testutil.DecodeJSON(t, response, &version)
+	if version.SchemaVersion != latest || version.Version == "" || version.GoVersion == "" {
+		t.Fatalf("version = %+v, want schema version %d and build info", version, latest)
+	}
+
+	if _, err := db.NewMigrator(database, migrations).Down(t.Context()); err != nil {
+		t.Fatalf("revert migration: %v", err)
+	}
+	response = testutil.JSONRequest(t, e, http.MethodGet, "/readyz", nil)
+	if response.Code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz after revert status = %d, want %d", response.Code, http.StatusServiceUnavailable)
+	}
+	readiness = health.ReadinessResponse{}
+	testutil.DecodeJSON(t, response, &readiness)
+	if readiness.Status != "unavailable" || readiness.Checks["migrations"] == "ok" || readiness.Checks["database"] != "ok" {
+		t.Fatalf("readiness after revert = %+v, want failing migrations check", readiness)
+	}
+
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatalf("get SQL database: %v", err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		t.Fatalf("close SQL database: %v", err)
+	}
+	readiness = health.ReadinessResponse{}
+	response = testutil.JSONRequest(t, e, http.MethodGet, "/readyz", nil)
+	testutil.DecodeJSON(t, response, &readiness)
+	if response.Code != http.StatusServiceUnavailable || readiness.Checks["database"] == "ok" {
+		t.Fatalf("readiness with closed database = %d %+v, want failing database check", response.Code, readiness)
+	}
+}
@@ -0,0 +1,21 @@
+package health
+
+import "time"
+
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+type VersionResponse struct {
+	Version       string     `json:"version"`
+	Revision      string     `json:"revision,omitempty"`
+	RevisionTime  *time.Time `json:"revision_time,omitempty"`
+	Modified      bool       `json:"modified"`
+	GoVersion     string     `json:"go_version"`
+	SchemaVersion int        `json:"schema_version"`
+}
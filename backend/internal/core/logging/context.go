@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/labstack/echo/v4"
+)
+
+type loggerContextKey struct{}
+
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+func AddRequestAttrs(c echo.Context, args ...any) {
+	request := c.Request()
+	c.SetRequest(request.WithContext(With(request.Context(), args...)))
+}
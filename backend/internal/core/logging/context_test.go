@@ -0,0 +1,29 @@
+package logging_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/azaviyalov/null3/backend/internal/core/logging"
+)
+
+func TestFromContextDefaultsToDefaultLogger(t *testing.T) {
+	if got := logging.FromContext(t.Context()); got != slog.Default() {
+		t.Fatalf("FromContext() = %v, want slog.Default()", got)
+	}
+}
+
+func TestWithAddsAttributesToContextLogger(t *testing.T) {
+	output := &bytes.Buffer{}
+	ctx := logging.NewContext(t.Context(), slog.New(slog.NewTextHandler(output, nil)))
+	ctx = logging.With(ctx, "request_id", "request-1")
+	ctx = logging.With(ctx, "user_id", 7)
+
+	logging.FromContext(ctx).Error("save failed")
+
+	if want := `msg="save failed" request_id=request-1 user_id=7`; !strings.Contains(output.String(), want) {
+		t.Fatalf("output = %q, want %q", output.String(), want)
+	}
+}
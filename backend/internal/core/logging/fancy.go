@@ -48,16 +48,16 @@ func (h *FancyHandler) Handle(_ context.Context, r slog.Record) error {
 
 func (h *FancyHandler) formatRecordAttrs(r slog.Record) string {
 	var attrLines []string
-	for _, stored := range h.attrs {
-		attrLines = append(attrLines, h.formatAttr(stored.groups, stored.attr))
-	}
 	if h.addSource {
 		if fileLine := sourceFromPC(r.PC); fileLine != "" {
-			attrLines = append(attrLines, h.formatAttr(nil, slog.String("source", fileLine)))
+			attrLines = h.appendAttr(attrLines, nil, slog.String("source", fileLine))
 		}
 	}
+	for _, stored := range h.attrs {
+		attrLines = h.appendAttr(attrLines, stored.groups, stored.attr)
+	}
 	r.Attrs(func(a slog.Attr) bool {
-		attrLines = append(attrLines, h.formatAttr(h.groups, a))
+		attrLines = h.appendAttr(attrLines, h.groups, a)
 		return true
 	})
 	if len(attrLines) > 0 {
@@ -66,11 +66,30 @@ func (h *FancyHandler) formatRecordAttrs(r slog.Record) string {
 	return ""
 }
 
-func (h *FancyHandler) formatAttr(groups []string, a slog.Attr) string {
+func (h *FancyHandler) appendAttr(lines []string, groups []string, a slog.Attr) []string {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if a.Key != "" {
+			groups = append(append([]string(nil), groups...), a.Key)
+		}
+		for _, attr := range attrs {
+			lines = h.appendAttr(lines, groups, attr)
+		}
+		return lines
+	}
 	if h.replaceAttrs != nil {
 		a = h.replaceAttrs(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return lines
 	}
-	val := a.Value.Resolve()
+	return append(lines, h.formatAttr(groups, a))
+}
+
+func (h *FancyHandler) formatAttr(groups []string, a slog.Attr) string {
+	val := a.Value
 	key := a.Key
 	if len(groups) > 0 {
 		key = strings.Join(groups, ".") + "." + key
@@ -101,3 +101,27 @@ func TestFancyHandlerWritesFormattedRecord(t *testing.T) {
 		})
 	}
 }
+
+func TestFancyHandlerRendersAttributesLikeTextHandler(t *testing.T) {
+	fancyOutput := &bytes.Buffer{}
+	textOutput := &bytes.Buffer{}
+	for _, handler := range []slog.Handler{
+		logging.NewFancyHandler(fancyOutput, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		slog.NewTextHandler(textOutput, nil),
+	} {
+		logger := slog.New(handler).With("request_id", "request-1", "route", "/entries/:id").With("user_id", uint(7))
+		logger.Warn("slow save", slog.Group("db", "operation", "create", slog.Group("retry", "count", 2)), slog.Attr{})
+	}
+
+	for _, key := range []string{"request_id", "route", "user_id", "db.operation", "db.retry.count"} {
+		if !strings.Contains(textOutput.String(), key+"=") {
+			t.Fatalf("text output %q does not contain %s", textOutput.String(), key)
+		}
+		if !strings.Contains(fancyOutput.String(), "\x1b[1;34m"+key+"\x1b[0m") {
+			t.Errorf("fancy output %q does not contain %s", fancyOutput.String(), key)
+		}
+	}
+	if got := strings.Count(fancyOutput.String(), "  - "); got != 5 {
+		t.Errorf("fancy output has %d attributes, want 5: %q", got, fancyOutput.String())
+	}
+}
@@ -1,13 +1,21 @@
 package logging
 
 import (
-	"log/slog"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
+const quietRequestKey = "logging.quiet"
+
+func QuietRequest(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Set(quietRequestKey, true)
+		return next(c)
+	}
+}
+
 func RequestLogger() echo.MiddlewareFunc {
 	requestID := middleware.RequestID()
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -16,24 +24,33 @@ func RequestLogger() echo.MiddlewareFunc {
 			request := c.Request()
 			response := c.Response()
 
+			attrs := []any{"request_id", response.Header().Get(echo.HeaderXRequestID)}
+			if route := c.Path(); route != "" {
+				attrs = append(attrs, "route", route)
+			}
+			AddRequestAttrs(c, attrs...)
+
 			err := next(c)
 			if err != nil {
 				c.Error(err)
 			}
 
-			logger := slog.Default().With("request_id", response.Header().Get(echo.HeaderXRequestID))
+			logger := FromContext(c.Request().Context())
 			path := c.Path()
 			if path == "" {
 				path = request.URL.Path
 			}
-			attrs := []any{
+			attrs = []any{
 				"method", request.Method,
 				"path", path,
 				"status", response.Status,
 				"latency", time.Since(start).String(),
 				"ip", c.RealIP(),
 			}
+			quiet, _ := c.Get(quietRequestKey).(bool)
 			switch {
+			case err == nil && quiet:
+				logger.Debug("HTTP request completed", attrs...)
 			case err == nil:
 				logger.Info("HTTP request completed", attrs...)
 			case response.Status >= 500:
@@ -167,6 +167,60 @@ func TestRequestLoggerLogsHandlerErrors(t *testing.T) {
 	}
 }
 
+func TestRequestLoggerLogsQuietRequestsAtDebug(t *testing.T) {
+	logBuffer := installJSONLogger(t)
+	e := echo.New()
+	e.Use(logging.RequestLogger())
+	e.GET("/healthz", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, logging.QuietRequest)
+	e.GET("/broken", func(echo.Context) error {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "not ready")
+	}, logging.QuietRequest)
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if logBuffer.Len() != 0 {
+		t.Fatalf("quiet request was logged at info level: %s", logBuffer.String())
+	}
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+	record := decodeLogRecord(t, logBuffer)
+	assertLogField(t, record, "level", "ERROR")
+	assertLogField(t, record, "path", "/broken")
+}
+
+func TestRequestLoggerCarriesLoggerInContext(t *testing.T) {
+	logBuffer := installJSONLogger(t)
+	e := echo.New()
+	e.Use(logging.RequestLogger())
+	authenticate := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			logging.AddRequestAttrs(c, "user_id", 42)
+			return next(c)
+		}
+	}
+	e.GET("/entries/:id", func(c echo.Context) error {
+		logging.FromContext(c.Request().Context()).Warn("entry is stale")
+		return c.NoContent(http.StatusNoContent)
+	}, authenticate)
+	request := httptest.NewRequest(http.MethodGet, "/entries/7", nil)
+	request.Header.Set("X-Request-Id", "request-7")
+
+	e.ServeHTTP(httptest.NewRecorder(), request)
+
+	decoder := json.NewDecoder(logBuffer)
+	for _, wantMessage := range []string{"entry is stale", "HTTP request completed"} {
+		var record map[string]any
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("decode log record: %v", err)
+		}
+		assertLogField(t, record, "msg", wantMessage)
+		assertLogField(t, record, "request_id", "request-7")
+		assertLogField(t, record, "route", "/entries/:id")
+		assertLogField(t, record, "user_id", float64(42))
+	}
+}
+
 func installJSONLogger(t *testing.T) *bytes.Buffer {
 	t.Helper()
 
@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+type Config struct {
+	Enabled bool
+	Token   string
+}
+
+func GetConfig() (Config, error) {
+	var config Config
+
+	if enabledParam := os.Getenv("METRICS_ENABLED"); enabledParam != "" {
+		enabled, err := strconv.ParseBool(enabledParam)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse METRICS_ENABLED: %w", err)
+		}
+		config.Enabled = enabled
+	}
+
+	config.Token = os.Getenv("METRICS_TOKEN")
+
+	return config, nil
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core/db"
+	"github.com/azaviyalov/null3/backend/internal/core/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+const (
+	namespace         = "null3"
+	queryStartKey     = "metrics:query_start"
+	unmatchedRoute    = "unmatched"
+	loginResultOK     = "success"
+	loginResultFailed = "failure"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+	factory  = promauto.With(registry)
+
+	httpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+	httpRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	dbQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database statement latency by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation"})
+	logins = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "User login attempts by result.",
+	}, []string{"result"})
+	refreshTokenRotations = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refresh_token_rotations_total",
+		Help:      "Refresh tokens exchanged for a new session.",
+	})
+	invitesCreated = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invites_created_total",
+		Help:      "Invites created by the administrator.",
+	})
+	invitesRedeemed = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invites_redeemed_total",
+		Help:      "Invites used to register an account.",
+	})
+	journalRecordsCreated = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "journal_records_created_total",
+		Help:      "Journal records created by type.",
+	}, []string{"type"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	logins.WithLabelValues(loginResultOK)
+	logins.WithLabelValues(loginResultFailed)
+}
+
+func RegisterRoutes(e *echo.Echo, config Config) {
+	if !config.Enabled {
+		return
+	}
+
+	middlewares := []echo.MiddlewareFunc{logging.QuietRequest}
+	if config.Token != "" {
+		middlewares = append(middlewares, requireToken(config.Token))
+	}
+	e.GET("/metrics", echo.WrapHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})), middlewares...)
+}
+
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			status := strconv.Itoa(c.Response().Status)
+			method := c.Request().Method
+			httpRequests.WithLabelValues(method, route, status).Inc()
+			httpRequestDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+func GormPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string {
+	return "metrics"
+}
+
+func (gormPlugin) Initialize(database *gorm.DB) error {
+	return db.RegisterStatementHooks(database, "metrics",
+		func(database *gorm.DB, _ string) {
+			database.InstanceSet(queryStartKey, time.Now())
+		},
+		func(database *gorm.DB, operation string) {
+			if start, ok := database.InstanceGet(queryStartKey); ok {
+				dbQueryDuration.WithLabelValues(operation).Observe(time.Since(start.(time.Time)).Seconds())
+			}
+		},
+	)
+}
+
+func ObserveLogin(success bool) {
+	result := loginResultFailed
+	if success {
+		result = loginResultOK
+	}
+	logins.WithLabelValues(result).Inc()
+}
+
+func CountRefreshTokenRotation() {
+	refreshTokenRotations.Inc()
+}
+
+func CountInviteCreated() {
+	invitesCreated.Inc()
+}
+
+func CountInviteRedeemed() {
+	invitesRedeemed.Inc()
+}
+
+func CountJournalRecordsCreated(recordType string, count int) {
+	if count > 0 {
+		journalRecordsCreated.WithLabelValues(recordType).Add(float64(count))
+	}
+}
+
+func requireToken(token string) echo.MiddlewareFunc {
+	want := []byte("Bearer " + token)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			got := []byte(c.Request().Header.Get(echo.HeaderAuthorization))
+			if subtle.ConstantTimeCompare(got, want) != 1 {
+				return echo.ErrUnauthorized
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/azaviyalov/null3/backend/internal/core/metrics"
+	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+	"github.com/labstack/echo/v4"
+)
+
+func TestGetConfig(t *testing.T) {
+	t.Setenv("METRICS_ENABLED", "")
+	t.Setenv("METRICS_TOKEN", "")
+	config, err := metrics.GetConfig()
+	if err != nil || config != (metrics.Config{}) {
+		t.Fatalf("GetConfig() = %+v, %v, want disabled", config, err)
+	}
+
+	t.Setenv("METRICS_ENABLED", "true")
+	t.Setenv("METRICS_TOKEN", "scrape-token")
+	config, err = metrics.GetConfig()
+	if err != nil || config != (metrics.Config{Enabled: true, Token: "scrape-token"}) {
+		t.Fatalf("GetConfig() = %+v, %v, want enabled with token", config, err)
+	}
+
+	t.Setenv("METRICS_ENABLED", "maybe")
+	if _, err := metrics.GetConfig(); err == nil || !strings.Contains(err.Error(), "METRICS_ENABLED") {
+		t.Fatalf("GetConfig() error = %v, want METRICS_ENABLED error", err)
+	}
+}
+
+func TestMetricsEndpointIsDisabledByDefault(t *testing.T) {
+	testutil.DiscardLogs(t)
+	e := server.NewEchoServer(server.Config{})
+	metrics.RegisterRoutes(e, metrics.Config{})
+
+	response := scrape(t, e, "")
+	if response.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", response.Code, http.StatusNotFound)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	testutil.DiscardLogs(t)
+	e := server.NewEchoServer(server.Config{})
+	metrics.RegisterRoutes(e, metrics.Config{Enabled: true, Token: "scrape-token"})
+	e.GET("/api/items/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/items/42", nil))
+	metrics.ObserveLogin(true)
+	metrics.CountJournalRecordsCreated("diary_entry", 2)
+
+	for _, token := range []string{"", "wrong-token"} {
+		if response := scrape(t, e, token); response.Code != http.StatusUnauthorized {
+			t.Fatalf("scrape with token %q status = %d, want %d", token, response.Code, http.StatusUnauthorized)
+		}
+	}
+
+	response := scrape(t, e, "scrape-token")
+	if response.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", response.Code, http.StatusOK)
+	}
+	body, _ := io.ReadAll(response.Body)
+	for _, want := range []string{
+		`null3_http_requests_total{method="GET",route="/api/items/:id",status="204"}`,
+		`null3_http_request_duration_seconds_count{method="GET",route="/api/items/:id",status="204"}`,
+		`null3_logins_total{result="failure"}`,
+		`null3_logins_total{result="success"}`,
+		`null3_journal_records_created_total{type="diary_entry"}`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output does not contain %q", want)
+		}
+	}
+	if strings.Contains(string(body), "/api/items/42") {
+		t.Error("metrics output contains a raw request path")
+	}
+}
+
+func TestGormPluginRecordsQueryDurations(t *testing.T) {
+	testutil.SkipIntegration(t)
+	testutil.DiscardLogs(t)
+	database := testutil.NewDatabase(t, "metrics.sqlite")
+	if err := database.Use(metrics.GormPlugin()); err != nil {
+		t.Fatalf("register plugin: %v", err)
+	}
+	var count int64
+	if err := database.Table("users").Count(&count).Error; err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	e := server.NewEchoServer(server.Config{})
+	metrics.RegisterRoutes(e, metrics.Config{Enabled: true})
+
+	body, _ := io.ReadAll(scrape(t, e, "").Body)
+	if !strings.Contains(string(body), `null3_db_query_duration_seconds_count{operation="query"}`) {
+		t.Fatal("metrics output does not contain query durations")
+	}
+}
+
+func scrape(t *testing.T, e *echo.Echo, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if token != "" {
+		request.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	response := httptest.NewRecorder()
+	e.ServeHTTP(response, request)
+	return response
+}
@@ -4,3 +4,9 @@ type Page[T any] struct {
 	Items      []T   `json:"items"`
 	TotalCount int64 `json:"total_count"`
 }
+
+type CursorPage[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	TotalCount *int64 `json:"total_count,omitempty"`
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+)
+
+type Config struct {
+	Enabled          bool
+	Burst            int
+	PerMinute        int
+	FailureThreshold int
+	Lockout          time.Duration
+	MaxLockout       time.Duration
+}
+
+func GetConfig() (Config, error) {
+	config := Config{
+		Enabled:          true,
+		Burst:            10,
+		PerMinute:        10,
+		FailureThreshold: 5,
+		Lockout:          time.Minute,
+		MaxLockout:       time.Hour,
+	}
+
+	if enabledParam := os.Getenv("RATE_LIMIT_ENABLED"); enabledParam != "" {
+		enabled, err := strconv.ParseBool(enabledParam)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse RATE_LIMIT_ENABLED: %w", err)
+		}
+		config.Enabled = enabled
+	}
+
+	var err error
+	if config.Burst, err = core.PositiveIntEnv("RATE_LIMIT_BURST", config.Burst); err != nil {
+		return Config{}, err
+	}
+	if config.PerMinute, err = core.PositiveIntEnv("RATE_LIMIT_PER_MINUTE", config.PerMinute); err != nil {
+		return Config{}, err
+	}
+	if config.FailureThreshold, err = core.PositiveIntEnv("RATE_LIMIT_FAILURES", config.FailureThreshold); err != nil {
+		return Config{}, err
+	}
+	if config.Lockout, err = parsePositiveDuration("RATE_LIMIT_LOCKOUT", config.Lockout); err != nil {
+		return Config{}, err
+	}
+	if config.MaxLockout, err = parsePositiveDuration("RATE_LIMIT_MAX_LOCKOUT", config.MaxLockout); err != nil {
+		return Config{}, err
+	}
+	if config.MaxLockout < config.Lockout {
+		return Config{}, fmt.Errorf("RATE_LIMIT_MAX_LOCKOUT must not be shorter than RATE_LIMIT_LOCKOUT")
+	}
+
+	return config, nil
+}
+
+func parsePositiveDuration(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	if parsed <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration", name)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,51 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core/ratelimit"
+)
+
+func TestGetConfig(t *testing.T) {
+	for _, name := range []string{"RATE_LIMIT_ENABLED", "RATE_LIMIT_BURST", "RATE_LIMIT_PER_MINUTE", "RATE_LIMIT_FAILURES", "RATE_LIMIT_LOCKOUT", "RATE_LIMIT_MAX_LOCKOUT"} {
+		t.Setenv(name, "")
+	}
+	config, err := ratelimit.GetConfig()
+	want := ratelimit.Config{Enabled: true, Burst: 10, PerMinute: 10, FailureThreshold: 5, Lockout: time.Minute, MaxLockout: time.Hour}
+	if err != nil || config != want {
+		t.Fatalf("GetConfig() = %+v, %v, want defaults", config, err)
+	}
+
+	t.Setenv("RATE_LIMIT_ENABLED", "false")
+	t.Setenv("RATE_LIMIT_BURST", "3")
+	t.Setenv("RATE_LIMIT_PER_MINUTE", "30")
+	t.Setenv("RATE_LIMIT_FAILURES", "2")
+	t.Setenv("RATE_LIMIT_LOCKOUT", "10s")
+	t.Setenv("RATE_LIMIT_MAX_LOCKOUT", "5m")
+	config, err = ratelimit.GetConfig()
+	want = ratelimit.Config{Burst: 3, PerMinute: 30, FailureThreshold: 2, Lockout: 10 * time.Second, MaxLockout: 5 * time.Minute}
+	if err != nil || config != want {
+		t.Fatalf("GetConfig() = %+v, %v, want overrides", config, err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "RATE_LIMIT_ENABLED", value: "sometimes"},
+		{name: "RATE_LIMIT_BURST", value: "0"},
+		{name: "RATE_LIMIT_PER_MINUTE", value: "many"},
+		{name: "RATE_LIMIT_FAILURES", value: "-1"},
+		{name: "RATE_LIMIT_LOCKOUT", value: "0s"},
+		{name: "RATE_LIMIT_MAX_LOCKOUT", value: "1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			if _, err := ratelimit.GetConfig(); err == nil {
+				t.Fatalf("GetConfig() with %s=%q succeeded", tt.name, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core/logging"
+	"github.com/labstack/echo/v4"
+)
+
+const maxBackoffShift = 30
+
+var ErrLimited = errors.New("too many attempts")
+
+type LimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LimitedError) Error() string {
+	return fmt.Sprintf("%s: retry after %s", ErrLimited, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LimitedError) Unwrap() error {
+	return ErrLimited
+}
+
+type Limiter struct {
+	store  Store
+	config Config
+	now    func() time.Time
+}
+
+func New(store Store, config Config) *Limiter {
+	return &Limiter{store: store, config: config, now: time.Now}
+}
+
+func (l *Limiter) Allow(ctx context.Context, keys ...string) error {
+	if l == nil {
+		return nil
+	}
+	now := l.now()
+	for _, key := range keys {
+		var retryAfter time.Duration
+		err := l.store.Update(ctx, key, now, func(state *State) {
+			l.refill(state, now)
+			switch {
+			case state.LockedUntil.After(now):
+				retryAfter = state.LockedUntil.Sub(now)
+			case state.Tokens < 1:
+				retryAfter = time.Duration((1 - state.Tokens) * float64(l.interval()))
+			default:
+				state.Tokens--
+			}
+			l.expire(state)
+		})
+		if err != nil {
+			return fmt.Errorf("rate limit %s: %w", key, err)
+		}
+		if retryAfter > 0 {
+			return &LimitedError{RetryAfter: retryAfter}
+		}
+	}
+	return nil
+}
+
+func (l *Limiter) Fail(ctx context.Context, keys ...string) {
+	if l == nil {
+		return
+	}
+	now := l.now()
+	for _, key := range keys {
+		err := l.store.Update(ctx, key, now, func(state *State) {
+			l.refill(state, now)
+			state.Failures++
+			state.LastFailure = now
+			if excess := state.Failures - l.config.FailureThreshold; excess >= 0 {
+				lockout := l.config.MaxLockout
+				if excess < maxBackoffShift {
+					lockout = min(l.config.Lockout<<excess, l.config.MaxLockout)
+				}
+				state.LockedUntil = now.Add(lockout)
+			}
+			l.expire(state)
+		})
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to record rate limit failure", "key", key, "error", err)
+		}
+	}
+}
+
+func (l *Limiter) Reset(ctx context.Context, keys ...string) {
+	if l == nil {
+		return
+	}
+	now := l.now()
+	for _, key := range keys {
+		err := l.store.Update(ctx, key, now, func(state *State) {
+			l.refill(state, now)
+			state.Failures = 0
+			state.LastFailure = time.Time{}
+			state.LockedUntil = time.Time{}
+			l.expire(state)
+		})
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to reset rate limit", "key", key, "error", err)
+		}
+	}
+}
+
+func (l *Limiter) refill(state *State, now time.Time) {
+	burst := float64(l.config.Burst)
+	if state.UpdatedAt.IsZero() {
+		state.Tokens = burst
+	} else if elapsed := now.Sub(state.UpdatedAt); elapsed > 0 {
+		state.Tokens = min(burst, state.Tokens+float64(elapsed)/float64(l.interval()))
+	}
+	state.UpdatedAt = now
+	if state.Failures > 0 && now.Sub(state.LastFailure) > l.config.MaxLockout {
+		state.Failures = 0
+	}
+}
+
+func (l *Limiter) expire(state *State) {
+	missing := float64(l.config.Burst) - state.Tokens
+	expiresAt := state.UpdatedAt.Add(time.Duration(missing * float64(l.interval())))
+	if state.LockedUntil.After(expiresAt) {
+		expiresAt = state.LockedUntil
+	}
+	if state.Failures > 0 {
+		if failuresExpireAt := state.LastFailure.Add(l.config.MaxLockout); failuresExpireAt.After(expiresAt) {
+			expiresAt = failuresExpireAt
+		}
+	}
+	state.ExpiresAt = expiresAt
+}
+
+func (l *Limiter) interval() time.Duration {
+	return time.Minute / time.Duration(l.config.PerMinute)
+}
+
+func Keys(action, ip string, subjects ...string) []string {
+	keys := make([]string, 0, len(subjects)+1)
+	if ip != "" {
+		keys = append(keys, action+":ip:"+ip)
+	}
+	for _, subject := range subjects {
+		keys = append(keys, action+":"+subject)
+	}
+	return keys
+}
+
+func TooManyRequests(c echo.Context, err error) error {
+	retryAfter := time.Second
+	var limited *LimitedError
+	if errors.As(err, &limited) {
+		retryAfter = max(limited.RetryAfter, time.Second)
+	}
+	c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	httpError := echo.NewHTTPError(http.StatusTooManyRequests, "Too many attempts. Try again later.")
+	httpError.Internal = err
+	return httpError
+}
@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestLimiterTokenBucket(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+
+	for i := range 3 {
+		if err := limiter.Allow(t.Context(), "login:ip:192.0.2.1"); err != nil {
+			t.Fatalf("Allow() attempt %d error = %v", i+1, err)
+		}
+	}
+	err := limiter.Allow(t.Context(), "login:ip:192.0.2.1")
+	var limited *LimitedError
+	if !errors.As(err, &limited) || !errors.Is(err, ErrLimited) || limited.RetryAfter != 10*time.Second {
+		t.Fatalf("Allow() over burst error = %v, want retry after 10s", err)
+	}
+	if err := limiter.Allow(t.Context(), "login:ip:192.0.2.2"); err != nil {
+		t.Fatalf("Allow() for another key error = %v", err)
+	}
+
+	now = now.Add(10 * time.Second)
+	if err := limiter.Allow(t.Context(), "login:ip:192.0.2.1"); err != nil {
+		t.Fatalf("Allow() after refill error = %v", err)
+	}
+	if err := limiter.Allow(t.Context(), "login:ip:192.0.2.1"); err == nil {
+		t.Fatal("Allow() refilled more than one token")
+	}
+}
+
+func TestLimiterLockoutBackoff(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+	limiter.config.Burst = 100
+
+	key := "login:name:someone"
+	limiter.Fail(t.Context(), key)
+	if err := limiter.Allow(t.Context(), key); err != nil {
+		t.Fatalf("Allow() below the failure threshold error = %v", err)
+	}
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+		limiter.Fail(t.Context(), key)
+		var limited *LimitedError
+		if err := limiter.Allow(t.Context(), key); !errors.As(err, &limited) || limited.RetryAfter != want {
+			t.Fatalf("Allow() after failure error = %v, want lockout %s", err, want)
+		}
+		now = now.Add(want)
+		if err := limiter.Allow(t.Context(), key); err != nil {
+			t.Fatalf("Allow() after lockout %s error = %v", want, err)
+		}
+	}
+
+	limiter.Reset(t.Context(), key)
+	limiter.Fail(t.Context(), key)
+	if err := limiter.Allow(t.Context(), key); err != nil {
+		t.Fatalf("Allow() after reset error = %v", err)
+	}
+
+	now = now.Add(6 * time.Minute)
+	limiter.Fail(t.Context(), key)
+	if err := limiter.Allow(t.Context(), key); err != nil {
+		t.Fatalf("Allow() after failures expired error = %v", err)
+	}
+}
+
+func TestMemoryStoreDropsExpiredEntries(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	limiter := New(store, Config{Burst: 3, PerMinute: 6, FailureThreshold: 2, Lockout: time.Minute, MaxLockout: 5 * time.Minute})
+	limiter.now = func() time.Time { return now }
+
+	if err := limiter.Allow(t.Context(), "first"); err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	limiter.Fail(t.Context(), "second")
+
+	now = now.Add(2 * time.Minute)
+	if err := limiter.Allow(t.Context(), "third"); err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if _, ok := store.entries["first"]; ok {
+		t.Error("memory store kept a refilled entry")
+	}
+	if _, ok := store.entries["second"]; !ok {
+		t.Error("memory store dropped an entry with recent failures")
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	var limiter *Limiter
+	limiter.Fail(t.Context(), "key")
+	limiter.Reset(t.Context(), "key")
+	if err := limiter.Allow(t.Context(), "key"); err != nil {
+		t.Fatalf("nil Limiter.Allow() error = %v", err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	keys := Keys("login", "192.0.2.1", "name:someone")
+	if len(keys) != 2 || keys[0] != "login:ip:192.0.2.1" || keys[1] != "login:name:someone" {
+		t.Errorf("Keys() = %q", keys)
+	}
+	if keys := Keys("admin-login", "", "password"); len(keys) != 1 || keys[0] != "admin-login:password" {
+		t.Errorf("Keys() without IP = %q", keys)
+	}
+}
+
+func TestTooManyRequests(t *testing.T) {
+	e := echo.New()
+	response := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), response)
+
+	err := TooManyRequests(c, &LimitedError{RetryAfter: 1500 * time.Millisecond})
+	var httpError *echo.HTTPError
+	if !errors.As(err, &httpError) || httpError.Code != http.StatusTooManyRequests {
+		t.Fatalf("TooManyRequests() = %v, want 429", err)
+	}
+	if got := response.Header().Get(echo.HeaderRetryAfter); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+}
+
+func newTestLimiter(now *time.Time) *Limiter {
+	limiter := New(NewMemoryStore(), Config{
+		Enabled:          true,
+		Burst:            3,
+		PerMinute:        6,
+		FailureThreshold: 2,
+		Lockout:          time.Minute,
+		MaxLockout:       5 * time.Minute,
+	})
+	limiter.now = func() time.Time { return *now }
+	return limiter
+}
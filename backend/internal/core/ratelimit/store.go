@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type State struct {
+	Tokens      float64
+	UpdatedAt   time.Time
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+	ExpiresAt   time.Time
+}
+
+type Store interface {
+	Update(ctx context.Context, key string, now time.Time, fn func(state *State)) error
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]State
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]State)}
+}
+
+func (s *MemoryStore) Update(_ context.Context, key string, now time.Time, fn func(state *State)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for entryKey, entry := range s.entries {
+			if entry.ExpiresAt.Before(now) {
+				delete(s.entries, entryKey)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	state, ok := s.entries[key]
+	if ok && state.ExpiresAt.Before(now) {
+		state = State{}
+	}
+	fn(&state)
+	s.entries[key] = state
+	return nil
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+)
+
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+type clientContextKey struct{}
+
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, client)
+}
+
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(clientContextKey{}).(Client)
+	return client
+}
+
+func ClientMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := WithClient(req.Context(), Client{IP: c.RealIP(), UserAgent: req.UserAgent()})
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
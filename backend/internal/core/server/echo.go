@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/azaviyalov/null3/backend/internal/core/logging"
+	"github.com/azaviyalov/null3/backend/internal/core/metrics"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -23,14 +25,19 @@ func NewEchoServer(config Config) *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
 
 	if config.EnableCORS {
 		e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 			AllowOrigins:     []string{config.FrontendURL},
 			AllowCredentials: true,
+			ExposeHeaders:    []string{"ETag"},
 		}))
 	}
 
+	e.Use(ClientMiddleware())
+	e.Use(tracing.Middleware())
+	e.Use(metrics.Middleware())
 	e.Use(logging.RequestLogger())
 	e.Use(middleware.Recover())
 
@@ -110,6 +110,51 @@ func TestNewEchoServerRecoversFromPanic(t *testing.T) {
 	}
 }
 
+func TestNewEchoServerTrustsForwardedForOnlyFromPrivateProxies(t *testing.T) {
+	testutil.DiscardLogs(t)
+	e := server.NewEchoServer(server.Config{})
+	e.GET("/ip", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.RealIP())
+	})
+
+	tests := []struct {
+		remoteAddr string
+		want       string
+	}{
+		{remoteAddr: "198.51.100.7:40000", want: "198.51.100.7"},
+		{remoteAddr: "10.0.0.2:40000", want: "203.0.113.9"},
+	}
+	for _, tt := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		request.RemoteAddr = tt.remoteAddr
+		request.Header.Set(echo.HeaderXForwardedFor, "203.0.113.9")
+		response := httptest.NewRecorder()
+		e.ServeHTTP(response, request)
+		if response.Body.String() != tt.want {
+			t.Errorf("RealIP() from %s = %q, want %q", tt.remoteAddr, response.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestNewEchoServerStoresClientInContext(t *testing.T) {
+	testutil.DiscardLogs(t)
+	e := server.NewEchoServer(server.Config{})
+	var client server.Client
+	e.GET("/", func(c echo.Context) error {
+		client = server.ClientFromContext(c.Request().Context())
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = "198.51.100.4:5555"
+	request.Header.Set("User-Agent", "journal-browser")
+	e.ServeHTTP(httptest.NewRecorder(), request)
+
+	if client.IP != "198.51.100.4" || client.UserAgent != "journal-browser" {
+		t.Errorf("client = %+v, want remote IP and user agent", client)
+	}
+}
+
 func TestStartServerWrapsStartupError(t *testing.T) {
 	testutil.DiscardLogs(t)
 	e := echo.New()
@@ -0,0 +1,34 @@
+package tracing
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+var exporters = []string{ExporterNone, ExporterOTLP, ExporterStdout}
+
+type Config struct {
+	Exporter string
+}
+
+func GetConfig() (Config, error) {
+	config := Config{Exporter: ExporterNone}
+
+	if exporter := os.Getenv("TRACING_EXPORTER"); exporter != "" {
+		exporter = strings.ToLower(exporter)
+		if !slices.Contains(exporters, exporter) {
+			return Config{}, fmt.Errorf("TRACING_EXPORTER must be one of %s", strings.Join(exporters, ", "))
+		}
+		config.Exporter = exporter
+	}
+
+	return config, nil
+}
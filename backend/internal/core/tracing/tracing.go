@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/azaviyalov/null3/backend/internal/core/db"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	instrumentationName = "github.com/azaviyalov/null3/backend"
+	serviceName         = "null3"
+	querySpanKey        = "tracing:span"
+)
+
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	return setup(ctx, config, os.Stdout)
+}
+
+func setup(ctx context.Context, config Config, stdout io.Writer) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(stdout))
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s trace exporter: %w", config.Exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			request := c.Request()
+			route := c.Path()
+			ctx := otel.GetTextMapPropagator().Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+			ctx, span := Start(ctx, request.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", request.Method),
+					attribute.String("http.route", route),
+					attribute.String("url.path", request.URL.Path),
+				),
+			)
+			defer span.End()
+			c.SetRequest(request.WithContext(ctx))
+
+			err := next(c)
+
+			status := c.Response().Status
+			span.SetAttributes(
+				attribute.Int("http.response.status_code", status),
+				attribute.String("http.request.id", c.Response().Header().Get(echo.HeaderXRequestID)),
+			)
+			if status >= 500 {
+				span.SetStatus(codes.Error, "")
+				if err != nil {
+					span.RecordError(err)
+				}
+			}
+			return err
+		}
+	}
+}
+
+func GormPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+func (gormPlugin) Initialize(database *gorm.DB) error {
+	return db.RegisterStatementHooks(database, "tracing",
+		func(database *gorm.DB, operation string) {
+			ctx, span := Start(database.Statement.Context, "db."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attribute.String("db.system.name", database.Dialector.Name())),
+			)
+			database.Statement.Context = ctx
+			database.InstanceSet(querySpanKey, span)
+		},
+		func(database *gorm.DB, _ string) {
+			value, ok := database.InstanceGet(querySpanKey)
+			if !ok {
+				return
+			}
+			span := value.(trace.Span)
+			defer span.End()
+
+			span.SetAttributes(
+				attribute.String("db.query.text", database.Statement.SQL.String()),
+				attribute.Int64("db.response.returned_rows", database.Statement.RowsAffected),
+			)
+			if database.Statement.Table != "" {
+				span.SetAttributes(attribute.String("db.collection.name", database.Statement.Table))
+			}
+			if err := database.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+		},
+	)
+}
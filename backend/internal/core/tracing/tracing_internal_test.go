@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetupStdoutExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var out bytes.Buffer
+	shutdown, err := setup(t.Context(), Config{Exporter: ExporterStdout}, &out)
+	if err != nil {
+		t.Fatalf("setup() error = %v", err)
+	}
+	_, span := Start(t.Context(), "journal.CreateDiaryEntry")
+	span.End()
+	if err := shutdown(t.Context()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	if !strings.Contains(out.String(), `"Name":"journal.CreateDiaryEntry"`) {
+		t.Fatalf("stdout exporter output = %q, want the span", out.String())
+	}
+}
@@ -0,0 +1,115 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestGetConfig(t *testing.T) {
+	for value, want := range map[string]string{
+		"":       tracing.ExporterNone,
+		"otlp":   tracing.ExporterOTLP,
+		"STDOUT": tracing.ExporterStdout,
+	} {
+		t.Setenv("TRACING_EXPORTER", value)
+		config, err := tracing.GetConfig()
+		if err != nil || config.Exporter != want {
+			t.Fatalf("GetConfig() with %q = %+v, %v, want %s", value, config, err, want)
+		}
+	}
+
+	t.Setenv("TRACING_EXPORTER", "zipkin")
+	if _, err := tracing.GetConfig(); err == nil || !strings.Contains(err.Error(), "TRACING_EXPORTER") {
+		t.Fatalf("GetConfig() error = %v, want TRACING_EXPORTER error", err)
+	}
+}
+
+func TestRequestSpansNestServiceAndQuerySpans(t *testing.T) {
+	testutil.SkipIntegration(t)
+	testutil.DiscardLogs(t)
+	recorder := installSpanRecorder(t)
+	database := testutil.NewDatabase(t, "tracing.sqlite")
+	if err := database.Use(tracing.GormPlugin()); err != nil {
+		t.Fatalf("register plugin: %v", err)
+	}
+
+	e := server.NewEchoServer(server.Config{})
+	e.GET("/api/users/:id", func(c echo.Context) error {
+		ctx, span := tracing.Start(c.Request().Context(), "account.GetUserByID")
+		defer span.End()
+		var count int64
+		if err := database.WithContext(ctx).Table("users").Where("id = ?", c.Param("id")).Count(&count).Error; err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	request := httptest.NewRequest(http.MethodGet, "/api/users/7", nil)
+	request.Header.Set(echo.HeaderXRequestID, "request-7")
+	e.ServeHTTP(httptest.NewRecorder(), request)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	serverSpan, serviceSpan, querySpan := spans["GET /api/users/:id"], spans["account.GetUserByID"], spans["db.query"]
+	if serverSpan == nil || serviceSpan == nil || querySpan == nil {
+		t.Fatalf("recorded spans = %v, want server, service and query spans", spanNames(recorder))
+	}
+	if serviceSpan.Parent().SpanID() != serverSpan.SpanContext().SpanID() {
+		t.Error("service span is not a child of the server span")
+	}
+	if querySpan.Parent().SpanID() != serviceSpan.SpanContext().SpanID() {
+		t.Error("query span is not a child of the service span")
+	}
+	assertAttribute(t, serverSpan, "http.request.id", attribute.StringValue("request-7"))
+	assertAttribute(t, serverSpan, "http.response.status_code", attribute.IntValue(http.StatusNoContent))
+	assertAttribute(t, querySpan, "db.system.name", attribute.StringValue(database.Dialector.Name()))
+	assertAttribute(t, querySpan, "db.collection.name", attribute.StringValue("users"))
+}
+
+func installSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(t.Context())
+	})
+	return recorder
+}
+
+func spanNames(recorder *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+func assertAttribute(t *testing.T, span sdktrace.ReadOnlySpan, key string, want attribute.Value) {
+	t.Helper()
+
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			if attr.Value != want {
+				t.Errorf("%s attribute %s = %v, want %v", span.Name(), key, attr.Value.Emit(), want.Emit())
+			}
+			return
+		}
+	}
+	t.Errorf("%s has no %s attribute", span.Name(), key)
+}
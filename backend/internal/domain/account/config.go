@@ -2,13 +2,23 @@ package account
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
+const (
+	minTOTPEncryptionKeyLength = 32
+	defaultFrontendURL         = "http://localhost:4200"
+)
+
 type Config struct {
 	PasswordResetTokenExpiration time.Duration
 	FrontendURL                  string
+	TOTPEncryptionKey            string
+	WebAuthnRPID                 string
+	WebAuthnOrigins              []string
 }
 
 func GetConfig() (Config, error) {
@@ -27,5 +37,29 @@ func GetConfig() (Config, error) {
 		config.PasswordResetTokenExpiration = resetExpiration
 	}
 
+	config.TOTPEncryptionKey = os.Getenv("TOTP_ENCRYPTION_KEY")
+	if config.TOTPEncryptionKey != "" && len(config.TOTPEncryptionKey) < minTOTPEncryptionKeyLength {
+		return Config{}, fmt.Errorf("TOTP_ENCRYPTION_KEY must be at least %d characters", minTOTPEncryptionKeyLength)
+	}
+
+	config.WebAuthnRPID = os.Getenv("WEBAUTHN_RP_ID")
+	if originsParam := os.Getenv("WEBAUTHN_ORIGINS"); originsParam != "" {
+		for origin := range strings.SplitSeq(originsParam, ",") {
+			origin = strings.TrimSpace(origin)
+			if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+				return Config{}, fmt.Errorf("WEBAUTHN_ORIGINS must be a comma-separated list of absolute URLs, got %q", origin)
+			}
+			config.WebAuthnOrigins = append(config.WebAuthnOrigins, origin)
+		}
+	}
+
 	return config, nil
 }
+
+func frontendBaseURL(config Config) string {
+	baseURL := strings.TrimRight(config.FrontendURL, "/")
+	if baseURL == "" {
+		return defaultFrontendURL
+	}
+	return baseURL
+}
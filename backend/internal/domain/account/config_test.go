@@ -43,3 +43,40 @@ func TestGetConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestGetConfigTOTPEncryptionKey(t *testing.T) {
+	t.Setenv("TOTP_ENCRYPTION_KEY", "")
+	config, err := account.GetConfig()
+	if err != nil || config.TOTPEncryptionKey != "" {
+		t.Fatalf("GetConfig() = %+v, %v; want no TOTP encryption key", config, err)
+	}
+
+	t.Setenv("TOTP_ENCRYPTION_KEY", "too-short")
+	if _, err := account.GetConfig(); err == nil || !strings.Contains(err.Error(), "TOTP_ENCRYPTION_KEY must be at least") {
+		t.Fatalf("GetConfig() error = %v, want short key error", err)
+	}
+
+	key := strings.Repeat("k", 32)
+	t.Setenv("TOTP_ENCRYPTION_KEY", key)
+	config, err = account.GetConfig()
+	if err != nil || config.TOTPEncryptionKey != key {
+		t.Fatalf("GetConfig() = %+v, %v; want TOTP encryption key", config, err)
+	}
+}
+
+func TestGetConfigWebAuthn(t *testing.T) {
+	t.Setenv("WEBAUTHN_RP_ID", "journal.example")
+	t.Setenv("WEBAUTHN_ORIGINS", "https://journal.example, https://app.journal.example")
+	config, err := account.GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig() error = %v", err)
+	}
+	if config.WebAuthnRPID != "journal.example" || len(config.WebAuthnOrigins) != 2 || config.WebAuthnOrigins[1] != "https://app.journal.example" {
+		t.Fatalf("GetConfig() = %+v, want WebAuthn relying party settings", config)
+	}
+
+	t.Setenv("WEBAUTHN_ORIGINS", "journal.example")
+	if _, err := account.GetConfig(); err == nil || !strings.Contains(err.Error(), "WEBAUTHN_ORIGINS") {
+		t.Fatalf("GetConfig() error = %v, want invalid origin error", err)
+	}
+}
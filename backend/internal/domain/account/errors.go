@@ -1,6 +1,9 @@
 package account
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrInvalidCredentials        = errors.New("invalid credentials")
@@ -11,4 +14,26 @@ var (
 	ErrInviteAlreadyUsed         = errors.New("invite already used")
 	ErrPasswordResetTokenInvalid = errors.New("invalid password reset token")
 	ErrPasswordResetTokenExpired = errors.New("password reset token expired")
+	ErrSecondFactorRequired      = errors.New("second factor required")
+	ErrSecondFactorInvalid       = errors.New("invalid second factor challenge")
+	ErrTwoFactorCodeInvalid      = errors.New("invalid two-factor code")
+	ErrTwoFactorUnavailable      = errors.New("two-factor authentication is not configured")
+	ErrTwoFactorAlreadyEnabled   = errors.New("two-factor authentication already enabled")
+	ErrTwoFactorNotEnabled       = errors.New("two-factor authentication not enabled")
+	ErrTwoFactorNotEnrolling     = errors.New("two-factor enrollment not started")
+	ErrPasskeysUnavailable       = errors.New("passkeys are not configured")
+	ErrPasskeyInvalid            = errors.New("invalid passkey response")
 )
+
+type SecondFactorChallenge struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+func (c *SecondFactorChallenge) Error() string {
+	return ErrSecondFactorRequired.Error()
+}
+
+func (c *SecondFactorChallenge) Unwrap() error {
+	return ErrSecondFactorRequired
+}
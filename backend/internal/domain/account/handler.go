@@ -4,18 +4,35 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/ratelimit"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
+	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/labstack/echo/v4"
 )
 
 func RegisterRoutes(e *echo.Echo, handler *Handler, userJWT echo.MiddlewareFunc) {
 	e.POST("/api/auth/login", handler.Login)
+	e.POST("/api/auth/login/two-factor", handler.LoginTwoFactor)
+	e.POST("/api/auth/login/passkey/begin", handler.BeginPasskeyLogin)
+	e.POST("/api/auth/login/passkey/finish", handler.FinishPasskeyLogin)
 	e.POST("/api/auth/logout", handler.Logout, userJWT)
 	e.POST("/api/auth/refresh", handler.Refresh)
 	e.GET("/api/auth/me", handler.Me, userJWT)
+	e.GET("/api/auth/me/activity", handler.Activity, userJWT)
+	e.GET("/api/auth/me/two-factor", handler.TwoFactorStatus, userJWT)
+	e.POST("/api/auth/me/two-factor/enroll", handler.EnrollTwoFactor, userJWT)
+	e.POST("/api/auth/me/two-factor/confirm", handler.ConfirmTwoFactor, userJWT)
+	e.POST("/api/auth/me/two-factor/disable", handler.DisableTwoFactor, userJWT)
+	e.GET("/api/auth/me/passkeys", handler.ListPasskeys, userJWT)
+	e.POST("/api/auth/me/passkeys/register/begin", handler.BeginPasskeyRegistration, userJWT)
+	e.POST("/api/auth/me/passkeys/register/finish", handler.FinishPasskeyRegistration, userJWT)
+	e.PUT("/api/auth/me/passkeys/:id", handler.RenamePasskey, userJWT)
+	e.DELETE("/api/auth/me/passkeys/:id", handler.DeletePasskey, userJWT)
 	e.POST("/api/auth/forgot-password", handler.ForgotPassword)
 	e.POST("/api/auth/reset-password", handler.ResetPassword)
 	e.GET("/api/auth/invites/:token", handler.GetInvite)
@@ -49,9 +66,20 @@ func (h *Handler) Login(c echo.Context) error {
 
 	res, tokenData, err := h.service.AuthenticateUser(c.Request().Context(), req)
 	if err != nil {
+		var challenge *SecondFactorChallenge
+		if errors.As(err, &challenge) {
+			return c.JSON(http.StatusUnauthorized, SecondFactorChallengeResponse{
+				Message:   "Two-factor authentication code required.",
+				Challenge: challenge.Token,
+				ExpiresAt: challenge.ExpiresAt,
+			})
+		}
 		if errors.Is(err, ErrInvalidCredentials) {
 			return newHTTPError(http.StatusUnauthorized, "Incorrect login credentials.", err)
 		}
+		if errors.Is(err, ratelimit.ErrLimited) {
+			return ratelimit.TooManyRequests(c, err)
+		}
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
 
@@ -59,6 +87,59 @@ func (h *Handler) Login(c echo.Context) error {
 	return c.JSON(http.StatusOK, res)
 }
 
+func (h *Handler) LoginTwoFactor(c echo.Context) error {
+	var req SecondFactorLoginRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	res, tokenData, err := h.service.CompleteSecondFactor(c.Request().Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrSecondFactorInvalid):
+			return newHTTPError(http.StatusUnauthorized, "This sign-in attempt has expired. Please log in again.", err)
+		case errors.Is(err, ErrTwoFactorCodeInvalid):
+			return newHTTPError(http.StatusUnauthorized, "Incorrect two-factor code.", err)
+		case errors.Is(err, ratelimit.ErrLimited):
+			return ratelimit.TooManyRequests(c, err)
+		default:
+			return echo.ErrInternalServerError.WithInternal(err)
+		}
+	}
+
+	session.SetUserSessionCookies(c, h.sessionConfig, tokenData)
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) BeginPasskeyLogin(c echo.Context) error {
+	assertion, err := h.service.BeginPasskeyLogin(c.Request().Context())
+	if err != nil {
+		return passkeyHTTPError(c, err)
+	}
+	return c.JSON(http.StatusOK, assertion)
+}
+
+func (h *Handler) FinishPasskeyLogin(c echo.Context) error {
+	var req protocol.CredentialAssertionResponse
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	res, tokenData, err := h.service.FinishPasskeyLogin(c.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, ErrPasskeyInvalid) {
+			return newHTTPError(http.StatusUnauthorized, "This passkey could not be verified.", err)
+		}
+		return passkeyHTTPError(c, err)
+	}
+
+	session.SetUserSessionCookies(c, h.sessionConfig, tokenData)
+	return c.JSON(http.StatusOK, res)
+}
+
 func (h *Handler) Logout(c echo.Context) error {
 	if refreshCookie, err := c.Cookie(session.UserRefreshCookieName); err == nil && refreshCookie != nil {
 		if err := h.sessionService.InvalidateRefreshToken(c.Request().Context(), refreshCookie.Value); err != nil {
@@ -80,6 +161,139 @@ func (h *Handler) Me(c echo.Context) error {
 	return c.JSON(http.StatusOK, NewUserResponse(user))
 }
 
+func (h *Handler) Activity(c echo.Context) error {
+	limit, offset, err := audit.ParsePagination(c)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	page, err := h.service.ListActivity(c.Request().Context(), session.GetUserID(c), limit, offset)
+	if err != nil {
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) TwoFactorStatus(c echo.Context) error {
+	status, err := h.service.GetTwoFactorStatus(c.Request().Context(), session.GetUserID(c))
+	if err != nil {
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+func (h *Handler) EnrollTwoFactor(c echo.Context) error {
+	enrollment, err := h.service.BeginTwoFactorEnrollment(c.Request().Context(), session.GetUserID(c))
+	if err != nil {
+		return twoFactorHTTPError(c, err)
+	}
+	return c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *Handler) ConfirmTwoFactor(c echo.Context) error {
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	codes, err := h.service.ConfirmTwoFactor(c.Request().Context(), session.GetUserID(c), req)
+	if err != nil {
+		return twoFactorHTTPError(c, err)
+	}
+	return c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *Handler) DisableTwoFactor(c echo.Context) error {
+	var req DisableTwoFactorRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	if err := h.service.DisableTwoFactor(c.Request().Context(), session.GetUserID(c), req); err != nil {
+		return twoFactorHTTPError(c, err)
+	}
+	return emptyJSON(c, http.StatusOK)
+}
+
+func (h *Handler) ListPasskeys(c echo.Context) error {
+	passkeys, err := h.service.ListPasskeys(c.Request().Context(), session.GetUserID(c))
+	if err != nil {
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, passkeys)
+}
+
+func (h *Handler) BeginPasskeyRegistration(c echo.Context) error {
+	var req PasskeyRegistrationBeginRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	creation, err := h.service.BeginPasskeyRegistration(c.Request().Context(), session.GetUserID(c), req)
+	if err != nil {
+		return passkeyHTTPError(c, err)
+	}
+	return c.JSON(http.StatusOK, creation)
+}
+
+func (h *Handler) FinishPasskeyRegistration(c echo.Context) error {
+	var req PasskeyRegistrationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	passkey, err := h.service.FinishPasskeyRegistration(c.Request().Context(), session.GetUserID(c), req)
+	if err != nil {
+		return passkeyHTTPError(c, err)
+	}
+	return c.JSON(http.StatusCreated, passkey)
+}
+
+func (h *Handler) RenamePasskey(c echo.Context) error {
+	id, err := parsePasskeyID(c)
+	if err != nil {
+		return err
+	}
+	var req PasskeyRenameRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	passkey, err := h.service.RenamePasskey(c.Request().Context(), session.GetUserID(c), id, req)
+	if err != nil {
+		return passkeyHTTPError(c, err)
+	}
+	return c.JSON(http.StatusOK, passkey)
+}
+
+func (h *Handler) DeletePasskey(c echo.Context) error {
+	id, err := parsePasskeyID(c)
+	if err != nil {
+		return err
+	}
+
+	passkey, err := h.service.DeletePasskey(c.Request().Context(), session.GetUserID(c), id)
+	if err != nil {
+		return passkeyHTTPError(c, err)
+	}
+	return c.JSON(http.StatusOK, passkey)
+}
+
 func (h *Handler) Refresh(c echo.Context) error {
 	refreshCookie, err := c.Cookie(session.UserRefreshCookieName)
 	if err != nil {
@@ -130,6 +344,8 @@ func (h *Handler) RegisterWithInvite(c echo.Context) error {
 			return newHTTPError(http.StatusBadRequest, inviteErrorMessage(err), err)
 		case errors.Is(err, ErrLoginAlreadyTaken), errors.Is(err, ErrEmailAlreadyTaken):
 			return newHTTPError(http.StatusConflict, clientErrorMessage(err), err)
+		case errors.Is(err, ratelimit.ErrLimited):
+			return ratelimit.TooManyRequests(c, err)
 		default:
 			return echo.ErrInternalServerError.WithInternal(err)
 		}
@@ -150,6 +366,9 @@ func (h *Handler) ForgotPassword(c echo.Context) error {
 
 	rawToken, err := h.service.RequestPasswordReset(c.Request().Context(), req)
 	if err != nil {
+		if errors.Is(err, ratelimit.ErrLimited) {
+			return ratelimit.TooManyRequests(c, err)
+		}
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
 
@@ -178,6 +397,8 @@ func (h *Handler) ResetPassword(c echo.Context) error {
 			return newHTTPError(http.StatusBadRequest, clientErrorMessage(err), err)
 		case errors.Is(err, ErrPasswordResetTokenInvalid), errors.Is(err, ErrPasswordResetTokenExpired):
 			return newHTTPError(http.StatusBadRequest, resetPasswordErrorMessage(err), err)
+		case errors.Is(err, ratelimit.ErrLimited):
+			return ratelimit.TooManyRequests(c, err)
 		default:
 			return echo.ErrInternalServerError.WithInternal(err)
 		}
@@ -189,11 +410,7 @@ func (h *Handler) ResetPassword(c echo.Context) error {
 }
 
 func (h *Handler) frontendURL(path string) string {
-	baseURL := strings.TrimRight(h.config.FrontendURL, "/")
-	if baseURL == "" {
-		baseURL = "http://localhost:4200"
-	}
-	return baseURL + path
+	return frontendBaseURL(h.config) + path
 }
 
 func isInviteError(err error) bool {
@@ -211,6 +428,58 @@ func inviteErrorMessage(err error) string {
 	}
 }
 
+func twoFactorHTTPError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrTwoFactorUnavailable):
+		return newHTTPError(http.StatusServiceUnavailable, "Two-factor authentication is not configured on this server.", err)
+	case errors.Is(err, ErrTwoFactorAlreadyEnabled):
+		return newHTTPError(http.StatusConflict, "Two-factor authentication is already enabled.", err)
+	case errors.Is(err, ErrTwoFactorNotEnabled):
+		return newHTTPError(http.StatusBadRequest, "Two-factor authentication is not enabled.", err)
+	case errors.Is(err, ErrTwoFactorNotEnrolling):
+		return newHTTPError(http.StatusBadRequest, "Start two-factor enrollment first.", err)
+	case errors.Is(err, ErrInvalidCredentials):
+		return newHTTPError(http.StatusForbidden, "Incorrect password.", err)
+	case errors.Is(err, ErrTwoFactorCodeInvalid):
+		return newHTTPError(http.StatusForbidden, "Incorrect two-factor code.", err)
+	case errors.Is(err, ratelimit.ErrLimited):
+		return ratelimit.TooManyRequests(c, err)
+	default:
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+}
+
+func passkeyHTTPError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrPasskeysUnavailable):
+		return newHTTPError(http.StatusServiceUnavailable, "Passkeys are not configured on this server.", err)
+	case errors.Is(err, ErrPasskeyInvalid):
+		return newHTTPError(http.StatusBadRequest, "This passkey could not be verified.", err)
+	case errors.Is(err, ErrInvalidCredentials):
+		return newHTTPError(http.StatusForbidden, "Incorrect password.", err)
+	case errors.Is(err, ErrSecondFactorRequired):
+		return newHTTPError(http.StatusForbidden, "Enter a two-factor code.", err)
+	case errors.Is(err, ErrTwoFactorCodeInvalid):
+		return newHTTPError(http.StatusForbidden, "Incorrect two-factor code.", err)
+	case errors.Is(err, core.ErrInvalidItem):
+		return newHTTPError(http.StatusBadRequest, clientErrorMessage(err), err)
+	case errors.Is(err, core.ErrItemNotFound):
+		return echo.ErrNotFound.WithInternal(err)
+	case errors.Is(err, ratelimit.ErrLimited):
+		return ratelimit.TooManyRequests(c, err)
+	default:
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+}
+
+func parsePasskeyID(c echo.Context) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.ErrBadRequest.WithInternal(err)
+	}
+	return uint(id), nil
+}
+
 func resetPasswordErrorMessage(err error) string {
 	if errors.Is(err, ErrPasswordResetTokenExpired) {
 		return "This password reset link has expired."
@@ -9,8 +9,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/azaviyalov/null3/backend/internal/core"
 	"github.com/azaviyalov/null3/backend/internal/core/server"
 	"github.com/azaviyalov/null3/backend/internal/domain/account"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"github.com/azaviyalov/null3/backend/internal/testutil"
 	"github.com/labstack/echo/v4"
@@ -78,6 +80,25 @@ func TestAccountSessionHTTPFlow(t *testing.T) {
 	if refreshTokenCount != 0 {
 		t.Fatalf("refresh token count = %d, want 0", refreshTokenCount)
 	}
+
+	activityResponse := testutil.JSONRequest(t, e, http.MethodGet, "/api/auth/me/activity?limit=1", nil, newAccessCookie)
+	if activityResponse.Code != http.StatusOK {
+		t.Fatalf("activity status = %d, want %d", activityResponse.Code, http.StatusOK)
+	}
+	var activity core.Page[audit.Event]
+	testutil.DecodeJSON(t, activityResponse, &activity)
+	if activity.TotalCount != 2 || len(activity.Items) != 1 {
+		t.Fatalf("activity = %d items of %d, want 1 of 2", len(activity.Items), activity.TotalCount)
+	}
+	if event := activity.Items[0]; event.Type != audit.TypeLogout || event.Outcome != audit.OutcomeSuccess || event.IP == "" {
+		t.Errorf("latest activity = %+v, want logout with client IP", event)
+	}
+	if response := testutil.JSONRequest(t, e, http.MethodGet, "/api/auth/me/activity?offset=-1", nil, newAccessCookie); response.Code != http.StatusBadRequest {
+		t.Errorf("invalid activity offset status = %d, want %d", response.Code, http.StatusBadRequest)
+	}
+	if response := testutil.JSONRequest(t, e, http.MethodGet, "/api/auth/me/activity", nil); response.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous activity status = %d, want %d", response.Code, http.StatusUnauthorized)
+	}
 }
 
 func TestAccountLoginHTTPRejections(t *testing.T) {
@@ -127,6 +148,60 @@ func TestAccountLoginHTTPRejections(t *testing.T) {
 	}
 }
 
+func TestAccountRateLimitHTTP(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newAccountTestEnvironment(t)
+	createTestUser(t, environment, "journal_user", "person@example.test")
+	e := newAccountTestServer(t, environment)
+
+	wrongPassword := `{"login":"journal_user","password":"incorrect-password"}`
+	for range 3 {
+		if response := postFrom(e, "/api/auth/login", wrongPassword, "192.0.2.10"); response.Code != http.StatusUnauthorized {
+			t.Fatalf("wrong password status = %d, want %d", response.Code, http.StatusUnauthorized)
+		}
+	}
+	lockedResponse := postFrom(e, "/api/auth/login", `{"login":"JOURNAL_USER","password":"correct-password"}`, "198.51.100.20")
+	if lockedResponse.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked login status = %d, want %d", lockedResponse.Code, http.StatusTooManyRequests)
+	}
+	if retryAfter := lockedResponse.Header().Get(echo.HeaderRetryAfter); retryAfter != "60" {
+		t.Errorf("locked login Retry-After = %q, want 60", retryAfter)
+	}
+	if response := postFrom(e, "/api/auth/login", `{"login":"someone_else","password":"incorrect-password"}`, "192.0.2.10"); response.Code != http.StatusTooManyRequests {
+		t.Errorf("locked IP status = %d, want %d", response.Code, http.StatusTooManyRequests)
+	}
+	if response := postFrom(e, "/api/auth/login", `{"login":"someone_else","password":"incorrect-password"}`, "203.0.113.30"); response.Code != http.StatusUnauthorized {
+		t.Errorf("unrelated login status = %d, want %d", response.Code, http.StatusUnauthorized)
+	}
+
+	for range 10 {
+		if response := postFrom(e, "/api/auth/forgot-password", `{"email":"unknown@example.test"}`, "192.0.2.40"); response.Code != http.StatusOK {
+			t.Fatalf("forgot password status = %d, want %d", response.Code, http.StatusOK)
+		}
+	}
+	if response := postFrom(e, "/api/auth/forgot-password", `{"email":"unknown@example.test"}`, "192.0.2.40"); response.Code != http.StatusTooManyRequests {
+		t.Errorf("forgot password over burst status = %d, want %d", response.Code, http.StatusTooManyRequests)
+	}
+
+	endpoints := []struct {
+		path string
+		body string
+	}{
+		{path: "/api/auth/reset-password", body: `{"token":"unknown-token","password":"new-correct-password"}`},
+		{path: "/api/auth/invites/unknown-token/register", body: `{"login":"new_user","email":"new@example.test","password":"correct-password"}`},
+	}
+	for _, endpoint := range endpoints {
+		for range 3 {
+			if response := postFrom(e, endpoint.path, endpoint.body, "192.0.2.50"); response.Code != http.StatusBadRequest {
+				t.Fatalf("%s status = %d, want %d", endpoint.path, response.Code, http.StatusBadRequest)
+			}
+		}
+		if response := postFrom(e, endpoint.path, endpoint.body, "192.0.2.50"); response.Code != http.StatusTooManyRequests {
+			t.Errorf("%s after failures status = %d, want %d", endpoint.path, response.Code, http.StatusTooManyRequests)
+		}
+	}
+}
+
 func TestAccountInviteHTTPFlow(t *testing.T) {
 	testutil.SkipIntegration(t)
 	environment := newAccountTestEnvironment(t)
@@ -323,3 +398,15 @@ func newAccountTestServer(t *testing.T, environment *accountTestEnvironment) *ec
 	account.RegisterRoutes(e, handler, session.UserJWTMiddleware(environment.sessionService, validateUser))
 	return e
 }
+
+func postFrom(e *echo.Echo, path, body, ip string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	request.RemoteAddr = ip + ":40000"
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+	e.ServeHTTP(response, request)
+	return response
+}
@@ -1,14 +1,24 @@
 package account
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
-	ID           uint      `json:"id" gorm:"primaryKey"`
-	Login        string    `json:"login" gorm:"not null;uniqueIndex"`
-	Email        string    `json:"email" gorm:"not null;uniqueIndex"`
-	PasswordHash string    `json:"-" gorm:"not null"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	Login         string     `json:"login" gorm:"not null;uniqueIndex"`
+	Email         string     `json:"email" gorm:"not null;uniqueIndex"`
+	PasswordHash  string     `json:"-" gorm:"not null"`
+	TOTPSecret    string     `json:"-" gorm:"not null;default:''"`
+	TOTPEnabledAt *time.Time `json:"-"`
+	TOTPLastStep  int64      `json:"-" gorm:"not null;default:0"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
 }
 
 type PasswordResetToken struct {
@@ -19,6 +29,31 @@ type PasswordResetToken struct {
 	ExpiresAt time.Time `gorm:"not null;index"`
 }
 
+type RecoveryCode struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	CodeHash  string    `gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+type Passkey struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"-" gorm:"not null;index"`
+	Name         string     `json:"name" gorm:"not null"`
+	CredentialID string     `json:"-" gorm:"not null;uniqueIndex"`
+	Credential   string     `json:"-" gorm:"not null"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"not null"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+}
+
+type PasskeyChallenge struct {
+	ID          uint      `gorm:"primaryKey"`
+	Challenge   string    `gorm:"not null;uniqueIndex"`
+	UserID      *uint     `gorm:"index"`
+	SessionData string    `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
 type Invite struct {
 	ID               uint       `gorm:"primaryKey"`
 	TokenHash        string     `gorm:"not null;uniqueIndex"`
@@ -48,20 +83,71 @@ type InviteRegistrationRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type SecondFactorLoginRequest struct {
+	Challenge string `json:"challenge" validate:"required"`
+	Code      string `json:"code" validate:"required"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type PasskeyRegistrationBeginRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code"`
+}
+
+type PasskeyRegistrationRequest struct {
+	Name       string          `json:"name" validate:"max=64"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+type PasskeyRenameRequest struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
+
 type UserResponse struct {
-	ID    uint   `json:"id"`
-	Login string `json:"login"`
-	Email string `json:"email"`
+	ID               uint   `json:"id"`
+	Login            string `json:"login"`
+	Email            string `json:"email"`
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
 }
 
 func NewUserResponse(user *User) *UserResponse {
 	return &UserResponse{
-		ID:    user.ID,
-		Login: user.Login,
-		Email: user.Email,
+		ID:               user.ID,
+		Login:            user.Login,
+		Email:            user.Email,
+		TwoFactorEnabled: user.TwoFactorEnabled(),
 	}
 }
 
+type SecondFactorChallengeResponse struct {
+	Message   string    `json:"message"`
+	Challenge string    `json:"challenge"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
 type MessageResponse struct {
 	Message string `json:"message"`
 }
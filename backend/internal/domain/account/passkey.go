@@ -0,0 +1,429 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/metrics"
+	"github.com/azaviyalov/null3/backend/internal/core/ratelimit"
+	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
+	"github.com/azaviyalov/null3/backend/internal/domain/session"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	passkeyRPDisplayName = "null3"
+	passkeyTimeout       = 5 * time.Minute
+	defaultPasskeyName   = "Passkey"
+)
+
+type passkeyUser struct {
+	user        *User
+	credentials []webauthn.Credential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	return passkeyUserHandle(u.user.ID)
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Login
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return u.user.Login
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+func newWebAuthn(config Config) (*webauthn.WebAuthn, error) {
+	origins := config.WebAuthnOrigins
+	if len(origins) == 0 {
+		origins = []string{frontendBaseURL(config)}
+	}
+	rpID := config.WebAuthnRPID
+	if rpID == "" {
+		u, err := url.Parse(origins[0])
+		if err != nil {
+			return nil, fmt.Errorf("derive WebAuthn RP ID: %w", err)
+		}
+		rpID = u.Hostname()
+	}
+
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: passkeyTimeout, TimeoutUVD: passkeyTimeout}
+	return webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: passkeyRPDisplayName,
+		RPOrigins:     origins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+}
+
+func (s *Service) BeginPasskeyRegistration(ctx context.Context, userID uint, req PasskeyRegistrationBeginRequest) (*protocol.CredentialCreation, error) {
+	ctx, span := tracing.Start(ctx, "account.BeginPasskeyRegistration")
+	defer span.End()
+
+	webAuthn, err := s.passkeys()
+	if err != nil {
+		return nil, err
+	}
+	limitKeys := passkeyRegistrationLimitKeys(ctx, userID)
+	if err := s.limiter.Allow(ctx, limitKeys...); err != nil {
+		return nil, err
+	}
+	user, err := s.loadPasskeyUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.user.PasswordHash), []byte(req.Password)); err != nil {
+		s.limiter.Fail(ctx, limitKeys...)
+		s.recordPasskeyRegistrationFailure(ctx, userID, "password")
+		return nil, ErrInvalidCredentials
+	}
+	if user.user.TwoFactorEnabled() {
+		if req.Code == "" {
+			return nil, ErrSecondFactorRequired
+		}
+		if _, err := s.verifySecondFactor(ctx, user.user, req.Code); err != nil {
+			if errors.Is(err, ErrTwoFactorCodeInvalid) {
+				s.limiter.Fail(ctx, limitKeys...)
+				s.recordPasskeyRegistrationFailure(ctx, userID, "second factor")
+			}
+			return nil, err
+		}
+	}
+	s.limiter.Reset(ctx, ratelimit.Keys("passkey-registration", "", userSubject(userID))...)
+
+	creation, sessionData, err := webAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("begin passkey registration: %w", err)
+	}
+	if err := s.savePasskeyChallenge(ctx, &userID, sessionData); err != nil {
+		return nil, err
+	}
+	return creation, nil
+}
+
+func (s *Service) FinishPasskeyRegistration(ctx context.Context, userID uint, req PasskeyRegistrationRequest) (*Passkey, error) {
+	ctx, span := tracing.Start(ctx, "account.FinishPasskeyRegistration")
+	defer span.End()
+
+	webAuthn, err := s.passkeys()
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPasskeyInvalid, err)
+	}
+	sessionData, err := s.takePasskeyChallenge(ctx, parsed.Response.CollectedClientData.Challenge, &userID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.loadPasskeyUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := webAuthn.CreateCredential(user, *sessionData, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPasskeyInvalid, err)
+	}
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return nil, fmt.Errorf("encode passkey credential: %w", err)
+	}
+
+	passkey, err := s.repo.CreatePasskey(ctx, &Passkey{
+		UserID:       userID,
+		Name:         name,
+		CredentialID: encodeCredentialID(credential.ID),
+		Credential:   string(data),
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("%w: credential is already registered", ErrPasskeyInvalid)
+		}
+		return nil, err
+	}
+
+	s.audit.Record(ctx, audit.Event{Type: audit.TypePasskeyRegistered, Actor: audit.ActorUser, UserID: &userID, Detail: passkey.Name})
+	return passkey, nil
+}
+
+func (s *Service) ListPasskeys(ctx context.Context, userID uint) ([]Passkey, error) {
+	ctx, span := tracing.Start(ctx, "account.ListPasskeys")
+	defer span.End()
+
+	return s.repo.ListPasskeysByUser(ctx, userID)
+}
+
+func (s *Service) RenamePasskey(ctx context.Context, userID, id uint, req PasskeyRenameRequest) (*Passkey, error) {
+	ctx, span := tracing.Start(ctx, "account.RenamePasskey")
+	defer span.End()
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", core.ErrInvalidItem)
+	}
+	passkey, err := s.repo.GetPasskey(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	passkey.Name = name
+	if err := s.repo.SavePasskey(ctx, passkey); err != nil {
+		return nil, err
+	}
+	return passkey, nil
+}
+
+func (s *Service) DeletePasskey(ctx context.Context, userID, id uint) (*Passkey, error) {
+	ctx, span := tracing.Start(ctx, "account.DeletePasskey")
+	defer span.End()
+
+	passkey, err := s.repo.GetPasskey(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.DeletePasskey(ctx, passkey); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, audit.Event{Type: audit.TypePasskeyRevoked, Actor: audit.ActorUser, UserID: &userID, Detail: passkey.Name})
+	return passkey, nil
+}
+
+func (s *Service) BeginPasskeyLogin(ctx context.Context) (*protocol.CredentialAssertion, error) {
+	ctx, span := tracing.Start(ctx, "account.BeginPasskeyLogin")
+	defer span.End()
+
+	webAuthn, err := s.passkeys()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.limiter.Allow(ctx, passkeyLoginLimitKeys(ctx)...); err != nil {
+		return nil, err
+	}
+
+	assertion, sessionData, err := webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, fmt.Errorf("begin passkey login: %w", err)
+	}
+	if err := s.savePasskeyChallenge(ctx, nil, sessionData); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+func (s *Service) FinishPasskeyLogin(ctx context.Context, response protocol.CredentialAssertionResponse) (*UserResponse, *session.UserSessionTokens, error) {
+	ctx, span := tracing.Start(ctx, "account.FinishPasskeyLogin")
+	defer span.End()
+
+	webAuthn, err := s.passkeys()
+	if err != nil {
+		return nil, nil, err
+	}
+	limitKeys := passkeyLoginLimitKeys(ctx)
+	if err := s.limiter.Allow(ctx, limitKeys...); err != nil {
+		return nil, nil, err
+	}
+
+	user, passkey, credential, err := s.validatePasskeyAssertion(ctx, webAuthn, response)
+	if err != nil {
+		if errors.Is(err, ErrPasskeyInvalid) {
+			s.limiter.Fail(ctx, limitKeys...)
+			metrics.ObserveLogin(false)
+			s.audit.Record(ctx, audit.Event{
+				Type:    audit.TypeLogin,
+				Outcome: audit.OutcomeFailure,
+				Actor:   audit.ActorAnonymous,
+				Detail:  "passkey",
+			})
+		}
+		return nil, nil, err
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode passkey credential: %w", err)
+	}
+	now := time.Now()
+	passkey.Credential = string(data)
+	passkey.LastUsedAt = &now
+	if err := s.repo.SavePasskey(ctx, passkey); err != nil {
+		return nil, nil, err
+	}
+
+	tokenData, err := s.createUserSession(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.limiter.Reset(ctx, limitKeys...)
+	metrics.ObserveLogin(true)
+	s.audit.Record(ctx, audit.Event{Type: audit.TypeLogin, Actor: audit.ActorUser, UserID: &user.ID, Detail: "passkey"})
+	return NewUserResponse(user), tokenData, nil
+}
+
+func (s *Service) validatePasskeyAssertion(ctx context.Context, webAuthn *webauthn.WebAuthn, response protocol.CredentialAssertionResponse) (*User, *Passkey, *webauthn.Credential, error) {
+	parsed, err := response.Parse()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %w", ErrPasskeyInvalid, err)
+	}
+	sessionData, err := s.takePasskeyChallenge(ctx, parsed.Response.CollectedClientData.Challenge, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var passkey *Passkey
+	var owner *passkeyUser
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		var err error
+		passkey, err = s.repo.GetPasskeyByCredentialID(ctx, encodeCredentialID(rawID))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(userHandle, passkeyUserHandle(passkey.UserID)) {
+			return nil, errors.New("user handle does not match credential owner")
+		}
+		owner, err = s.loadPasskeyUser(ctx, passkey.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return owner, nil
+	}
+
+	_, credential, err := webAuthn.ValidatePasskeyLogin(handler, *sessionData, parsed)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %w", ErrPasskeyInvalid, err)
+	}
+	if credential.Authenticator.CloneWarning {
+		return nil, nil, nil, fmt.Errorf("%w: signature counter went backwards", ErrPasskeyInvalid)
+	}
+	return owner.user, passkey, credential, nil
+}
+
+func (s *Service) passkeys() (*webauthn.WebAuthn, error) {
+	if s.webAuthn == nil {
+		return nil, fmt.Errorf("%w: %w", ErrPasskeysUnavailable, s.webAuthnErr)
+	}
+	return s.webAuthn, nil
+}
+
+func (s *Service) loadPasskeyUser(ctx context.Context, userID uint) (*passkeyUser, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	passkeys, err := s.repo.ListPasskeysByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, len(passkeys))
+	for i, passkey := range passkeys {
+		if err := json.Unmarshal([]byte(passkey.Credential), &credentials[i]); err != nil {
+			return nil, fmt.Errorf("decode passkey %d: %w", passkey.ID, err)
+		}
+	}
+	return &passkeyUser{user: user, credentials: credentials}, nil
+}
+
+func (s *Service) savePasskeyChallenge(ctx context.Context, userID *uint, sessionData *webauthn.SessionData) error {
+	now := time.Now()
+	if err := s.repo.DeleteExpiredPasskeyChallenges(ctx, now); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(sessionData)
+	if err != nil {
+		return fmt.Errorf("encode passkey session: %w", err)
+	}
+	expiresAt := sessionData.Expires
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(passkeyTimeout)
+	}
+	return s.repo.CreatePasskeyChallenge(ctx, &PasskeyChallenge{
+		Challenge:   sessionData.Challenge,
+		UserID:      userID,
+		SessionData: string(data),
+		ExpiresAt:   expiresAt,
+	})
+}
+
+func (s *Service) takePasskeyChallenge(ctx context.Context, value string, userID *uint) (*webauthn.SessionData, error) {
+	challenge, err := s.repo.TakePasskeyChallenge(ctx, value)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return nil, fmt.Errorf("%w: unknown challenge", ErrPasskeyInvalid)
+		}
+		return nil, err
+	}
+	if challenge.ExpiresAt.Before(time.Now()) {
+		return nil, fmt.Errorf("%w: challenge expired", ErrPasskeyInvalid)
+	}
+	if (userID == nil) != (challenge.UserID == nil) || (userID != nil && *userID != *challenge.UserID) {
+		return nil, fmt.Errorf("%w: challenge belongs to another ceremony", ErrPasskeyInvalid)
+	}
+
+	var sessionData webauthn.SessionData
+	if err := json.Unmarshal([]byte(challenge.SessionData), &sessionData); err != nil {
+		return nil, fmt.Errorf("decode passkey session: %w", err)
+	}
+	return &sessionData, nil
+}
+
+func (s *Service) recordPasskeyRegistrationFailure(ctx context.Context, userID uint, detail string) {
+	s.audit.Record(ctx, audit.Event{
+		Type:    audit.TypePasskeyRegistered,
+		Outcome: audit.OutcomeFailure,
+		Actor:   audit.ActorUser,
+		UserID:  &userID,
+		Detail:  detail,
+	})
+}
+
+func passkeyLoginLimitKeys(ctx context.Context) []string {
+	return ratelimit.Keys("passkey-login", server.ClientFromContext(ctx).IP)
+}
+
+func passkeyRegistrationLimitKeys(ctx context.Context, userID uint) []string {
+	return ratelimit.Keys("passkey-registration", server.ClientFromContext(ctx).IP, userSubject(userID))
+}
+
+func passkeyUserHandle(userID uint) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userID))
+}
+
+func encodeCredentialID(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
@@ -0,0 +1,272 @@
+package account_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/domain/account"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
+	"github.com/azaviyalov/null3/backend/internal/domain/session"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+	"github.com/descope/virtualwebauthn"
+	"github.com/labstack/echo/v4"
+)
+
+func TestAccountPasskeyHTTPFlow(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newAccountTestEnvironment(t)
+	user := createTestUser(t, environment, "journal_user", "person@example.test")
+	createTestUser(t, environment, "other_user", "other@example.test")
+	e := newAccountTestServer(t, environment)
+	accessCookie := loginCookie(t, e, "journal_user")
+
+	rp := virtualwebauthn.RelyingParty{Name: "null3", ID: "journal.example", Origin: "https://journal.example"}
+	authenticator := virtualwebauthn.NewAuthenticator()
+	laptop := newPasskeyCredential(t)
+	phone := newPasskeyCredential(t)
+
+	laptopResponse := registerPasskey(t, e, rp, &authenticator, laptop, "Laptop", accessCookie)
+	if laptopResponse.Code != http.StatusCreated {
+		t.Fatalf("register passkey status = %d, want %d: %s", laptopResponse.Code, http.StatusCreated, laptopResponse.Body)
+	}
+	var laptopPasskey account.Passkey
+	testutil.DecodeJSON(t, laptopResponse, &laptopPasskey)
+	if laptopPasskey.Name != "Laptop" {
+		t.Errorf("passkey name = %q, want Laptop", laptopPasskey.Name)
+	}
+	if response := registerPasskey(t, e, rp, &authenticator, phone, " ", accessCookie); response.Code != http.StatusCreated {
+		t.Fatalf("register second passkey status = %d, want %d: %s", response.Code, http.StatusCreated, response.Body)
+	}
+	authenticator.AddCredential(laptop)
+	authenticator.AddCredential(phone)
+
+	var passkeys []account.Passkey
+	testutil.DecodeJSON(t, testutil.JSONRequest(t, e, http.MethodGet, "/api/auth/me/passkeys", nil, accessCookie), &passkeys)
+	if len(passkeys) != 2 || passkeys[0].Name != "Laptop" || passkeys[1].Name != "Passkey" {
+		t.Fatalf("passkeys = %+v, want Laptop and default-named passkey", passkeys)
+	}
+
+	passkeyPath := fmt.Sprintf("/api/auth/me/passkeys/%d", laptopPasskey.ID)
+	renameResponse := testutil.JSONRequest(t, e, http.MethodPut, passkeyPath, `{"name":"Work laptop"}`, accessCookie)
+	if renameResponse.Code != http.StatusOK {
+		t.Fatalf("rename passkey status = %d, want %d", renameResponse.Code, http.StatusOK)
+	}
+	if response := testutil.JSONRequest(t, e, http.MethodPut, passkeyPath, `{"name":"  "}`, accessCookie); response.Code != http.StatusBadRequest {
+		t.Fatalf("rename passkey to blank status = %d, want %d", response.Code, http.StatusBadRequest)
+	}
+	otherCookie := loginCookie(t, e, "other_user")
+	if response := testutil.JSONRequest(t, e, http.MethodDelete, passkeyPath, nil, otherCookie); response.Code != http.StatusNotFound {
+		t.Fatalf("delete another user's passkey status = %d, want %d", response.Code, http.StatusNotFound)
+	}
+
+	loginResponse, assertion := passkeyLogin(t, e, rp, authenticator, laptop)
+	if loginResponse.Code != http.StatusOK {
+		t.Fatalf("passkey login status = %d, want %d: %s", loginResponse.Code, http.StatusOK, loginResponse.Body)
+	}
+	var loginUser account.UserResponse
+	testutil.DecodeJSON(t, loginResponse, &loginUser)
+	assertUserResponse(t, &loginUser, user)
+	passkeyCookie := testutil.ResponseCookie(t, loginResponse, session.UserCookieName)
+	testutil.ResponseCookie(t, loginResponse, session.UserRefreshCookieName)
+	if response := testutil.JSONRequest(t, e, http.MethodGet, "/api/auth/me", nil, passkeyCookie); response.Code != http.StatusOK {
+		t.Fatalf("me after passkey login status = %d, want %d", response.Code, http.StatusOK)
+	}
+
+	if response := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/login/passkey/finish", assertion); response.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed assertion status = %d, want %d", response.Code, http.StatusUnauthorized)
+	}
+
+	passkeys = nil
+	testutil.DecodeJSON(t, testutil.JSONRequest(t, e, http.MethodGet, "/api/auth/me/passkeys", nil, accessCookie), &passkeys)
+	if passkeys[0].Name != "Work laptop" || passkeys[0].LastUsedAt == nil || passkeys[1].LastUsedAt != nil {
+		t.Fatalf("passkeys after login = %+v, want renamed and used laptop passkey", passkeys)
+	}
+
+	if response := testutil.JSONRequest(t, e, http.MethodDelete, passkeyPath, nil, accessCookie); response.Code != http.StatusOK {
+		t.Fatalf("delete passkey status = %d, want %d", response.Code, http.StatusOK)
+	}
+	if response, _ := passkeyLogin(t, e, rp, authenticator, laptop); response.Code != http.StatusUnauthorized {
+		t.Fatalf("login with revoked passkey status = %d, want %d", response.Code, http.StatusUnauthorized)
+	}
+	if response, _ := passkeyLogin(t, e, rp, authenticator, phone); response.Code != http.StatusOK {
+		t.Fatalf("login with remaining passkey status = %d, want %d: %s", response.Code, http.StatusOK, response.Body)
+	}
+
+	page, err := environment.auditService.ListEvents(t.Context(), audit.NewEventFilter().WithUserID(user.ID), 20, 0)
+	if err != nil {
+		t.Fatalf("list audit events: %v", err)
+	}
+	var events []string
+	for _, event := range page.Items {
+		events = append(events, event.Type+":"+event.Detail)
+	}
+	want := []string{"login:passkey", "passkey_revoked:Work laptop", "login:passkey", "passkey_registered:Passkey", "passkey_registered:Laptop", "login:"}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("audit events = %v, want %v", events, want)
+	}
+}
+
+func TestAccountPasskeyRegistrationRejectsReplay(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newAccountTestEnvironment(t)
+	createTestUser(t, environment, "journal_user", "person@example.test")
+	e := newAccountTestServer(t, environment)
+	accessCookie := loginCookie(t, e, "journal_user")
+
+	rp := virtualwebauthn.RelyingParty{Name: "null3", ID: "journal.example", Origin: "https://journal.example"}
+	authenticator := virtualwebauthn.NewAuthenticator()
+	credential := newPasskeyCredential(t)
+	options := beginPasskeyRegistration(t, e, accessCookie)
+	body := fmt.Sprintf(`{"credential":%s}`, virtualwebauthn.CreateAttestationResponse(rp, authenticator, credential, *options))
+
+	if response := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/me/passkeys/register/finish", body, accessCookie); response.Code != http.StatusCreated {
+		t.Fatalf("register passkey status = %d, want %d: %s", response.Code, http.StatusCreated, response.Body)
+	}
+	if response := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/me/passkeys/register/finish", body, accessCookie); response.Code != http.StatusBadRequest {
+		t.Fatalf("replayed registration status = %d, want %d", response.Code, http.StatusBadRequest)
+	}
+
+	wrongOrigin := virtualwebauthn.RelyingParty{Name: "null3", ID: "journal.example", Origin: "https://evil.example"}
+	options = beginPasskeyRegistration(t, e, accessCookie)
+	body = fmt.Sprintf(`{"credential":%s}`, virtualwebauthn.CreateAttestationResponse(wrongOrigin, authenticator, newPasskeyCredential(t), *options))
+	if response := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/me/passkeys/register/finish", body, accessCookie); response.Code != http.StatusBadRequest {
+		t.Fatalf("registration from another origin status = %d, want %d", response.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAccountPasskeyRegistrationRequiresReauthentication(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newAccountTestEnvironment(t)
+	user := createTestUser(t, environment, "journal_user", "person@example.test")
+	e := newAccountTestServer(t, environment)
+	accessCookie := loginCookie(t, e, "journal_user")
+	const beginPath = "/api/auth/me/passkeys/register/begin"
+
+	if response := testutil.JSONRequest(t, e, http.MethodPost, beginPath, nil, accessCookie); response.Code != http.StatusBadRequest {
+		t.Fatalf("begin registration without password status = %d, want %d", response.Code, http.StatusBadRequest)
+	}
+	if response := testutil.JSONRequest(t, e, http.MethodPost, beginPath, `{"password":"incorrect-password"}`, accessCookie); response.Code != http.StatusForbidden {
+		t.Fatalf("begin registration with wrong password status = %d, want %d", response.Code, http.StatusForbidden)
+	}
+
+	enrollResponse := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/me/two-factor/enroll", nil, accessCookie)
+	var enrollment account.TwoFactorEnrollment
+	testutil.DecodeJSON(t, enrollResponse, &enrollment)
+	confirmResponse := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/me/two-factor/confirm",
+		fmt.Sprintf(`{"code":%q}`, totpCode(t, enrollment.Secret, time.Now())), accessCookie)
+	if confirmResponse.Code != http.StatusOK {
+		t.Fatalf("confirm two-factor status = %d, want %d: %s", confirmResponse.Code, http.StatusOK, confirmResponse.Body)
+	}
+	var recovery account.RecoveryCodesResponse
+	testutil.DecodeJSON(t, confirmResponse, &recovery)
+
+	passwordOnly := fmt.Sprintf(`{"password":%q}`, testPassword)
+	if response := testutil.JSONRequest(t, e, http.MethodPost, beginPath, passwordOnly, accessCookie); response.Code != http.StatusForbidden {
+		t.Fatalf("begin registration without second factor status = %d, want %d", response.Code, http.StatusForbidden)
+	}
+	if response := testutil.JSONRequest(t, e, http.MethodPost, beginPath, fmt.Sprintf(`{"password":%q,"code":"000000"}`, testPassword), accessCookie); response.Code != http.StatusForbidden {
+		t.Fatalf("begin registration with wrong code status = %d, want %d", response.Code, http.StatusForbidden)
+	}
+	withRecoveryCode := fmt.Sprintf(`{"password":%q,"code":%q}`, testPassword, recovery.RecoveryCodes[0])
+	if response := testutil.JSONRequest(t, e, http.MethodPost, beginPath, withRecoveryCode, accessCookie); response.Code != http.StatusOK {
+		t.Fatalf("begin registration with recovery code status = %d, want %d: %s", response.Code, http.StatusOK, response.Body)
+	}
+
+	page, err := environment.auditService.ListEvents(t.Context(), audit.NewEventFilter().WithUserID(user.ID).WithType(audit.TypePasskeyRegistered), 20, 0)
+	if err != nil {
+		t.Fatalf("list audit events: %v", err)
+	}
+	var failures []string
+	for _, event := range page.Items {
+		failures = append(failures, event.Outcome+":"+event.Detail)
+	}
+	if want := []string{"failure:second factor", "failure:password"}; fmt.Sprint(failures) != fmt.Sprint(want) {
+		t.Errorf("passkey registration events = %v, want %v", failures, want)
+	}
+}
+
+func newPasskeyCredential(t *testing.T) virtualwebauthn.Credential {
+	t.Helper()
+
+	for {
+		credential := virtualwebauthn.NewCredential(virtualwebauthn.KeyTypeEC2)
+		key, err := x509.ParsePKCS8PrivateKey(credential.Key.Data)
+		if err != nil {
+			t.Fatalf("parse passkey credential key: %v", err)
+		}
+		point, err := key.(*ecdsa.PrivateKey).PublicKey.Bytes()
+		if err != nil {
+			t.Fatalf("encode passkey public key: %v", err)
+		}
+		if point[1] != 0 && point[33] != 0 {
+			return credential
+		}
+	}
+}
+
+func loginCookie(t *testing.T, e *echo.Echo, login string) *http.Cookie {
+	t.Helper()
+	response := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/login", fmt.Sprintf(`{"login":%q,"password":%q}`, login, testPassword))
+	if response.Code != http.StatusOK {
+		t.Fatalf("login status = %d, want %d", response.Code, http.StatusOK)
+	}
+	return testutil.ResponseCookie(t, response, session.UserCookieName)
+}
+
+func beginPasskeyRegistration(t *testing.T, e *echo.Echo, accessCookie *http.Cookie) *virtualwebauthn.AttestationOptions {
+	t.Helper()
+	response := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/me/passkeys/register/begin", fmt.Sprintf(`{"password":%q}`, testPassword), accessCookie)
+	if response.Code != http.StatusOK {
+		t.Fatalf("begin registration status = %d, want %d: %s", response.Code, http.StatusOK, response.Body)
+	}
+	options, err := virtualwebauthn.ParseAttestationOptions(response.Body.String())
+	if err != nil {
+		t.Fatalf("parse attestation options: %v", err)
+	}
+	return options
+}
+
+func registerPasskey(t *testing.T, e *echo.Echo, rp virtualwebauthn.RelyingParty, authenticator *virtualwebauthn.Authenticator, credential virtualwebauthn.Credential, name string, accessCookie *http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+	options := beginPasskeyRegistration(t, e, accessCookie)
+	if options.RelyingPartyID != rp.ID {
+		t.Fatalf("relying party ID = %q, want %q", options.RelyingPartyID, rp.ID)
+	}
+	if credential.IsExcludedForAttestation(*options) {
+		t.Fatal("new credential is excluded from registration")
+	}
+	authenticator.Options.UserHandle = []byte(options.UserID)
+
+	body, err := json.Marshal(map[string]any{
+		"name":       name,
+		"credential": json.RawMessage(virtualwebauthn.CreateAttestationResponse(rp, *authenticator, credential, *options)),
+	})
+	if err != nil {
+		t.Fatalf("encode registration: %v", err)
+	}
+	return testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/me/passkeys/register/finish", string(body), accessCookie)
+}
+
+func passkeyLogin(t *testing.T, e *echo.Echo, rp virtualwebauthn.RelyingParty, authenticator virtualwebauthn.Authenticator, credential virtualwebauthn.Credential) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	response := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/login/passkey/begin", nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("begin passkey login status = %d, want %d: %s", response.Code, http.StatusOK, response.Body)
+	}
+	options, err := virtualwebauthn.ParseAssertionOptions(response.Body.String())
+	if err != nil {
+		t.Fatalf("parse assertion options: %v", err)
+	}
+	if len(options.AllowCredentials) != 0 {
+		t.Fatalf("allowed credentials = %v, want discoverable login", options.AllowCredentials)
+	}
+
+	assertion := virtualwebauthn.CreateAssertionResponse(rp, authenticator, credential, *options)
+	return testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/login/passkey/finish", assertion), assertion
+}
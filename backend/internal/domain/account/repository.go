@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/azaviyalov/null3/backend/internal/core"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
@@ -124,3 +125,146 @@ func (r *Repository) SaveInvite(ctx context.Context, invite *Invite) error {
 	}
 	return nil
 }
+
+func (r *Repository) UpdateUserTOTP(ctx context.Context, userID uint, secret string, enabledAt *time.Time, lastStep int64) error {
+	err := r.db.WithContext(ctx).Model(&User{}).Where("id = ?", userID).Updates(map[string]any{
+		"totp_secret":     secret,
+		"totp_enabled_at": enabledAt,
+		"totp_last_step":  lastStep,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("update TOTP for user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *Repository) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, fmt.Errorf("advance TOTP step for user %d: %w", userID, result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error {
+	if err := r.DeleteRecoveryCodesByUser(ctx, userID); err != nil {
+		return err
+	}
+	now := time.Now()
+	codes := make([]RecoveryCode, len(codeHashes))
+	for i, codeHash := range codeHashes {
+		codes[i] = RecoveryCode{UserID: userID, CodeHash: codeHash, CreatedAt: now}
+	}
+	if err := r.db.WithContext(ctx).Create(&codes).Error; err != nil {
+		return fmt.Errorf("create recovery codes for user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Where("user_id = ? AND code_hash = ?", userID, codeHash).Delete(&RecoveryCode{})
+	if result.Error != nil {
+		return false, fmt.Errorf("use recovery code for user %d: %w", userID, result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *Repository) CountRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&RecoveryCode{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count recovery codes for user %d: %w", userID, err)
+	}
+	return count, nil
+}
+
+func (r *Repository) DeleteRecoveryCodesByUser(ctx context.Context, userID uint) error {
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return fmt.Errorf("delete recovery codes for user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *Repository) CreatePasskey(ctx context.Context, passkey *Passkey) (*Passkey, error) {
+	if err := r.db.WithContext(ctx).Create(passkey).Error; err != nil {
+		return nil, fmt.Errorf("create passkey: %w", err)
+	}
+	return passkey, nil
+}
+
+func (r *Repository) ListPasskeysByUser(ctx context.Context, userID uint) ([]Passkey, error) {
+	var passkeys []Passkey
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at, id").Find(&passkeys).Error; err != nil {
+		return nil, fmt.Errorf("list passkeys for user %d: %w", userID, err)
+	}
+	return passkeys, nil
+}
+
+func (r *Repository) GetPasskey(ctx context.Context, userID, id uint) (*Passkey, error) {
+	var passkey Passkey
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&passkey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, core.ErrItemNotFound
+		}
+		return nil, fmt.Errorf("get passkey %d: %w", id, err)
+	}
+	return &passkey, nil
+}
+
+func (r *Repository) GetPasskeyByCredentialID(ctx context.Context, credentialID string) (*Passkey, error) {
+	var passkey Passkey
+	if err := r.db.WithContext(ctx).Where("credential_id = ?", credentialID).First(&passkey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, core.ErrItemNotFound
+		}
+		return nil, fmt.Errorf("get passkey by credential ID: %w", err)
+	}
+	return &passkey, nil
+}
+
+func (r *Repository) SavePasskey(ctx context.Context, passkey *Passkey) error {
+	if err := r.db.WithContext(ctx).Save(passkey).Error; err != nil {
+		return fmt.Errorf("save passkey %d: %w", passkey.ID, err)
+	}
+	return nil
+}
+
+func (r *Repository) DeletePasskey(ctx context.Context, passkey *Passkey) error {
+	if err := r.db.WithContext(ctx).Delete(passkey).Error; err != nil {
+		return fmt.Errorf("delete passkey %d: %w", passkey.ID, err)
+	}
+	return nil
+}
+
+func (r *Repository) CreatePasskeyChallenge(ctx context.Context, challenge *PasskeyChallenge) error {
+	if err := r.db.WithContext(ctx).Create(challenge).Error; err != nil {
+		return fmt.Errorf("create passkey challenge: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) TakePasskeyChallenge(ctx context.Context, value string) (*PasskeyChallenge, error) {
+	var challenge PasskeyChallenge
+	if err := r.db.WithContext(ctx).Where("challenge = ?", value).First(&challenge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, core.ErrItemNotFound
+		}
+		return nil, fmt.Errorf("get passkey challenge: %w", err)
+	}
+	result := r.db.WithContext(ctx).Delete(&PasskeyChallenge{}, challenge.ID)
+	if result.Error != nil {
+		return nil, fmt.Errorf("delete passkey challenge %d: %w", challenge.ID, result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return nil, core.ErrItemNotFound
+	}
+	return &challenge, nil
+}
+
+func (r *Repository) DeleteExpiredPasskeyChallenges(ctx context.Context, now time.Time) error {
+	if err := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&PasskeyChallenge{}).Error; err != nil {
+		return fmt.Errorf("delete expired passkey challenges: %w", err)
+	}
+	return nil
+}
@@ -13,7 +13,13 @@ import (
 	"time"
 
 	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/metrics"
+	"github.com/azaviyalov/null3/backend/internal/core/ratelimit"
+	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -29,27 +35,71 @@ var loginPattern = regexp.MustCompile(`^[a-z0-9_-]{3,32}$`)
 type Service struct {
 	repo           *Repository
 	sessionService *session.Service
+	audit          *audit.Service
+	limiter        *ratelimit.Limiter
+	webAuthn       *webauthn.WebAuthn
+	webAuthnErr    error
 	config         Config
 }
 
-func NewService(repo *Repository, sessionService *session.Service, config Config) *Service {
+func NewService(repo *Repository, sessionService *session.Service, auditService *audit.Service, limiter *ratelimit.Limiter, config Config) *Service {
+	webAuthn, webAuthnErr := newWebAuthn(config)
 	return &Service{
 		repo:           repo,
 		sessionService: sessionService,
+		audit:          auditService,
+		limiter:        limiter,
+		webAuthn:       webAuthn,
+		webAuthnErr:    webAuthnErr,
 		config:         config,
 	}
 }
 
 func (s *Service) AuthenticateUser(ctx context.Context, req LoginRequest) (*UserResponse, *session.UserSessionTokens, error) {
+	ctx, span := tracing.Start(ctx, "account.AuthenticateUser")
+	defer span.End()
+
+	loginSubject := "name:" + normalizeLogin(req.Login)
+	limitKeys := ratelimit.Keys("login", server.ClientFromContext(ctx).IP, loginSubject)
+	if err := s.limiter.Allow(ctx, limitKeys...); err != nil {
+		return nil, nil, err
+	}
+
 	user, err := s.authenticateByLogin(ctx, req)
 	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			s.limiter.Fail(ctx, limitKeys...)
+			metrics.ObserveLogin(false)
+			event := audit.Event{
+				Type:    audit.TypeLogin,
+				Outcome: audit.OutcomeFailure,
+				Actor:   audit.ActorAnonymous,
+				Detail:  "login=" + normalizeLogin(req.Login),
+			}
+			if user != nil {
+				event.UserID = &user.ID
+			}
+			s.audit.Record(ctx, event)
+		}
 		return nil, nil, err
 	}
+	s.limiter.Reset(ctx, ratelimit.Keys("login", "", loginSubject)...)
+
+	if user.TwoFactorEnabled() {
+		token, expiresAt, err := s.sessionService.GenerateSecondFactorToken(user.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, &SecondFactorChallenge{Token: token, ExpiresAt: expiresAt}
+	}
+
 	tokenData, err := s.createUserSession(ctx, user)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	metrics.ObserveLogin(true)
+	s.audit.Record(ctx, audit.Event{Type: audit.TypeLogin, Actor: audit.ActorUser, UserID: &user.ID})
 	return NewUserResponse(user), tokenData, nil
 }
 
@@ -67,32 +117,28 @@ func (s *Service) authenticateByLogin(ctx context.Context, req LoginRequest) (*U
 		return nil, err
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return nil, ErrInvalidCredentials
+		return user, ErrInvalidCredentials
 	}
 
 	return user, nil
 }
 
 func (s *Service) GetUserByID(ctx context.Context, id uint) (*User, error) {
+	ctx, span := tracing.Start(ctx, "account.GetUserByID")
+	defer span.End()
+
 	return s.repo.GetUserByID(ctx, id)
 }
 
 func (s *Service) RefreshUserSession(ctx context.Context, tokenString string) (*UserResponse, *session.UserSessionTokens, error) {
-	sessionRepo := s.repo.SessionRepository()
+	ctx, span := tracing.Start(ctx, "account.RefreshUserSession")
+	defer span.End()
 
-	token, err := sessionRepo.GetRefreshToken(ctx, tokenString)
+	token, err := s.sessionService.ConsumeRefreshToken(ctx, tokenString)
 	if err != nil {
-		if errors.Is(err, core.ErrItemNotFound) {
-			return nil, nil, session.ErrRefreshTokenInvalid
-		}
 		return nil, nil, err
 	}
 
-	if token.ExpiresAt.Before(time.Now()) {
-		_ = sessionRepo.DeleteRefreshToken(ctx, token)
-		return nil, nil, session.ErrRefreshTokenInvalid
-	}
-
 	user, err := s.repo.GetUserByID(ctx, token.UserID)
 	if err != nil {
 		if errors.Is(err, core.ErrItemNotFound) {
@@ -101,19 +147,19 @@ func (s *Service) RefreshUserSession(ctx context.Context, tokenString string) (*
 		return nil, nil, err
 	}
 
-	if err := sessionRepo.DeleteRefreshToken(ctx, token); err != nil {
-		return nil, nil, err
-	}
-
 	tokenData, err := s.createUserSession(ctx, user)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	metrics.CountRefreshTokenRotation()
 	return NewUserResponse(user), tokenData, nil
 }
 
 func (s *Service) CreateInvite(ctx context.Context) (string, *Invite, error) {
+	ctx, span := tracing.Start(ctx, "account.CreateInvite")
+	defer span.End()
+
 	now := time.Now()
 	rawToken, err := generateRandomToken()
 	if err != nil {
@@ -129,11 +175,16 @@ func (s *Service) CreateInvite(ctx context.Context) (string, *Invite, error) {
 	if err != nil {
 		return "", nil, err
 	}
+	metrics.CountInviteCreated()
+	s.audit.Record(ctx, audit.Event{Type: audit.TypeInviteCreated, Actor: audit.ActorAdmin})
 
 	return rawToken, createdInvite, nil
 }
 
 func (s *Service) ValidateInvite(ctx context.Context, rawToken string) (*Invite, error) {
+	ctx, span := tracing.Start(ctx, "account.ValidateInvite")
+	defer span.End()
+
 	invite, err := s.repo.GetInviteByHash(ctx, hashToken(rawToken))
 	if err != nil {
 		if errors.Is(err, core.ErrItemNotFound) {
@@ -153,6 +204,14 @@ func (s *Service) ValidateInvite(ctx context.Context, rawToken string) (*Invite,
 }
 
 func (s *Service) RegisterWithInvite(ctx context.Context, rawToken string, req InviteRegistrationRequest) (*UserResponse, *session.UserSessionTokens, error) {
+	ctx, span := tracing.Start(ctx, "account.RegisterWithInvite")
+	defer span.End()
+
+	limitKeys := ratelimit.Keys("register", server.ClientFromContext(ctx).IP)
+	if err := s.limiter.Allow(ctx, limitKeys...); err != nil {
+		return nil, nil, err
+	}
+
 	login := normalizeLogin(req.Login)
 	email := normalizeEmail(req.Email)
 
@@ -191,10 +250,14 @@ func (s *Service) RegisterWithInvite(ctx context.Context, rawToken string, req I
 			return err
 		}
 
-		createdUser, err = repo.CreateUser(ctx, &User{
-			Login:        login,
-			Email:        email,
-			PasswordHash: passwordHash,
+		err = repo.WithTx(ctx, func(repo *Repository) error {
+			var err error
+			createdUser, err = repo.CreateUser(ctx, &User{
+				Login:        login,
+				Email:        email,
+				PasswordHash: passwordHash,
+			})
+			return err
 		})
 		if err != nil {
 			if errors.Is(err, gorm.ErrDuplicatedKey) {
@@ -219,8 +282,13 @@ func (s *Service) RegisterWithInvite(ctx context.Context, rawToken string, req I
 		return err
 	})
 	if err != nil {
+		if isInviteError(err) {
+			s.limiter.Fail(ctx, limitKeys...)
+		}
 		return nil, nil, err
 	}
+	metrics.CountInviteRedeemed()
+	s.audit.Record(ctx, audit.Event{Type: audit.TypeInviteRedeemed, Actor: audit.ActorUser, UserID: &createdUser.ID})
 
 	accessToken, err := s.sessionService.GenerateUserAccessToken(createdUser.ID)
 	if err != nil {
@@ -234,7 +302,13 @@ func (s *Service) RegisterWithInvite(ctx context.Context, rawToken string, req I
 }
 
 func (s *Service) RequestPasswordReset(ctx context.Context, req ForgotPasswordRequest) (string, error) {
+	ctx, span := tracing.Start(ctx, "account.RequestPasswordReset")
+	defer span.End()
+
 	email := normalizeEmail(req.Email)
+	if err := s.limiter.Allow(ctx, ratelimit.Keys("forgot-password", server.ClientFromContext(ctx).IP, "email:"+email)...); err != nil {
+		return "", err
+	}
 	user, err := s.repo.GetUserByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, core.ErrItemNotFound) {
@@ -265,11 +339,20 @@ func (s *Service) RequestPasswordReset(ctx context.Context, req ForgotPasswordRe
 	if err != nil {
 		return "", err
 	}
+	s.audit.Record(ctx, audit.Event{Type: audit.TypePasswordResetRequested, Actor: audit.ActorAnonymous, UserID: &user.ID})
 
 	return rawToken, nil
 }
 
 func (s *Service) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	ctx, span := tracing.Start(ctx, "account.ResetPassword")
+	defer span.End()
+
+	limitKeys := ratelimit.Keys("reset-password", server.ClientFromContext(ctx).IP)
+	if err := s.limiter.Allow(ctx, limitKeys...); err != nil {
+		return err
+	}
+
 	if err := validatePassword(req.Password); err != nil {
 		return err
 	}
@@ -281,7 +364,8 @@ func (s *Service) ResetPassword(ctx context.Context, req ResetPasswordRequest) e
 
 	tokenHash := hashToken(req.Token)
 
-	return s.repo.WithTx(ctx, func(repo *Repository) error {
+	var userID uint
+	err = s.repo.WithTx(ctx, func(repo *Repository) error {
 		resetToken, err := repo.GetPasswordResetTokenByHash(ctx, tokenHash)
 		if err != nil {
 			if errors.Is(err, core.ErrItemNotFound) {
@@ -310,8 +394,30 @@ func (s *Service) ResetPassword(ctx context.Context, req ResetPasswordRequest) e
 		if err := repo.SessionRepository().DeleteRefreshTokensByUser(ctx, user.ID); err != nil {
 			return err
 		}
+		userID = user.ID
 		return nil
 	})
+	if err != nil {
+		if errors.Is(err, ErrPasswordResetTokenInvalid) || errors.Is(err, ErrPasswordResetTokenExpired) {
+			s.limiter.Fail(ctx, limitKeys...)
+			s.audit.Record(ctx, audit.Event{
+				Type:    audit.TypePasswordReset,
+				Outcome: audit.OutcomeFailure,
+				Actor:   audit.ActorAnonymous,
+				Detail:  err.Error(),
+			})
+		}
+		return err
+	}
+	s.audit.Record(ctx, audit.Event{Type: audit.TypePasswordReset, Actor: audit.ActorUser, UserID: &userID})
+	return nil
+}
+
+func (s *Service) ListActivity(ctx context.Context, userID uint, limit, offset int) (core.Page[audit.Event], error) {
+	ctx, span := tracing.Start(ctx, "account.ListActivity")
+	defer span.End()
+
+	return s.audit.ListEvents(ctx, audit.NewEventFilter().WithUserID(userID), limit, offset)
 }
 
 func (s *Service) createUserSession(ctx context.Context, user *User) (*session.UserSessionTokens, error) {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/azaviyalov/null3/backend/internal/core"
 	"github.com/azaviyalov/null3/backend/internal/domain/account"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"github.com/azaviyalov/null3/backend/internal/testutil"
 	"golang.org/x/crypto/bcrypt"
@@ -100,6 +101,25 @@ func TestServiceAuthenticateUser(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("failed logins are attributed to existing users", func(t *testing.T) {
+		page, err := environment.auditService.ListEvents(t.Context(), audit.NewEventFilter().WithType(audit.TypeLogin).WithOutcome(audit.OutcomeFailure), 20, 0)
+		if err != nil {
+			t.Fatalf("ListEvents() error = %v", err)
+		}
+		var attributed, unknown int
+		for _, event := range page.Items {
+			switch {
+			case event.UserID != nil && *event.UserID == user.ID:
+				attributed++
+			case event.UserID == nil && event.Detail == "login=unknown":
+				unknown++
+			}
+		}
+		if attributed != 1 || unknown != 1 {
+			t.Fatalf("failed login events = %d attributed and %d unknown, want 1 and 1", attributed, unknown)
+		}
+	})
 }
 
 func TestServiceInviteLifecycle(t *testing.T) {
@@ -4,7 +4,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/azaviyalov/null3/backend/internal/core/ratelimit"
 	"github.com/azaviyalov/null3/backend/internal/domain/account"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"github.com/azaviyalov/null3/backend/internal/testutil"
 	"golang.org/x/crypto/bcrypt"
@@ -18,6 +20,7 @@ type accountTestEnvironment struct {
 	repository     *account.Repository
 	service        *account.Service
 	sessionService *session.Service
+	auditService   *audit.Service
 	sessionConfig  session.Config
 	accountConfig  account.Config
 }
@@ -32,20 +35,31 @@ func newAccountTestEnvironment(t *testing.T) *accountTestEnvironment {
 		JWTExpiration:          time.Hour,
 		RefreshTokenExpiration: 7 * 24 * time.Hour,
 	}
-	sessionService := session.NewService(session.NewRepository(database), sessionConfig)
+	auditService := audit.NewService(audit.NewRepository(database))
+	sessionService := session.NewService(session.NewRepository(database), auditService, sessionConfig)
 	repository := account.NewRepository(database)
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.Config{
+		Enabled:          true,
+		Burst:            10,
+		PerMinute:        10,
+		FailureThreshold: 3,
+		Lockout:          time.Minute,
+		MaxLockout:       time.Hour,
+	})
 	accountConfig := account.Config{
 		PasswordResetTokenExpiration: time.Hour,
 		FrontendURL:                  "https://journal.example",
+		TOTPEncryptionKey:            "account-test-totp-encryption-key",
 	}
 
 	return &accountTestEnvironment{
 		database:       database,
 		repository:     repository,
 		sessionService: sessionService,
+		auditService:   auditService,
 		sessionConfig:  sessionConfig,
 		accountConfig:  accountConfig,
-		service:        account.NewService(repository, sessionService, accountConfig),
+		service:        account.NewService(repository, sessionService, auditService, limiter, accountConfig),
 	}
 }
 
@@ -0,0 +1,104 @@
+package account
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateTOTPMatchesRFC6238(t *testing.T) {
+	secret := base32NoPadding.EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+	for _, tt := range tests {
+		got, err := GenerateTOTP(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateTOTP(%d) error = %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("GenerateTOTP(%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generateTOTPSecret() error = %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	current := totpStep(now)
+
+	for _, offset := range []time.Duration{-totpPeriod * time.Second, 0, totpPeriod * time.Second} {
+		code, _ := GenerateTOTP(secret, now.Add(offset))
+		if _, ok := verifyTOTP(secret, code, now, 0); !ok {
+			t.Errorf("verifyTOTP rejected code at offset %v", offset)
+		}
+	}
+
+	code, _ := GenerateTOTP(secret, now.Add(2*totpPeriod*time.Second))
+	if _, ok := verifyTOTP(secret, code, now, 0); ok {
+		t.Error("verifyTOTP accepted code outside the window")
+	}
+
+	code, _ = GenerateTOTP(secret, now)
+	if step, ok := verifyTOTP(secret, code, now, 0); !ok || step != current {
+		t.Fatalf("verifyTOTP() = %d, %v; want %d, true", step, ok, current)
+	}
+	if _, ok := verifyTOTP(secret, code, now, current); ok {
+		t.Error("verifyTOTP accepted an already used step")
+	}
+	if _, ok := verifyTOTP(secret, "12345", now, 0); ok {
+		t.Error("verifyTOTP accepted a short code")
+	}
+}
+
+func TestTOTPSecretEncryption(t *testing.T) {
+	key := "test-totp-encryption-key-0123456789"
+	encrypted, err := encryptTOTPSecret(key, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("encryptTOTPSecret() error = %v", err)
+	}
+	if strings.Contains(encrypted, "JBSWY3DPEHPK3PXP") {
+		t.Fatal("encrypted secret contains the plaintext")
+	}
+
+	secret, err := decryptTOTPSecret(key, encrypted)
+	if err != nil || secret != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("decryptTOTPSecret() = %q, %v; want original secret", secret, err)
+	}
+	if _, err := decryptTOTPSecret("another-totp-encryption-key-0123456789", encrypted); err == nil {
+		t.Fatal("decryptTOTPSecret() with wrong key succeeded")
+	}
+	if _, err := encryptTOTPSecret("", "JBSWY3DPEHPK3PXP"); !errors.Is(err, ErrTwoFactorUnavailable) {
+		t.Fatalf("encryptTOTPSecret() without key error = %v, want ErrTwoFactorUnavailable", err)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("generateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("len(codes) = %d, want %d", len(codes), recoveryCodeCount)
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || seen[code] {
+			t.Fatalf("unexpected recovery code %q", code)
+		}
+		seen[code] = true
+		if normalized := normalizeRecoveryCode(" " + strings.ToUpper(code) + " "); normalized != strings.ReplaceAll(code, "-", "") {
+			t.Errorf("normalizeRecoveryCode(%q) = %q", code, normalized)
+		}
+	}
+}
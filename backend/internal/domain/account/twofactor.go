@@ -0,0 +1,273 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/metrics"
+	"github.com/azaviyalov/null3/backend/internal/core/ratelimit"
+	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
+	"github.com/azaviyalov/null3/backend/internal/domain/session"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	secondFactorTOTP         = "totp"
+	secondFactorRecoveryCode = "recovery_code"
+)
+
+func (s *Service) CompleteSecondFactor(ctx context.Context, req SecondFactorLoginRequest) (*UserResponse, *session.UserSessionTokens, error) {
+	ctx, span := tracing.Start(ctx, "account.CompleteSecondFactor")
+	defer span.End()
+
+	userID, err := s.sessionService.ParseSecondFactorToken(req.Challenge)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrSecondFactorInvalid, err)
+	}
+
+	limitKeys := twoFactorLimitKeys(ctx, userID)
+	if err := s.limiter.Allow(ctx, limitKeys...); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return nil, nil, ErrSecondFactorInvalid
+		}
+		return nil, nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, nil, ErrSecondFactorInvalid
+	}
+
+	method, err := s.verifySecondFactor(ctx, user, req.Code)
+	if err != nil {
+		if errors.Is(err, ErrTwoFactorCodeInvalid) {
+			s.limiter.Fail(ctx, limitKeys...)
+			metrics.ObserveLogin(false)
+			s.audit.Record(ctx, audit.Event{
+				Type:    audit.TypeLogin,
+				Outcome: audit.OutcomeFailure,
+				Actor:   audit.ActorAnonymous,
+				UserID:  &user.ID,
+				Detail:  "second factor",
+			})
+		}
+		return nil, nil, err
+	}
+
+	tokenData, err := s.createUserSession(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.limiter.Reset(ctx, ratelimit.Keys("two-factor", "", userSubject(user.ID))...)
+	metrics.ObserveLogin(true)
+	s.audit.Record(ctx, audit.Event{Type: audit.TypeLogin, Actor: audit.ActorUser, UserID: &user.ID, Detail: method})
+	return NewUserResponse(user), tokenData, nil
+}
+
+func (s *Service) GetTwoFactorStatus(ctx context.Context, userID uint) (*TwoFactorStatus, error) {
+	ctx, span := tracing.Start(ctx, "account.GetTwoFactorStatus")
+	defer span.End()
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{
+		Enabled:   user.TwoFactorEnabled(),
+		EnabledAt: user.TOTPEnabledAt,
+	}
+	if status.Enabled {
+		status.RecoveryCodesRemaining, err = s.repo.CountRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+func (s *Service) BeginTwoFactorEnrollment(ctx context.Context, userID uint) (*TwoFactorEnrollment, error) {
+	ctx, span := tracing.Start(ctx, "account.BeginTwoFactorEnrollment")
+	defer span.End()
+
+	if s.config.TOTPEncryptionKey == "" {
+		return nil, ErrTwoFactorUnavailable
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encryptTOTPSecret(s.config.TOTPEncryptionKey, secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateUserTOTP(ctx, userID, encrypted, nil, 0); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURL: totpURL(secret, user.Login),
+	}, nil
+}
+
+func (s *Service) ConfirmTwoFactor(ctx context.Context, userID uint, req TwoFactorCodeRequest) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "account.ConfirmTwoFactor")
+	defer span.End()
+
+	limitKeys := twoFactorLimitKeys(ctx, userID)
+	if err := s.limiter.Allow(ctx, limitKeys...); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolling
+	}
+
+	secret, err := decryptTOTPSecret(s.config.TOTPEncryptionKey, user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := verifyTOTP(secret, req.Code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		s.limiter.Fail(ctx, limitKeys...)
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	codeHashes := make([]string, len(codes))
+	for i, code := range codes {
+		codeHashes[i] = hashToken(normalizeRecoveryCode(code))
+	}
+
+	now := time.Now()
+	err = s.repo.WithTx(ctx, func(repo *Repository) error {
+		if err := repo.UpdateUserTOTP(ctx, userID, user.TOTPSecret, &now, step); err != nil {
+			return err
+		}
+		return repo.ReplaceRecoveryCodes(ctx, userID, codeHashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.limiter.Reset(ctx, ratelimit.Keys("two-factor", "", userSubject(userID))...)
+	s.audit.Record(ctx, audit.Event{Type: audit.TypeTwoFactorEnabled, Actor: audit.ActorUser, UserID: &userID})
+	return codes, nil
+}
+
+func (s *Service) DisableTwoFactor(ctx context.Context, userID uint, req DisableTwoFactorRequest) error {
+	ctx, span := tracing.Start(ctx, "account.DisableTwoFactor")
+	defer span.End()
+
+	limitKeys := twoFactorLimitKeys(ctx, userID)
+	if err := s.limiter.Allow(ctx, limitKeys...); err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		s.limiter.Fail(ctx, limitKeys...)
+		s.recordTwoFactorDisableFailure(ctx, userID, "password")
+		return ErrInvalidCredentials
+	}
+	if _, err := s.verifySecondFactor(ctx, user, req.Code); err != nil {
+		if errors.Is(err, ErrTwoFactorCodeInvalid) {
+			s.limiter.Fail(ctx, limitKeys...)
+			s.recordTwoFactorDisableFailure(ctx, userID, "second factor")
+		}
+		return err
+	}
+
+	err = s.repo.WithTx(ctx, func(repo *Repository) error {
+		if err := repo.UpdateUserTOTP(ctx, userID, "", nil, 0); err != nil {
+			return err
+		}
+		return repo.DeleteRecoveryCodesByUser(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.limiter.Reset(ctx, ratelimit.Keys("two-factor", "", userSubject(userID))...)
+	s.audit.Record(ctx, audit.Event{Type: audit.TypeTwoFactorDisabled, Actor: audit.ActorUser, UserID: &userID})
+	return nil
+}
+
+func (s *Service) verifySecondFactor(ctx context.Context, user *User, code string) (string, error) {
+	secret, err := decryptTOTPSecret(s.config.TOTPEncryptionKey, user.TOTPSecret)
+	if err != nil {
+		return "", err
+	}
+	if step, ok := verifyTOTP(secret, code, time.Now(), user.TOTPLastStep); ok {
+		advanced, err := s.repo.AdvanceTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			return "", err
+		}
+		if advanced {
+			return secondFactorTOTP, nil
+		}
+		return "", ErrTwoFactorCodeInvalid
+	}
+
+	used, err := s.repo.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return "", err
+	}
+	if used {
+		return secondFactorRecoveryCode, nil
+	}
+	return "", ErrTwoFactorCodeInvalid
+}
+
+func (s *Service) recordTwoFactorDisableFailure(ctx context.Context, userID uint, detail string) {
+	s.audit.Record(ctx, audit.Event{
+		Type:    audit.TypeTwoFactorDisabled,
+		Outcome: audit.OutcomeFailure,
+		Actor:   audit.ActorUser,
+		UserID:  &userID,
+		Detail:  detail,
+	})
+}
+
+func twoFactorLimitKeys(ctx context.Context, userID uint) []string {
+	return ratelimit.Keys("two-factor", server.ClientFromContext(ctx).IP, userSubject(userID))
+}
+
+func userSubject(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
@@ -0,0 +1,187 @@
+package account_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/domain/account"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
+	"github.com/azaviyalov/null3/backend/internal/domain/session"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestAccountTwoFactorHTTPFlow(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newAccountTestEnvironment(t)
+	user := createTestUser(t, environment, "journal_user", "person@example.test")
+	e := newAccountTestServer(t, environment)
+	const credentials = `{"login":"journal_user","password":"correct-password"}`
+
+	loginResponse := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/login", credentials)
+	if loginResponse.Code != http.StatusOK {
+		t.Fatalf("login status = %d, want %d", loginResponse.Code, http.StatusOK)
+	}
+	accessCookie := testutil.ResponseCookie(t, loginResponse, session.UserCookieName)
+
+	enrollResponse := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/me/two-factor/enroll", nil, accessCookie)
+	if enrollResponse.Code != http.StatusOK {
+		t.Fatalf("enroll status = %d, want %d: %s", enrollResponse.Code, http.StatusOK, enrollResponse.Body)
+	}
+	var enrollment account.TwoFactorEnrollment
+	testutil.DecodeJSON(t, enrollResponse, &enrollment)
+	wantURL := fmt.Sprintf("otpauth://totp/null3:journal_user?algorithm=SHA1&digits=6&issuer=null3&period=30&secret=%s", enrollment.Secret)
+	if enrollment.OTPAuthURL != wantURL {
+		t.Errorf("otpauth URL = %q, want %q", enrollment.OTPAuthURL, wantURL)
+	}
+	stored, err := environment.repository.GetUserByID(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if stored.TOTPSecret == "" || stored.TOTPSecret == enrollment.Secret || stored.TwoFactorEnabled() {
+		t.Fatalf("stored TOTP secret = %q enabled %v, want encrypted pending secret", stored.TOTPSecret, stored.TwoFactorEnabled())
+	}
+
+	wrongConfirm := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/me/two-factor/confirm", `{"code":"000000"}`, accessCookie)
+	if wrongConfirm.Code != http.StatusForbidden {
+		t.Fatalf("confirm with wrong code status = %d, want %d", wrongConfirm.Code, http.StatusForbidden)
+	}
+
+	now := time.Now()
+	confirmResponse := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/me/two-factor/confirm",
+		fmt.Sprintf(`{"code":%q}`, totpCode(t, enrollment.Secret, now)), accessCookie)
+	if confirmResponse.Code != http.StatusOK {
+		t.Fatalf("confirm status = %d, want %d: %s", confirmResponse.Code, http.StatusOK, confirmResponse.Body)
+	}
+	var recovery account.RecoveryCodesResponse
+	testutil.DecodeJSON(t, confirmResponse, &recovery)
+	if len(recovery.RecoveryCodes) != 10 {
+		t.Fatalf("recovery codes = %v, want 10", recovery.RecoveryCodes)
+	}
+
+	reenroll := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/me/two-factor/enroll", nil, accessCookie)
+	if reenroll.Code != http.StatusConflict {
+		t.Fatalf("enroll while enabled status = %d, want %d", reenroll.Code, http.StatusConflict)
+	}
+
+	challengeResponse := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/login", credentials)
+	challenge := decodeChallenge(t, challengeResponse)
+
+	replayed := postFrom(e, "/api/auth/login/two-factor",
+		fmt.Sprintf(`{"challenge":%q,"code":%q}`, challenge, totpCode(t, enrollment.Secret, now)), "192.0.2.71")
+	if replayed.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed TOTP status = %d, want %d", replayed.Code, http.StatusUnauthorized)
+	}
+
+	completeResponse := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/login/two-factor",
+		fmt.Sprintf(`{"challenge":%q,"code":%q}`, challenge, totpCode(t, enrollment.Secret, now.Add(30*time.Second))))
+	if completeResponse.Code != http.StatusOK {
+		t.Fatalf("two-factor login status = %d, want %d: %s", completeResponse.Code, http.StatusOK, completeResponse.Body)
+	}
+	var loginUser account.UserResponse
+	testutil.DecodeJSON(t, completeResponse, &loginUser)
+	assertUserResponse(t, &loginUser, user)
+	if !loginUser.TwoFactorEnabled {
+		t.Error("user response does not report two-factor authentication")
+	}
+	accessCookie = testutil.ResponseCookie(t, completeResponse, session.UserCookieName)
+	testutil.ResponseCookie(t, completeResponse, session.UserRefreshCookieName)
+
+	challengeCookie := &http.Cookie{Name: session.UserCookieName, Value: challenge}
+	if response := testutil.JSONRequest(t, e, http.MethodGet, "/api/auth/me", nil, challengeCookie); response.Code != http.StatusUnauthorized {
+		t.Fatalf("me with challenge token status = %d, want %d", response.Code, http.StatusUnauthorized)
+	}
+
+	challenge = decodeChallenge(t, testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/login", credentials))
+	recoveryLogin := postFrom(e, "/api/auth/login/two-factor",
+		fmt.Sprintf(`{"challenge":%q,"code":%q}`, challenge, recovery.RecoveryCodes[0]), "192.0.2.72")
+	if recoveryLogin.Code != http.StatusOK {
+		t.Fatalf("recovery code login status = %d, want %d: %s", recoveryLogin.Code, http.StatusOK, recoveryLogin.Body)
+	}
+	reusedRecovery := postFrom(e, "/api/auth/login/two-factor",
+		fmt.Sprintf(`{"challenge":%q,"code":%q}`, challenge, recovery.RecoveryCodes[0]), "192.0.2.73")
+	if reusedRecovery.Code != http.StatusUnauthorized {
+		t.Fatalf("reused recovery code status = %d, want %d", reusedRecovery.Code, http.StatusUnauthorized)
+	}
+
+	statusResponse := testutil.JSONRequest(t, e, http.MethodGet, "/api/auth/me/two-factor", nil, accessCookie)
+	var status account.TwoFactorStatus
+	testutil.DecodeJSON(t, statusResponse, &status)
+	if !status.Enabled || status.RecoveryCodesRemaining != 9 {
+		t.Fatalf("two-factor status = %+v, want enabled with 9 recovery codes", status)
+	}
+
+	wrongPassword := postFrom(e, "/api/auth/me/two-factor/disable",
+		fmt.Sprintf(`{"password":"incorrect-password","code":%q}`, recovery.RecoveryCodes[1]), "192.0.2.74", accessCookie)
+	if wrongPassword.Code != http.StatusForbidden {
+		t.Fatalf("disable with wrong password status = %d, want %d", wrongPassword.Code, http.StatusForbidden)
+	}
+	disableResponse := postFrom(e, "/api/auth/me/two-factor/disable",
+		fmt.Sprintf(`{"password":"correct-password","code":%q}`, recovery.RecoveryCodes[1]), "192.0.2.75", accessCookie)
+	if disableResponse.Code != http.StatusOK {
+		t.Fatalf("disable status = %d, want %d: %s", disableResponse.Code, http.StatusOK, disableResponse.Body)
+	}
+
+	if response := testutil.JSONRequest(t, e, http.MethodPost, "/api/auth/login", credentials); response.Code != http.StatusOK {
+		t.Fatalf("login after disable status = %d, want %d", response.Code, http.StatusOK)
+	}
+	var remaining int64
+	if err := environment.database.Model(&account.RecoveryCode{}).Where("user_id = ?", user.ID).Count(&remaining).Error; err != nil {
+		t.Fatalf("count recovery codes: %v", err)
+	}
+	if remaining != 0 {
+		t.Fatalf("recovery codes after disable = %d, want 0", remaining)
+	}
+
+	page, err := environment.auditService.ListEvents(t.Context(), audit.NewEventFilter().WithUserID(user.ID).WithOutcome(audit.OutcomeSuccess), 20, 0)
+	if err != nil {
+		t.Fatalf("list audit events: %v", err)
+	}
+	var types []string
+	for _, event := range page.Items {
+		types = append(types, event.Type+":"+event.Detail)
+	}
+	want := []string{"login:", "two_factor_disabled:", "login:recovery_code", "login:totp", "two_factor_enabled:", "login:"}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Errorf("audit events = %v, want %v", types, want)
+	}
+}
+
+func TestBeginTwoFactorEnrollmentRequiresEncryptionKey(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newAccountTestEnvironment(t)
+	user := createTestUser(t, environment, "journal_user", "person@example.test")
+	service := account.NewService(environment.repository, environment.sessionService, environment.auditService, nil, account.Config{})
+
+	if _, err := service.BeginTwoFactorEnrollment(t.Context(), user.ID); !errors.Is(err, account.ErrTwoFactorUnavailable) {
+		t.Fatalf("BeginTwoFactorEnrollment() error = %v, want ErrTwoFactorUnavailable", err)
+	}
+}
+
+func decodeChallenge(t *testing.T, response *httptest.ResponseRecorder) string {
+	t.Helper()
+	if response.Code != http.StatusUnauthorized {
+		t.Fatalf("login status = %d, want %d", response.Code, http.StatusUnauthorized)
+	}
+	if cookies := response.Result().Cookies(); len(cookies) != 0 {
+		t.Fatalf("login set cookies %v before the second factor", cookies)
+	}
+	var challenge account.SecondFactorChallengeResponse
+	testutil.DecodeJSON(t, response, &challenge)
+	if challenge.Challenge == "" || !challenge.ExpiresAt.After(time.Now()) {
+		t.Fatalf("challenge = %+v, want token with future expiry", challenge)
+	}
+	return challenge.Challenge
+}
+
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := account.GenerateTOTP(secret, at)
+	if err != nil {
+		t.Fatalf("GenerateTOTP() error = %v", err)
+	}
+	return code
+}
@@ -5,7 +5,9 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/azaviyalov/null3/backend/internal/core/ratelimit"
 	"github.com/azaviyalov/null3/backend/internal/domain/account"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"github.com/labstack/echo/v4"
 )
@@ -15,6 +17,7 @@ func RegisterRoutes(e *echo.Echo, handler *Handler, adminJWT echo.MiddlewareFunc
 	e.POST("/api/admin/auth/logout", handler.Logout, adminJWT)
 	e.GET("/api/admin/auth/me", handler.Me, adminJWT)
 	e.POST("/api/admin/invites", handler.CreateInvite, adminJWT)
+	e.GET("/api/admin/audit-events", handler.ListAuditEvents, adminJWT)
 }
 
 type Handler struct {
@@ -38,11 +41,14 @@ func (h *Handler) Login(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.ErrBadRequest.WithInternal(err)
 	}
-	token, err := h.adminService.Authenticate(req.Password)
+	token, err := h.adminService.Authenticate(c.Request().Context(), req.Password)
 	if err != nil {
 		if errors.Is(err, ErrInvalidCredentials) {
 			return newHTTPError(http.StatusUnauthorized, "Incorrect admin credentials.", err)
 		}
+		if errors.Is(err, ratelimit.ErrLimited) {
+			return ratelimit.TooManyRequests(c, err)
+		}
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
 
@@ -72,6 +78,23 @@ func (h *Handler) CreateInvite(c echo.Context) error {
 	return c.JSON(http.StatusCreated, resp)
 }
 
+func (h *Handler) ListAuditEvents(c echo.Context) error {
+	limit, offset, err := audit.ParsePagination(c)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	filter, err := audit.ParseEventFilter(c)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	page, err := h.adminService.ListAuditEvents(c.Request().Context(), filter, limit, offset)
+	if err != nil {
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
 func (h *Handler) frontendURL(path string) string {
 	baseURL := strings.TrimRight(h.config.FrontendURL, "/")
 	if baseURL == "" {
@@ -1,13 +1,16 @@
 package admin_test
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
 
+	"github.com/azaviyalov/null3/backend/internal/core"
 	"github.com/azaviyalov/null3/backend/internal/domain/account"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"github.com/azaviyalov/null3/backend/internal/testutil"
 	"github.com/labstack/echo/v4"
@@ -87,6 +90,41 @@ func TestAdminAuthenticationHTTPFlow(t *testing.T) {
 	}
 }
 
+func TestAdminLoginLockoutHTTP(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newAdminTestEnvironment(t)
+
+	adminLogin := func(password, ip string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/api/admin/auth/login", strings.NewReader(fmt.Sprintf(`{"password":%q}`, password)))
+		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		request.RemoteAddr = ip + ":40000"
+		response := httptest.NewRecorder()
+		environment.echo.ServeHTTP(response, request)
+		return response
+	}
+
+	for i := range 3 {
+		if response := adminLogin("incorrect-admin-password", "198.51.100.7"); response.Code != http.StatusUnauthorized {
+			t.Fatalf("wrong password %d status = %d, want %d", i+1, response.Code, http.StatusUnauthorized)
+		}
+	}
+
+	response := adminLogin(testAdminPassword, "198.51.100.7")
+	if response.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked admin login status = %d, want %d", response.Code, http.StatusTooManyRequests)
+	}
+	if response.Header().Get(echo.HeaderRetryAfter) == "" {
+		t.Error("locked admin login has no Retry-After header")
+	}
+	if len(response.Result().Cookies()) != 0 {
+		t.Error("locked admin login set a cookie")
+	}
+
+	if response := adminLogin(testAdminPassword, "192.0.2.10"); response.Code != http.StatusOK {
+		t.Fatalf("admin login from another address status = %d, want %d", response.Code, http.StatusOK)
+	}
+}
+
 func TestCreateInviteHTTP(t *testing.T) {
 	testutil.SkipIntegration(t)
 	environment := newAdminTestEnvironment(t)
@@ -145,6 +183,53 @@ func TestCreateInviteHTTP(t *testing.T) {
 	assertAdminIsStateless(t, environment)
 }
 
+func TestListAuditEventsHTTP(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newAdminTestEnvironment(t)
+
+	unauthorizedResponse := testutil.JSONRequest(t, environment.echo, http.MethodGet, "/api/admin/audit-events", nil)
+	if unauthorizedResponse.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthorized audit events status = %d, want %d", unauthorizedResponse.Code, http.StatusUnauthorized)
+	}
+
+	testutil.JSONRequest(t, environment.echo, http.MethodPost, "/api/admin/auth/login", `{"password":"incorrect-admin-password"}`)
+	adminCookie := loginAdmin(t, environment)
+	testutil.JSONRequest(t, environment.echo, http.MethodPost, "/api/admin/invites", nil, adminCookie)
+
+	tests := []struct {
+		query       string
+		wantTotal   int64
+		wantType    string
+		wantOutcome string
+	}{
+		{query: "", wantTotal: 3, wantType: audit.TypeInviteCreated, wantOutcome: audit.OutcomeSuccess},
+		{query: "?type=admin_login", wantTotal: 2, wantType: audit.TypeAdminLogin, wantOutcome: audit.OutcomeSuccess},
+		{query: "?type=admin_login&outcome=failure", wantTotal: 1, wantType: audit.TypeAdminLogin, wantOutcome: audit.OutcomeFailure},
+		{query: "?from=2000-01-01T00:00:00Z&limit=1&offset=2", wantTotal: 3, wantType: audit.TypeAdminLogin, wantOutcome: audit.OutcomeFailure},
+	}
+	for _, tt := range tests {
+		response := testutil.JSONRequest(t, environment.echo, http.MethodGet, "/api/admin/audit-events"+tt.query, nil, adminCookie)
+		if response.Code != http.StatusOK {
+			t.Fatalf("audit events %q status = %d, want %d", tt.query, response.Code, http.StatusOK)
+		}
+		var page core.Page[audit.Event]
+		testutil.DecodeJSON(t, response, &page)
+		if page.TotalCount != tt.wantTotal || len(page.Items) == 0 {
+			t.Fatalf("audit events %q = %d items of %d, want total %d", tt.query, len(page.Items), page.TotalCount, tt.wantTotal)
+		}
+		if event := page.Items[0]; event.Type != tt.wantType || event.Outcome != tt.wantOutcome || event.IP == "" {
+			t.Errorf("audit events %q first item = %+v, want %s %s with client IP", tt.query, event, tt.wantType, tt.wantOutcome)
+		}
+	}
+
+	for _, query := range []string{"?type=unknown", "?outcome=maybe", "?user_id=abc", "?from=2030-01-01T00:00:00Z&to=2020-01-01T00:00:00Z", "?limit=0"} {
+		response := testutil.JSONRequest(t, environment.echo, http.MethodGet, "/api/admin/audit-events"+query, nil, adminCookie)
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("audit events %q status = %d, want %d", query, response.Code, http.StatusBadRequest)
+		}
+	}
+}
+
 func loginAdmin(t *testing.T, environment *adminTestEnvironment) *http.Cookie {
 	t.Helper()
 
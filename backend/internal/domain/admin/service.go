@@ -1,11 +1,17 @@
 package admin
 
 import (
+	"context"
 	"crypto/sha256"
 	"crypto/subtle"
 	"errors"
 	"time"
 
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/ratelimit"
+	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 )
 
@@ -16,16 +22,41 @@ var ErrInvalidCredentials = errors.New("invalid credentials")
 type Service struct {
 	passwordHash [sha256.Size]byte
 	tokens       *session.Service
+	audit        *audit.Service
+	limiter      *ratelimit.Limiter
 }
 
-func NewService(password string, tokens *session.Service) *Service {
-	return &Service{passwordHash: sha256.Sum256([]byte(password)), tokens: tokens}
+func NewService(password string, tokens *session.Service, auditService *audit.Service, limiter *ratelimit.Limiter) *Service {
+	return &Service{passwordHash: sha256.Sum256([]byte(password)), tokens: tokens, audit: auditService, limiter: limiter}
 }
 
-func (s *Service) Authenticate(password string) (string, error) {
+func (s *Service) Authenticate(ctx context.Context, password string) (string, error) {
+	ctx, span := tracing.Start(ctx, "admin.Authenticate")
+	defer span.End()
+
+	limitKeys := ratelimit.Keys("admin-login", server.ClientFromContext(ctx).IP)
+	if err := s.limiter.Allow(ctx, limitKeys...); err != nil {
+		return "", err
+	}
+
 	candidate := sha256.Sum256([]byte(password))
 	if subtle.ConstantTimeCompare(candidate[:], s.passwordHash[:]) != 1 || password == "" {
+		s.limiter.Fail(ctx, limitKeys...)
+		s.audit.Record(ctx, audit.Event{Type: audit.TypeAdminLogin, Outcome: audit.OutcomeFailure, Actor: audit.ActorAnonymous})
 		return "", ErrInvalidCredentials
 	}
-	return s.tokens.GenerateAdminAccessToken(adminAccessTokenTTL)
+	token, err := s.tokens.GenerateAdminAccessToken(adminAccessTokenTTL)
+	if err != nil {
+		return "", err
+	}
+	s.limiter.Reset(ctx, limitKeys...)
+	s.audit.Record(ctx, audit.Event{Type: audit.TypeAdminLogin, Actor: audit.ActorAdmin})
+	return token, nil
+}
+
+func (s *Service) ListAuditEvents(ctx context.Context, filter *audit.EventFilter, limit, offset int) (core.Page[audit.Event], error) {
+	ctx, span := tracing.Start(ctx, "admin.ListAuditEvents")
+	defer span.End()
+
+	return s.audit.ListEvents(ctx, filter, limit, offset)
 }
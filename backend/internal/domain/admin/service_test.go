@@ -16,15 +16,15 @@ const (
 )
 
 func TestServiceAuthenticate(t *testing.T) {
-	tokenService := session.NewService(nil, session.Config{
+	tokenService := session.NewService(nil, nil, session.Config{
 		JWTSecret:     testJWTSecret,
 		JWTExpiration: time.Hour,
 	})
-	service := admin.NewService(testAdminPassword, tokenService)
+	service := admin.NewService(testAdminPassword, tokenService, nil, nil)
 
 	t.Run("valid password", func(t *testing.T) {
 		before := time.Now()
-		token, err := service.Authenticate(testAdminPassword)
+		token, err := service.Authenticate(t.Context(), testAdminPassword)
 		after := time.Now()
 
 		if err != nil {
@@ -62,7 +62,7 @@ func TestServiceAuthenticate(t *testing.T) {
 		}
 		for _, tt := range tests {
 			t.Run(tt.name, func(t *testing.T) {
-				token, err := service.Authenticate(tt.password)
+				token, err := service.Authenticate(t.Context(), tt.password)
 				if !errors.Is(err, admin.ErrInvalidCredentials) {
 					t.Fatalf("Authenticate() error = %v, want ErrInvalidCredentials", err)
 				}
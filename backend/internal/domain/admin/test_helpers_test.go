@@ -4,9 +4,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/azaviyalov/null3/backend/internal/core/ratelimit"
 	"github.com/azaviyalov/null3/backend/internal/core/server"
 	"github.com/azaviyalov/null3/backend/internal/domain/account"
 	"github.com/azaviyalov/null3/backend/internal/domain/admin"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"github.com/azaviyalov/null3/backend/internal/testutil"
 	"github.com/labstack/echo/v4"
@@ -30,12 +32,21 @@ func newAdminTestEnvironment(t *testing.T) *adminTestEnvironment {
 		RefreshTokenExpiration: 7 * 24 * time.Hour,
 		SecureCookies:          true,
 	}
-	sessionService := session.NewService(session.NewRepository(database), sessionConfig)
-	accountService := account.NewService(account.NewRepository(database), sessionService, account.Config{
+	auditService := audit.NewService(audit.NewRepository(database))
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.Config{
+		Enabled:          true,
+		Burst:            10,
+		PerMinute:        10,
+		FailureThreshold: 3,
+		Lockout:          time.Minute,
+		MaxLockout:       time.Hour,
+	})
+	sessionService := session.NewService(session.NewRepository(database), auditService, sessionConfig)
+	accountService := account.NewService(account.NewRepository(database), sessionService, auditService, limiter, account.Config{
 		PasswordResetTokenExpiration: time.Hour,
 		FrontendURL:                  "https://journal.example",
 	})
-	adminService := admin.NewService(testAdminPassword, sessionService)
+	adminService := admin.NewService(testAdminPassword, sessionService, auditService, limiter)
 
 	testutil.DiscardLogs(t)
 
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EventFilter struct {
+	UserID  *uint
+	Type    *string
+	Outcome *string
+	From    *time.Time
+	To      *time.Time
+}
+
+func NewEventFilter() *EventFilter {
+	return &EventFilter{}
+}
+
+func (f *EventFilter) WithUserID(userID uint) *EventFilter {
+	f.UserID = &userID
+	return f
+}
+
+func (f *EventFilter) WithType(eventType string) *EventFilter {
+	f.Type = &eventType
+	return f
+}
+
+func (f *EventFilter) WithOutcome(outcome string) *EventFilter {
+	f.Outcome = &outcome
+	return f
+}
+
+func (f *EventFilter) WithCreatedRange(from, to *time.Time) *EventFilter {
+	f.From = from
+	f.To = to
+	return f
+}
+
+func (f EventFilter) Apply(db *gorm.DB) *gorm.DB {
+	if f.UserID != nil {
+		db = db.Where("user_id = ?", *f.UserID)
+	}
+	if f.Type != nil {
+		db = db.Where("type = ?", *f.Type)
+	}
+	if f.Outcome != nil {
+		db = db.Where("outcome = ?", *f.Outcome)
+	}
+	if f.From != nil {
+		db = db.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		db = db.Where("created_at < ?", *f.To)
+	}
+	return db
+}
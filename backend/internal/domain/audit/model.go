@@ -0,0 +1,45 @@
+package audit
+
+import "time"
+
+const (
+	TypeLogin                  = "login"
+	TypeLogout                 = "logout"
+	TypeRefreshTokenReused     = "refresh_token_reused"
+	TypePasswordResetRequested = "password_reset_requested"
+	TypePasswordReset          = "password_reset"
+	TypeInviteCreated          = "invite_created"
+	TypeInviteRedeemed         = "invite_redeemed"
+	TypeAdminLogin             = "admin_login"
+	TypeTwoFactorEnabled       = "two_factor_enabled"
+	TypeTwoFactorDisabled      = "two_factor_disabled"
+	TypePasskeyRegistered      = "passkey_registered"
+	TypePasskeyRevoked         = "passkey_revoked"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+const (
+	ActorUser      = "user"
+	ActorAdmin     = "admin"
+	ActorAnonymous = "anonymous"
+)
+
+type Event struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null;index"`
+	Type      string    `json:"type" gorm:"not null;index"`
+	Outcome   string    `json:"outcome" gorm:"not null"`
+	Actor     string    `json:"actor" gorm:"not null"`
+	UserID    *uint     `json:"user_id,omitempty" gorm:"index"`
+	IP        string    `json:"ip" gorm:"not null"`
+	UserAgent string    `json:"user_agent" gorm:"not null"`
+	Detail    string    `json:"detail,omitempty" gorm:"not null"`
+}
+
+func (Event) TableName() string {
+	return "audit_events"
+}
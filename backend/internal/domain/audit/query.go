@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	eventTypes = []string{
+		TypeLogin,
+		TypeLogout,
+		TypeRefreshTokenReused,
+		TypePasswordResetRequested,
+		TypePasswordReset,
+		TypeInviteCreated,
+		TypeInviteRedeemed,
+		TypeAdminLogin,
+		TypeTwoFactorEnabled,
+		TypeTwoFactorDisabled,
+		TypePasskeyRegistered,
+		TypePasskeyRevoked,
+	}
+	outcomes = []string{OutcomeSuccess, OutcomeFailure}
+)
+
+func ParsePagination(c echo.Context) (int, int, error) {
+	limit, err := parseIntQueryParam(c, "limit", 10)
+	if err != nil {
+		return 0, 0, err
+	}
+	if limit <= 0 {
+		return 0, 0, fmt.Errorf("limit must be positive")
+	}
+	offset, err := parseIntQueryParam(c, "offset", 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	if offset < 0 {
+		return 0, 0, fmt.Errorf("offset cannot be negative")
+	}
+	return limit, offset, nil
+}
+
+func ParseEventFilter(c echo.Context) (*EventFilter, error) {
+	filter := NewEventFilter()
+	if eventType := c.QueryParam("type"); eventType != "" {
+		if !slices.Contains(eventTypes, eventType) {
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+		filter = filter.WithType(eventType)
+	}
+	if outcome := c.QueryParam("outcome"); outcome != "" {
+		if !slices.Contains(outcomes, outcome) {
+			return nil, fmt.Errorf("unknown outcome %q", outcome)
+		}
+		filter = filter.WithOutcome(outcome)
+	}
+	if userIDParam := c.QueryParam("user_id"); userIDParam != "" {
+		userID, err := strconv.ParseUint(userIDParam, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse user_id: %w", err)
+		}
+		filter = filter.WithUserID(uint(userID))
+	}
+
+	from, err := parseTimeQueryParam(c, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseTimeQueryParam(c, "to")
+	if err != nil {
+		return nil, err
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	return filter.WithCreatedRange(from, to), nil
+}
+
+func parseIntQueryParam(c echo.Context, name string, fallback int) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return parsed, nil
+}
+
+func parseTimeQueryParam(c echo.Context, name string) (*time.Time, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: expected RFC 3339 timestamp", name)
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) SaveEvent(ctx context.Context, event *Event) (*Event, error) {
+	if err := r.db.WithContext(ctx).Create(event).Error; err != nil {
+		return nil, fmt.Errorf("save audit event: %w", err)
+	}
+	return event, nil
+}
+
+func (r *Repository) ListEvents(ctx context.Context, filter *EventFilter, limit, offset int) ([]Event, error) {
+	var events []Event
+	err := filter.Apply(r.db.WithContext(ctx)).
+		Order("created_at DESC").
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&events).Error
+	if err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *Repository) CountEvents(ctx context.Context, filter *EventFilter) (int64, error) {
+	var count int64
+	if err := filter.Apply(r.db.WithContext(ctx).Model(&Event{})).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count audit events: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/logging"
+	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Record(ctx context.Context, event Event) {
+	if s == nil {
+		return
+	}
+	ctx, span := tracing.Start(ctx, "audit.Record")
+	defer span.End()
+
+	client := server.ClientFromContext(ctx)
+	if event.IP == "" {
+		event.IP = client.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = client.UserAgent
+	}
+	if event.Actor == "" {
+		event.Actor = ActorAnonymous
+	}
+	if event.Outcome == "" {
+		event.Outcome = OutcomeSuccess
+	}
+
+	if _, err := s.repo.SaveEvent(ctx, &event); err != nil {
+		logging.FromContext(ctx).Warn("failed to record audit event", "type", event.Type, "outcome", event.Outcome, "error", err)
+	}
+}
+
+func (s *Service) ListEvents(ctx context.Context, filter *EventFilter, limit, offset int) (core.Page[Event], error) {
+	ctx, span := tracing.Start(ctx, "audit.ListEvents")
+	defer span.End()
+
+	events, err := s.repo.ListEvents(ctx, filter, limit, offset)
+	if err != nil {
+		return core.Page[Event]{}, err
+	}
+	totalCount, err := s.repo.CountEvents(ctx, filter)
+	if err != nil {
+		return core.Page[Event]{}, err
+	}
+	if events == nil {
+		events = []Event{}
+	}
+	return core.Page[Event]{Items: events, TotalCount: totalCount}, nil
+}
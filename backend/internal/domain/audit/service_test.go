@@ -0,0 +1,57 @@
+package audit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestServiceRecordAndListEvents(t *testing.T) {
+	testutil.SkipIntegration(t)
+	database := testutil.NewDatabase(t, "audit.sqlite")
+	service := audit.NewService(audit.NewRepository(database))
+	ctx := server.WithClient(t.Context(), server.Client{IP: "203.0.113.9", UserAgent: "audit-test"})
+
+	firstUserID, secondUserID := uint(7), uint(8)
+	service.Record(ctx, audit.Event{Type: audit.TypeLogin, Actor: audit.ActorUser, UserID: &firstUserID})
+	service.Record(ctx, audit.Event{Type: audit.TypeLogin, Outcome: audit.OutcomeFailure, Detail: "login=someone"})
+	service.Record(ctx, audit.Event{Type: audit.TypeLogout, Actor: audit.ActorUser, UserID: &firstUserID, IP: "192.0.2.1"})
+	service.Record(ctx, audit.Event{Type: audit.TypeLogin, Actor: audit.ActorUser, UserID: &secondUserID})
+
+	page, err := service.ListEvents(t.Context(), audit.NewEventFilter().WithUserID(firstUserID), 10, 0)
+	if err != nil {
+		t.Fatalf("ListEvents() error = %v", err)
+	}
+	if page.TotalCount != 2 || len(page.Items) != 2 {
+		t.Fatalf("ListEvents() = %d items of %d, want 2 of 2", len(page.Items), page.TotalCount)
+	}
+	if latest := page.Items[0]; latest.Type != audit.TypeLogout || latest.IP != "192.0.2.1" || latest.UserAgent != "audit-test" || latest.Outcome != audit.OutcomeSuccess {
+		t.Errorf("latest event = %+v, want logout with explicit IP and client user agent", latest)
+	}
+
+	page, err = service.ListEvents(t.Context(), audit.NewEventFilter().WithType(audit.TypeLogin).WithOutcome(audit.OutcomeFailure), 10, 0)
+	if err != nil {
+		t.Fatalf("ListEvents() failures error = %v", err)
+	}
+	if page.TotalCount != 1 {
+		t.Fatalf("failed login count = %d, want 1", page.TotalCount)
+	}
+	if failure := page.Items[0]; failure.Actor != audit.ActorAnonymous || failure.UserID != nil || failure.IP != "203.0.113.9" || failure.Detail != "login=someone" {
+		t.Errorf("failed login event = %+v", failure)
+	}
+
+	future := time.Now().Add(time.Hour)
+	page, err = service.ListEvents(t.Context(), audit.NewEventFilter().WithCreatedRange(&future, nil), 10, 0)
+	if err != nil {
+		t.Fatalf("ListEvents() future error = %v", err)
+	}
+	if page.TotalCount != 0 || page.Items == nil {
+		t.Errorf("future events = %+v, want an empty page", page)
+	}
+
+	var nilService *audit.Service
+	nilService.Record(ctx, audit.Event{Type: audit.TypeLogin})
+}
@@ -0,0 +1,189 @@
+package journal
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/logging"
+	"github.com/azaviyalov/null3/backend/internal/core/metrics"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
+)
+
+const (
+	maxAttachmentFileNameLength = 255
+	attachmentSniffLength       = 512
+)
+
+type AttachmentUsage struct {
+	Used  int64 `json:"used"`
+	Quota int64 `json:"quota"`
+}
+
+func (s *Service) CreateAttachment(ctx context.Context, userID, entryID uint, fileName string, size int64, r io.Reader) (*Attachment, error) {
+	ctx, span := tracing.Start(ctx, "journal.CreateAttachment")
+	defer span.End()
+
+	filter := NewDiaryEntryFilter().WithUserID(userID).WithID(entryID)
+	if _, err := s.repo.GetDiaryEntry(ctx, filter); err != nil {
+		return nil, err
+	}
+	fileName, err := normalizeAttachmentFileName(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if size > s.config.AttachmentMaxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+	used, err := s.repo.SumAttachmentSizes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if used+max(size, 0) > s.config.AttachmentQuota {
+		return nil, ErrAttachmentQuota
+	}
+
+	reader := bufio.NewReaderSize(io.LimitReader(r, s.config.AttachmentMaxSize+1), attachmentSniffLength)
+	head, err := reader.Peek(attachmentSniffLength)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("read attachment: %w", err)
+	}
+	mimeType := http.DetectContentType(head)
+
+	hash := sha256.New()
+	key := fmt.Sprintf("%d/%s", userID, rand.Text())
+	written, err := s.storage.Put(ctx, key, io.TeeReader(reader, hash))
+	if err != nil {
+		s.deleteStoredFile(ctx, key)
+		return nil, err
+	}
+	if written > s.config.AttachmentMaxSize {
+		s.deleteStoredFile(ctx, key)
+		return nil, ErrAttachmentTooLarge
+	}
+
+	attachment, err := s.repo.SaveAttachment(ctx, &Attachment{
+		UserID:       userID,
+		DiaryEntryID: entryID,
+		FileName:     fileName,
+		MIMEType:     mimeType,
+		Size:         written,
+		SHA256:       hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:   key,
+	}, s.config.AttachmentQuota)
+	if err != nil {
+		s.deleteStoredFile(ctx, key)
+		return nil, err
+	}
+	metrics.CountJournalRecordsCreated("attachment", 1)
+	return attachment, nil
+}
+
+func (s *Service) ListAttachments(ctx context.Context, userID, entryID uint) ([]Attachment, error) {
+	ctx, span := tracing.Start(ctx, "journal.ListAttachments")
+	defer span.End()
+
+	filter := NewDiaryEntryFilter().WithUserID(userID).WithID(entryID).WithDeletedMode(core.DeletedModeAll)
+	if _, err := s.repo.GetDiaryEntry(ctx, filter); err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.repo.ListAttachments(ctx, NewAttachmentFilter().WithUserID(userID).WithDiaryEntryID(entryID))
+	if err != nil {
+		return nil, err
+	}
+	if attachments == nil {
+		attachments = []Attachment{}
+	}
+	return attachments, nil
+}
+
+func (s *Service) GetAttachment(ctx context.Context, userID, id uint) (*Attachment, error) {
+	ctx, span := tracing.Start(ctx, "journal.GetAttachment")
+	defer span.End()
+
+	return s.repo.GetAttachment(ctx, NewAttachmentFilter().WithUserID(userID).WithID(id))
+}
+
+func (s *Service) OpenAttachment(ctx context.Context, userID, id uint) (*Attachment, io.ReadCloser, error) {
+	ctx, span := tracing.Start(ctx, "journal.OpenAttachment")
+	defer span.End()
+
+	attachment, err := s.GetAttachment(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.storage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+func (s *Service) GetAttachmentUsage(ctx context.Context, userID uint) (*AttachmentUsage, error) {
+	ctx, span := tracing.Start(ctx, "journal.GetAttachmentUsage")
+	defer span.End()
+
+	used, err := s.repo.SumAttachmentSizes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &AttachmentUsage{Used: used, Quota: s.config.AttachmentQuota}, nil
+}
+
+func (s *Service) DeleteAttachment(ctx context.Context, userID, id uint) (*Attachment, error) {
+	ctx, span := tracing.Start(ctx, "journal.DeleteAttachment")
+	defer span.End()
+
+	attachment, err := s.GetAttachment(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	filter := NewDiaryEntryFilter().WithUserID(userID).WithID(attachment.DiaryEntryID).WithDeletedMode(core.DeletedModeAll)
+	entry, err := s.repo.GetDiaryEntry(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	referenced, err := ExtractAttachmentIDs(entry.Markdown)
+	if err == nil && slices.Contains(referenced, attachment.ID) {
+		return nil, ErrAttachmentInUse
+	}
+
+	if err := s.repo.DeleteAttachment(ctx, attachment); err != nil {
+		return nil, err
+	}
+	s.deleteStoredFile(ctx, attachment.StorageKey)
+	return attachment, nil
+}
+
+func (s *Service) deleteStoredFile(ctx context.Context, key string) {
+	if s.pendingFiles != nil {
+		*s.pendingFiles = append(*s.pendingFiles, key)
+		return
+	}
+	if err := s.storage.Delete(ctx, key); err != nil {
+		logging.FromContext(ctx).Warn("failed to delete stored attachment", "key", key, "error", err)
+	}
+}
+
+func normalizeAttachmentFileName(fileName string) (string, error) {
+	fileName = strings.TrimSpace(path.Base(strings.ReplaceAll(fileName, `\`, "/")))
+	if fileName == "" || fileName == "." || fileName == "/" {
+		return "", fmt.Errorf("%w: attachment file name is required", core.ErrInvalidItem)
+	}
+	if !utf8.ValidString(fileName) || utf8.RuneCountInString(fileName) > maxAttachmentFileNameLength {
+		return "", fmt.Errorf("%w: attachment file name is invalid", core.ErrInvalidItem)
+	}
+	return fileName, nil
+}
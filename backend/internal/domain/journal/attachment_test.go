@@ -0,0 +1,237 @@
+package journal_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestServiceAttachments(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	other := createJournalUser(t, environment, "other")
+	ctx := t.Context()
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	entry, err := environment.service.CreateDiaryEntry(ctx, owner.ID, diaryRequest("photo day", &occurredAt))
+	if err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+	sibling, err := environment.service.CreateDiaryEntry(ctx, owner.ID, diaryRequest("another day", &occurredAt))
+	if err != nil {
+		t.Fatalf("create sibling diary entry: %v", err)
+	}
+
+	photo, err := environment.service.CreateAttachment(ctx, owner.ID, entry.ID, `C:\photos\lake.png`, int64(len(pngHeader)), bytes.NewReader(pngHeader))
+	if err != nil {
+		t.Fatalf("CreateAttachment() error = %v", err)
+	}
+	sum := sha256.Sum256(pngHeader)
+	if photo.FileName != "lake.png" || photo.MIMEType != "image/png" || photo.Size != int64(len(pngHeader)) || photo.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("CreateAttachment() = %+v, want lake.png image/png with its size and hash", photo)
+	}
+	if _, err := environment.service.CreateAttachment(ctx, other.ID, entry.ID, "x.txt", 1, strings.NewReader("x")); !errors.Is(err, core.ErrItemNotFound) {
+		t.Fatalf("CreateAttachment() on foreign entry error = %v, want ErrItemNotFound", err)
+	}
+
+	reference := fmt.Sprintf("photo day ![lake](attachment:%d)", photo.ID)
+	entry, err = environment.service.UpdateDiaryEntry(ctx, owner.ID, entry.ID, diaryRequest(reference, &occurredAt))
+	if err != nil {
+		t.Fatalf("UpdateDiaryEntry() with attachment reference error = %v", err)
+	}
+	if len(entry.Attachments) != 1 || entry.Attachments[0].ID != photo.ID {
+		t.Fatalf("diary entry attachments = %+v, want the uploaded photo", entry.Attachments)
+	}
+	if _, err := environment.service.UpdateDiaryEntry(ctx, owner.ID, sibling.ID, diaryRequest(reference, &occurredAt)); !errors.Is(err, core.ErrInvalidItem) {
+		t.Fatalf("UpdateDiaryEntry() referencing another entry's attachment error = %v, want ErrInvalidItem", err)
+	}
+	if _, err := environment.service.CreateDiaryEntry(ctx, owner.ID, diaryRequest(reference, &occurredAt)); !errors.Is(err, core.ErrInvalidItem) {
+		t.Fatalf("CreateDiaryEntry() referencing an attachment error = %v, want ErrInvalidItem", err)
+	}
+	if _, err := environment.service.DeleteAttachment(ctx, owner.ID, photo.ID); !errors.Is(err, journal.ErrAttachmentInUse) {
+		t.Fatalf("DeleteAttachment() while referenced error = %v, want ErrAttachmentInUse", err)
+	}
+
+	if _, err := environment.service.CreateAttachment(ctx, owner.ID, entry.ID, "big.bin", -1, bytes.NewReader(make([]byte, testJournalConfig.AttachmentMaxSize+1))); !errors.Is(err, journal.ErrAttachmentTooLarge) {
+		t.Fatalf("CreateAttachment() over the size limit error = %v, want ErrAttachmentTooLarge", err)
+	}
+	full := make([]byte, testJournalConfig.AttachmentMaxSize)
+	if _, err := environment.service.CreateAttachment(ctx, owner.ID, sibling.ID, "full.bin", int64(len(full)), bytes.NewReader(full)); err != nil {
+		t.Fatalf("CreateAttachment() within quota error = %v", err)
+	}
+	if _, err := environment.service.CreateAttachment(ctx, owner.ID, sibling.ID, "over.bin", int64(len(full)), bytes.NewReader(full)); !errors.Is(err, journal.ErrAttachmentQuota) {
+		t.Fatalf("CreateAttachment() over quota error = %v, want ErrAttachmentQuota", err)
+	}
+	if _, err := environment.service.CreateAttachment(ctx, other.ID, entry.ID, "x.txt", 1, strings.NewReader("x")); !errors.Is(err, core.ErrItemNotFound) {
+		t.Fatalf("CreateAttachment() for another user error = %v, want ErrItemNotFound", err)
+	}
+
+	if _, err := environment.service.UpdateDiaryEntry(ctx, owner.ID, entry.ID, diaryRequest("photo day", &occurredAt)); err != nil {
+		t.Fatalf("UpdateDiaryEntry() removing reference error = %v", err)
+	}
+	if _, err := environment.service.DeleteAttachment(ctx, owner.ID, photo.ID); err != nil {
+		t.Fatalf("DeleteAttachment() error = %v", err)
+	}
+	if _, _, err := environment.service.OpenAttachment(ctx, owner.ID, photo.ID); !errors.Is(err, core.ErrItemNotFound) {
+		t.Fatalf("OpenAttachment() after delete error = %v, want ErrItemNotFound", err)
+	}
+	usage, err := environment.service.GetAttachmentUsage(ctx, owner.ID)
+	if err != nil || usage.Used != testJournalConfig.AttachmentMaxSize || usage.Quota != testJournalConfig.AttachmentQuota {
+		t.Fatalf("GetAttachmentUsage() = %+v, %v, want only the remaining attachment counted", usage, err)
+	}
+}
+
+func TestSaveAttachmentSerializesQuota(t *testing.T) {
+	testutil.SkipIntegration(t)
+	database := testutil.NewDatabase(t, "journal.sqlite?_txlock=deferred")
+	repository := journal.NewRepository(database)
+	environment := &journalTestEnvironment{
+		database:   database,
+		repository: repository,
+		service:    journal.NewService(repository, journal.NewLocalStorage(t.TempDir()), testJournalConfig),
+	}
+	owner := createJournalUser(t, environment, "owner")
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	entry, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("photo day", &occurredAt))
+	if err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+
+	err = database.Callback().Row().After("gorm:row").Register("test:slow_quota_sum", func(db *gorm.DB) {
+		if db.Statement.Table == "attachments" {
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	const quota = 4
+	errs := make([]error, 12)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Go(func() {
+			_, errs[i] = repository.SaveAttachment(t.Context(), &journal.Attachment{
+				UserID:       owner.ID,
+				DiaryEntryID: entry.ID,
+				FileName:     "note.txt",
+				MIMEType:     "text/plain",
+				Size:         1,
+				StorageKey:   fmt.Sprintf("%d/note-%d", owner.ID, i),
+			}, quota)
+		})
+	}
+	wg.Wait()
+
+	saved := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			saved++
+		case !errors.Is(err, journal.ErrAttachmentQuota):
+			t.Fatalf("SaveAttachment() error = %v, want nil or ErrAttachmentQuota", err)
+		}
+	}
+	if saved != quota {
+		t.Fatalf("saved attachments = %d, want %d", saved, quota)
+	}
+}
+
+func TestAttachmentsHTTPContract(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	other := createJournalUser(t, environment, "other")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	otherCookie := journalUserCookie(t, tokenService, other.ID)
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	entry, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("notes", &occurredAt))
+	if err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+	uploadPath := fmt.Sprintf("/api/journal/diary-entries/%d/attachments", entry.ID)
+
+	upload := uploadAttachment(t, e, uploadPath, "notes.txt", []byte("plain text notes"), ownerCookie)
+	if upload.Code != http.StatusCreated {
+		t.Fatalf("POST %s status = %d, want %d: %s", uploadPath, upload.Code, http.StatusCreated, upload.Body.String())
+	}
+	var attachment journal.Attachment
+	decodeJournalResponse(t, upload, &attachment)
+	contentPath := fmt.Sprintf("/api/journal/attachments/%d/content", attachment.ID)
+
+	download := serveJournalJSON(t, e, http.MethodGet, contentPath, nil, ownerCookie)
+	if download.Code != http.StatusOK || download.Body.String() != "plain text notes" {
+		t.Fatalf("GET %s = %d %q, want the uploaded content", contentPath, download.Code, download.Body.String())
+	}
+	if got := download.Header().Get("Content-Disposition"); got != `attachment; filename=notes.txt` {
+		t.Fatalf("GET %s Content-Disposition = %q", contentPath, got)
+	}
+	if got := download.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Fatalf("GET %s X-Content-Type-Options = %q, want nosniff", contentPath, got)
+	}
+
+	tests := []struct {
+		name     string
+		response *httptest.ResponseRecorder
+		want     int
+	}{
+		{name: "foreign download", response: serveJournalJSON(t, e, http.MethodGet, contentPath, nil, otherCookie), want: http.StatusNotFound},
+		{name: "anonymous download", response: serveJournalJSON(t, e, http.MethodGet, contentPath, nil), want: http.StatusUnauthorized},
+		{name: "foreign upload", response: uploadAttachment(t, e, uploadPath, "x.txt", []byte("x"), otherCookie), want: http.StatusNotFound},
+		{name: "too large", response: uploadAttachment(t, e, uploadPath, "big.bin", make([]byte, testJournalConfig.AttachmentMaxSize+1), ownerCookie), want: http.StatusRequestEntityTooLarge},
+		{name: "missing file", response: serveJournalJSON(t, e, http.MethodPost, uploadPath, nil, ownerCookie), want: http.StatusBadRequest},
+		{name: "list", response: serveJournalJSON(t, e, http.MethodGet, uploadPath, nil, ownerCookie), want: http.StatusOK},
+		{name: "usage", response: serveJournalJSON(t, e, http.MethodGet, "/api/journal/attachments/usage", nil, ownerCookie), want: http.StatusOK},
+		{name: "metadata", response: serveJournalJSON(t, e, http.MethodGet, fmt.Sprintf("/api/journal/attachments/%d", attachment.ID), nil, ownerCookie), want: http.StatusOK},
+		{name: "delete", response: serveJournalJSON(t, e, http.MethodDelete, fmt.Sprintf("/api/journal/attachments/%d", attachment.ID), nil, ownerCookie), want: http.StatusOK},
+		{name: "download after delete", response: serveJournalJSON(t, e, http.MethodGet, contentPath, nil, ownerCookie), want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if tt.response.Code != tt.want {
+			t.Fatalf("%s status = %d, want %d", tt.name, tt.response.Code, tt.want)
+		}
+	}
+}
+
+func uploadAttachment(t *testing.T, e http.Handler, path, fileName string, content []byte, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatalf("create multipart file: %v", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		t.Fatalf("write multipart file: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close multipart body: %v", err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, path, &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+	e.ServeHTTP(response, request)
+	return response
+}
@@ -0,0 +1,230 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
+)
+
+const maxBulkIDs = 500
+
+type BulkAction string
+
+const (
+	BulkActionDelete  BulkAction = "delete"
+	BulkActionRestore BulkAction = "restore"
+	BulkActionPurge   BulkAction = "purge"
+	BulkActionTag     BulkAction = "tag"
+)
+
+type BulkMode string
+
+const (
+	BulkModeAllOrNothing BulkMode = "all_or_nothing"
+	BulkModeBestEffort   BulkMode = "best_effort"
+)
+
+const (
+	BulkErrorNotFound = "not_found"
+	BulkErrorInvalid  = "invalid"
+)
+
+type BulkRequest struct {
+	IDs    []uint     `json:"ids" validate:"required"`
+	Action BulkAction `json:"action" validate:"required"`
+	Mode   BulkMode   `json:"mode,omitempty"`
+	Tags   []string   `json:"tags,omitempty"`
+}
+
+type BulkResult struct {
+	ID      uint   `json:"id"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type BulkReport struct {
+	Action    BulkAction   `json:"action"`
+	Mode      BulkMode     `json:"mode"`
+	Applied   bool         `json:"applied"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []BulkResult `json:"results"`
+}
+
+type bulkApplyFunc func(s *Service, ctx context.Context, userID, id uint) error
+
+func (s *Service) BulkMoodRecords(ctx context.Context, userID uint, req BulkRequest) (*BulkReport, error) {
+	ctx, span := tracing.Start(ctx, "journal.BulkMoodRecords")
+	defer span.End()
+
+	var apply bulkApplyFunc
+	switch req.Action {
+	case BulkActionDelete:
+		apply = func(s *Service, ctx context.Context, userID, id uint) error {
+			_, err := s.DeleteMoodRecord(ctx, userID, id)
+			return err
+		}
+	case BulkActionRestore:
+		apply = func(s *Service, ctx context.Context, userID, id uint) error {
+			_, err := s.RestoreMoodRecord(ctx, userID, id)
+			return err
+		}
+	case BulkActionPurge:
+		apply = func(s *Service, ctx context.Context, userID, id uint) error {
+			_, err := s.PurgeMoodRecord(ctx, userID, id)
+			return err
+		}
+	case BulkActionTag:
+		apply = func(s *Service, ctx context.Context, userID, id uint) error {
+			entry, err := s.repo.GetMoodRecord(ctx, NewMoodRecordFilter().WithUserID(userID).WithID(id))
+			if err != nil {
+				return err
+			}
+			_, err = s.UpdateMoodRecord(ctx, userID, id, MoodEditRecordRequest{
+				Feeling: entry.Feeling,
+				Emoji:   entry.Emoji,
+				Note:    entry.Note,
+				Tags:    append(tagNames(entry.Tags), req.Tags...),
+			})
+			return err
+		}
+	}
+	return s.runBulk(ctx, userID, req, apply)
+}
+
+func (s *Service) BulkDiaryEntries(ctx context.Context, userID uint, req BulkRequest) (*BulkReport, error) {
+	ctx, span := tracing.Start(ctx, "journal.BulkDiaryEntries")
+	defer span.End()
+
+	var apply bulkApplyFunc
+	switch req.Action {
+	case BulkActionDelete:
+		apply = func(s *Service, ctx context.Context, userID, id uint) error {
+			_, err := s.DeleteDiaryEntry(ctx, userID, id)
+			return err
+		}
+	case BulkActionRestore:
+		apply = func(s *Service, ctx context.Context, userID, id uint) error {
+			_, err := s.RestoreDiaryEntry(ctx, userID, id)
+			return err
+		}
+	case BulkActionPurge:
+		apply = func(s *Service, ctx context.Context, userID, id uint) error {
+			_, err := s.PurgeDiaryEntry(ctx, userID, id)
+			return err
+		}
+	case BulkActionTag:
+		apply = func(s *Service, ctx context.Context, userID, id uint) error {
+			entry, err := s.repo.GetDiaryEntry(ctx, NewDiaryEntryFilter().WithUserID(userID).WithID(id))
+			if err != nil {
+				return err
+			}
+			_, err = s.UpdateDiaryEntry(ctx, userID, id, DiaryEditEntryRequest{
+				Title:      entry.Title,
+				Markdown:   entry.Markdown,
+				OccurredAt: &entry.OccurredAt,
+				Tags:       append(explicitDiaryTagNames(entry), req.Tags...),
+			})
+			return err
+		}
+	}
+	return s.runBulk(ctx, userID, req, apply)
+}
+
+func (s *Service) runBulk(ctx context.Context, userID uint, req BulkRequest, apply bulkApplyFunc) (*BulkReport, error) {
+	ids, mode, err := normalizeBulkRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if apply == nil {
+		return nil, fmt.Errorf("%w: unknown bulk action %q", core.ErrInvalidItem, req.Action)
+	}
+
+	report := &BulkReport{Action: req.Action, Mode: mode, Results: make([]BulkResult, 0, len(ids))}
+	var files []string
+	err = s.repo.WithTx(ctx, func(repo *Repository) error {
+		for _, id := range ids {
+			var itemService *Service
+			err := repo.WithTx(ctx, func(itemRepo *Repository) error {
+				itemService = s.inTransaction(itemRepo)
+				return apply(itemService, ctx, userID, id)
+			})
+
+			result := BulkResult{ID: id, OK: err == nil}
+			switch {
+			case err == nil:
+				report.Succeeded++
+				files = append(files, *itemService.pendingFiles...)
+			case errors.Is(err, core.ErrItemNotFound):
+				result.Error = BulkErrorNotFound
+			case errors.Is(err, core.ErrInvalidItem):
+				result.Error = BulkErrorInvalid
+			default:
+				return err
+			}
+			if err != nil {
+				result.Message = err.Error()
+				report.Failed++
+			}
+			report.Results = append(report.Results, result)
+		}
+
+		if report.Failed > 0 && mode == BulkModeAllOrNothing {
+			return ErrBulkFailed
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrBulkFailed) {
+			return report, err
+		}
+		return nil, err
+	}
+
+	report.Applied = true
+	for _, key := range files {
+		s.deleteStoredFile(ctx, key)
+	}
+	return report, nil
+}
+
+func normalizeBulkRequest(req BulkRequest) ([]uint, BulkMode, error) {
+	mode := req.Mode
+	switch mode {
+	case "":
+		mode = BulkModeAllOrNothing
+	case BulkModeAllOrNothing, BulkModeBestEffort:
+	default:
+		return nil, "", fmt.Errorf("%w: unknown bulk mode %q", core.ErrInvalidItem, req.Mode)
+	}
+
+	ids := make([]uint, 0, len(req.IDs))
+	seen := make(map[uint]struct{}, len(req.IDs))
+	for _, id := range req.IDs {
+		if _, ok := seen[id]; ok || id == 0 {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one ID is required", core.ErrInvalidItem)
+	}
+	if len(ids) > maxBulkIDs {
+		return nil, "", fmt.Errorf("%w: at most %d IDs can be changed at once", core.ErrInvalidItem, maxBulkIDs)
+	}
+
+	if req.Action == BulkActionTag {
+		if len(req.Tags) == 0 {
+			return nil, "", fmt.Errorf("%w: tags are required for the tag action", core.ErrInvalidItem)
+		}
+		if _, err := normalizeTagNames(req.Tags); err != nil {
+			return nil, "", err
+		}
+	}
+	return ids, mode, nil
+}
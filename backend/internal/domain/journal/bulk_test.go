@@ -0,0 +1,155 @@
+package journal_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestServiceBulkMoodRecords(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	other := createJournalUser(t, environment, "other")
+	ctx := t.Context()
+	createdAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	first := saveMoodRecord(t, environment, owner.ID, "calm", createdAt)
+	second := saveMoodRecord(t, environment, owner.ID, "tired", createdAt)
+	foreign := saveMoodRecord(t, environment, other.ID, "busy", createdAt)
+	tagged, err := environment.service.CreateMoodRecord(ctx, owner.ID, journal.MoodEditRecordRequest{Feeling: "glad", Tags: []string{"home"}})
+	if err != nil {
+		t.Fatalf("create tagged mood record: %v", err)
+	}
+
+	ids := []uint{first.ID, second.ID, foreign.ID, first.ID}
+	report, err := environment.service.BulkMoodRecords(ctx, owner.ID, journal.BulkRequest{IDs: ids, Action: journal.BulkActionDelete})
+	if !errors.Is(err, journal.ErrBulkFailed) {
+		t.Fatalf("all-or-nothing BulkMoodRecords() error = %v, want ErrBulkFailed", err)
+	}
+	if report.Applied || report.Succeeded != 2 || report.Failed != 1 || report.Results[2].Error != journal.BulkErrorNotFound {
+		t.Fatalf("all-or-nothing report = %+v, want the foreign record reported as not found", report)
+	}
+	assertActiveMoodRecords(t, environment, owner.ID, 3)
+
+	report, err = environment.service.BulkMoodRecords(ctx, owner.ID, journal.BulkRequest{IDs: ids, Action: journal.BulkActionDelete, Mode: journal.BulkModeBestEffort})
+	if err != nil {
+		t.Fatalf("best-effort BulkMoodRecords() error = %v", err)
+	}
+	if !report.Applied || report.Succeeded != 2 || report.Failed != 1 || len(report.Results) != 3 {
+		t.Fatalf("best-effort report = %+v, want two deleted and one failure", report)
+	}
+	assertActiveMoodRecords(t, environment, owner.ID, 1)
+
+	report, err = environment.service.BulkMoodRecords(ctx, owner.ID, journal.BulkRequest{
+		IDs:    []uint{tagged.ID, first.ID},
+		Action: journal.BulkActionTag,
+		Mode:   journal.BulkModeBestEffort,
+		Tags:   []string{"Work"},
+	})
+	if err != nil || report.Succeeded != 1 || report.Results[1].Error != journal.BulkErrorNotFound {
+		t.Fatalf("tag BulkMoodRecords() = %+v, %v, want the deleted record reported as not found", report, err)
+	}
+	loaded, err := environment.service.GetMoodRecord(ctx, owner.ID, tagged.ID)
+	if err != nil || !slices.Equal(tagNamesOf(loaded.Tags), []string{"home", "work"}) {
+		t.Fatalf("tags after bulk tag = %v, %v, want [home work]", tagNamesOf(loaded.Tags), err)
+	}
+
+	if _, err := environment.service.BulkMoodRecords(ctx, owner.ID, journal.BulkRequest{IDs: []uint{first.ID}, Action: journal.BulkActionTag, Tags: []string{"two words"}}); !errors.Is(err, core.ErrInvalidItem) {
+		t.Fatalf("BulkMoodRecords() with an invalid tag error = %v, want ErrInvalidItem", err)
+	}
+	if _, err := environment.service.BulkMoodRecords(ctx, owner.ID, journal.BulkRequest{IDs: []uint{first.ID}, Action: "archive"}); !errors.Is(err, core.ErrInvalidItem) {
+		t.Fatalf("BulkMoodRecords() with an unknown action error = %v, want ErrInvalidItem", err)
+	}
+
+	report, err = environment.service.BulkMoodRecords(ctx, owner.ID, journal.BulkRequest{IDs: []uint{first.ID, second.ID}, Action: journal.BulkActionRestore})
+	if err != nil || report.Succeeded != 2 {
+		t.Fatalf("restore BulkMoodRecords() = %+v, %v", report, err)
+	}
+	assertActiveMoodRecords(t, environment, owner.ID, 3)
+}
+
+func TestServiceBulkPurgeKeepsFilesOnRollback(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	ctx := t.Context()
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	entry, err := environment.service.CreateDiaryEntry(ctx, owner.ID, diaryRequest("photo day", &occurredAt))
+	if err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+	photo, err := environment.service.CreateAttachment(ctx, owner.ID, entry.ID, "lake.png", int64(len(pngHeader)), strings.NewReader(string(pngHeader)))
+	if err != nil {
+		t.Fatalf("create attachment: %v", err)
+	}
+
+	request := journal.BulkRequest{IDs: []uint{entry.ID, 999}, Action: journal.BulkActionPurge}
+	if _, err := environment.service.BulkDiaryEntries(ctx, owner.ID, request); !errors.Is(err, journal.ErrBulkFailed) {
+		t.Fatalf("all-or-nothing BulkDiaryEntries() error = %v, want ErrBulkFailed", err)
+	}
+	_, content, err := environment.service.OpenAttachment(ctx, owner.ID, photo.ID)
+	if err != nil {
+		t.Fatalf("OpenAttachment() after rolled back purge error = %v", err)
+	}
+	stored, err := io.ReadAll(content)
+	content.Close()
+	if err != nil || string(stored) != string(pngHeader) {
+		t.Fatalf("attachment content after rolled back purge = %q, %v", stored, err)
+	}
+
+	request.Mode = journal.BulkModeBestEffort
+	if _, err := environment.service.BulkDiaryEntries(ctx, owner.ID, request); err != nil {
+		t.Fatalf("best-effort BulkDiaryEntries() error = %v", err)
+	}
+	if _, _, err := environment.service.OpenAttachment(ctx, owner.ID, photo.ID); !errors.Is(err, core.ErrItemNotFound) {
+		t.Fatalf("OpenAttachment() after purge error = %v, want ErrItemNotFound", err)
+	}
+}
+
+func TestBulkHTTPContract(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	record := saveMoodRecord(t, environment, owner.ID, "calm", time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		path string
+		body any
+		want int
+	}{
+		{path: "/api/journal/mood-records/bulk", body: journal.BulkRequest{IDs: []uint{record.ID}, Action: journal.BulkActionDelete}, want: http.StatusOK},
+		{path: "/api/journal/mood-records/bulk", body: journal.BulkRequest{IDs: []uint{record.ID, 999}, Action: journal.BulkActionRestore}, want: http.StatusUnprocessableEntity},
+		{path: "/api/journal/mood-records/bulk", body: journal.BulkRequest{IDs: []uint{record.ID}, Action: journal.BulkActionRestore, Mode: "sometimes"}, want: http.StatusBadRequest},
+		{path: "/api/journal/mood-records/bulk", body: journal.BulkRequest{Action: journal.BulkActionDelete}, want: http.StatusBadRequest},
+		{path: "/api/journal/diary-entries/bulk", body: journal.BulkRequest{IDs: []uint{999}, Action: journal.BulkActionTag}, want: http.StatusBadRequest},
+		{path: "/api/journal/diary-entries/bulk", body: journal.BulkRequest{IDs: []uint{999}, Action: journal.BulkActionPurge, Mode: journal.BulkModeBestEffort}, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		response := serveJournalJSON(t, e, http.MethodPost, tt.path, tt.body, ownerCookie)
+		if response.Code != tt.want {
+			t.Fatalf("POST %s %+v status = %d, want %d: %s", tt.path, tt.body, response.Code, tt.want, response.Body.String())
+		}
+	}
+}
+
+func assertActiveMoodRecords(t *testing.T, environment *journalTestEnvironment, userID uint, want int64) {
+	t.Helper()
+
+	count, err := environment.repository.CountMoodRecords(t.Context(), journal.NewMoodRecordFilter().WithUserID(userID))
+	if err != nil {
+		t.Fatalf("count active mood records: %v", err)
+	}
+	if count != want {
+		t.Fatalf("active mood record count = %d, want %d", count, want)
+	}
+}
@@ -0,0 +1,69 @@
+package journal
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	AttachmentDir     string
+	AttachmentMaxSize int64
+	AttachmentQuota   int64
+	TrashRetention    time.Duration
+}
+
+func GetConfig() (Config, error) {
+	config := Config{
+		AttachmentDir:     "attachments",
+		AttachmentMaxSize: 10 << 20,
+		AttachmentQuota:   100 << 20,
+		TrashRetention:    30 * 24 * time.Hour,
+	}
+
+	if dir := os.Getenv("ATTACHMENT_DIR"); dir != "" {
+		config.AttachmentDir = dir
+	}
+
+	maxSize, err := parseByteSize("ATTACHMENT_MAX_SIZE", config.AttachmentMaxSize)
+	if err != nil {
+		return Config{}, err
+	}
+	config.AttachmentMaxSize = maxSize
+
+	quota, err := parseByteSize("ATTACHMENT_QUOTA", config.AttachmentQuota)
+	if err != nil {
+		return Config{}, err
+	}
+	config.AttachmentQuota = quota
+
+	if retentionParam := os.Getenv("TRASH_RETENTION"); retentionParam != "" {
+		retention, err := time.ParseDuration(retentionParam)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse TRASH_RETENTION: %w", err)
+		}
+		if retention <= 0 {
+			return Config{}, fmt.Errorf("TRASH_RETENTION must be a positive duration")
+		}
+		config.TrashRetention = retention
+	}
+
+	return config, nil
+}
+
+func parseByteSize(name string, fallback int64) (int64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	if size <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number of bytes", name)
+	}
+	return size, nil
+}
@@ -0,0 +1,60 @@
+package journal_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+)
+
+func TestGetConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxSize   string
+		quota     string
+		wantMax   int64
+		wantQuota int64
+		wantErr   string
+	}{
+		{name: "default", wantMax: 10 << 20, wantQuota: 100 << 20},
+		{name: "environment override", maxSize: "2048", quota: "4096", wantMax: 2048, wantQuota: 4096},
+		{name: "invalid size", maxSize: "big", wantErr: "parse ATTACHMENT_MAX_SIZE"},
+		{name: "zero quota", quota: "0", wantErr: "ATTACHMENT_QUOTA must be a positive number of bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ATTACHMENT_MAX_SIZE", tt.maxSize)
+			t.Setenv("ATTACHMENT_QUOTA", tt.quota)
+
+			config, err := journal.GetConfig()
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetConfig() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetConfig() error = %v", err)
+			}
+			if config.AttachmentMaxSize != tt.wantMax || config.AttachmentQuota != tt.wantQuota {
+				t.Errorf("GetConfig() = %+v, want max %d and quota %d", config, tt.wantMax, tt.wantQuota)
+			}
+		})
+	}
+}
+
+func TestGetConfigTrashRetention(t *testing.T) {
+	t.Setenv("TRASH_RETENTION", "")
+	config, err := journal.GetConfig()
+	if err != nil || config.TrashRetention != 30*24*time.Hour {
+		t.Fatalf("GetConfig() trash retention = %v, %v, want 720h", config.TrashRetention, err)
+	}
+
+	t.Setenv("TRASH_RETENTION", "-1h")
+	if _, err := journal.GetConfig(); err == nil || !strings.Contains(err.Error(), "TRASH_RETENTION must be a positive duration") {
+		t.Fatalf("GetConfig() error = %v, want a positive duration error", err)
+	}
+}
@@ -0,0 +1,49 @@
+package journal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+)
+
+type MoodRecordCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        uint      `json:"i"`
+}
+
+func NewMoodRecordCursor(entry *MoodRecord) MoodRecordCursor {
+	return MoodRecordCursor{CreatedAt: entry.CreatedAt.UTC(), ID: entry.ID}
+}
+
+type DiaryEntryCursor struct {
+	OccurredAt time.Time `json:"o"`
+	CreatedAt  time.Time `json:"c"`
+	ID         uint      `json:"i"`
+}
+
+func NewDiaryEntryCursor(entry *DiaryEntry) DiaryEntryCursor {
+	return DiaryEntryCursor{OccurredAt: entry.OccurredAt.UTC(), CreatedAt: entry.CreatedAt.UTC(), ID: entry.ID}
+}
+
+func EncodeCursor[T MoodRecordCursor | DiaryEntryCursor](cursor T) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor[T MoodRecordCursor | DiaryEntryCursor](value string) (*T, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", core.ErrInvalidItem)
+	}
+	var cursor T
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", core.ErrInvalidItem)
+	}
+	return &cursor, nil
+}
@@ -201,21 +201,21 @@ func TestServiceListDiaryEntries(t *testing.T) {
 		t.Fatalf("delete diary entry: %v", err)
 	}
 
-	firstPage, err := environment.service.ListDiaryEntries(t.Context(), owner.ID, 1, 0, false)
+	firstPage, err := environment.service.ListDiaryEntries(t.Context(), owner.ID, journal.NewDiaryEntryFilter(), 1, 0)
 	if err != nil {
 		t.Fatalf("ListDiaryEntries() error = %v", err)
 	}
 	if firstPage.TotalCount != 2 || !slices.Equal(diaryEntryIDs(firstPage.Items), []uint{newest.ID}) {
 		t.Fatalf("first active page IDs = %v total %d, want [%d] total 2", diaryEntryIDs(firstPage.Items), firstPage.TotalCount, newest.ID)
 	}
-	secondPage, err := environment.service.ListDiaryEntries(t.Context(), owner.ID, 1, 1, false)
+	secondPage, err := environment.service.ListDiaryEntries(t.Context(), owner.ID, journal.NewDiaryEntryFilter(), 1, 1)
 	if err != nil {
 		t.Fatalf("ListDiaryEntries() second page error = %v", err)
 	}
 	if secondPage.TotalCount != 2 || !slices.Equal(diaryEntryIDs(secondPage.Items), []uint{oldest.ID}) {
 		t.Fatalf("second active page IDs = %v total %d, want [%d] total 2", diaryEntryIDs(secondPage.Items), secondPage.TotalCount, oldest.ID)
 	}
-	deletedPage, err := environment.service.ListDiaryEntries(t.Context(), owner.ID, 10, 0, true)
+	deletedPage, err := environment.service.ListDiaryEntries(t.Context(), owner.ID, journal.NewDiaryEntryFilter().WithDeletedMode(core.DeletedModeDeletedOnly), 10, 0)
 	if err != nil {
 		t.Fatalf("ListDiaryEntries() deleted error = %v", err)
 	}
@@ -265,6 +265,55 @@ func TestServiceMoodRecordBacklinks(t *testing.T) {
 	}
 }
 
+func TestServiceDiaryEntryLinks(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	other := createJournalUser(t, environment, "other")
+	occurredAt := time.Date(2026, time.January, 2, 8, 0, 0, 0, time.UTC)
+	first, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("first", &occurredAt))
+	if err != nil {
+		t.Fatalf("create first diary entry: %v", err)
+	}
+	second, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest(fmt.Sprintf("after [[diary:%d|first]] and /diary-entries/%d", first.ID, first.ID), &occurredAt))
+	if err != nil {
+		t.Fatalf("create linking diary entry: %v", err)
+	}
+	if gotIDs := diaryEntryIDs(second.Links); !slices.Equal(gotIDs, []uint{first.ID}) {
+		t.Fatalf("outgoing link IDs = %v, want [%d]", gotIDs, first.ID)
+	}
+
+	loaded, err := environment.service.GetDiaryEntry(t.Context(), owner.ID, first.ID)
+	if err != nil {
+		t.Fatalf("GetDiaryEntry() error = %v", err)
+	}
+	if gotIDs := diaryEntryIDs(loaded.Backlinks); !slices.Equal(gotIDs, []uint{second.ID}) || len(loaded.Links) != 0 {
+		t.Fatalf("backlink IDs = %v links %v, want [%d] and none", gotIDs, diaryEntryIDs(loaded.Links), second.ID)
+	}
+
+	foreign, err := environment.service.CreateDiaryEntry(t.Context(), other.ID, diaryRequest("private", &occurredAt))
+	if err != nil {
+		t.Fatalf("create foreign diary entry: %v", err)
+	}
+	for name, markdown := range map[string]string{
+		"self link":    fmt.Sprintf("[[diary:%d]]", first.ID),
+		"foreign link": fmt.Sprintf("[[diary:%d]]", foreign.ID),
+		"missing link": "/diary-entries/999",
+	} {
+		if _, err := environment.service.UpdateDiaryEntry(t.Context(), owner.ID, first.ID, diaryRequest(markdown, &occurredAt)); !errors.Is(err, core.ErrInvalidItem) {
+			t.Fatalf("UpdateDiaryEntry() with %s error = %v, want ErrInvalidItem", name, err)
+		}
+	}
+
+	if _, err := environment.service.DeleteDiaryEntry(t.Context(), owner.ID, second.ID); err != nil {
+		t.Fatalf("delete linking diary entry: %v", err)
+	}
+	loaded, err = environment.service.GetDiaryEntry(t.Context(), owner.ID, first.ID)
+	if err != nil || len(loaded.Backlinks) != 0 {
+		t.Fatalf("backlinks after delete = %v, %v, want none", diaryEntryIDs(loaded.Backlinks), err)
+	}
+}
+
 func diaryRequest(markdown string, occurredAt *time.Time) journal.DiaryEditEntryRequest {
 	return journal.DiaryEditEntryRequest{Markdown: markdown, OccurredAt: occurredAt}
 }
@@ -280,3 +329,95 @@ func diaryEntryIDs(entries []journal.DiaryEntry) []uint {
 	}
 	return ids
 }
+
+func TestServiceListDiaryEntriesFilters(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	mood := saveMoodRecord(t, environment, owner.ID, "calm", time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC))
+	februaryTime := time.Date(2026, time.February, 28, 23, 0, 0, 0, time.UTC)
+	marchTime := time.Date(2026, time.March, 15, 9, 0, 0, 0, time.UTC)
+	february, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("february", &februaryTime))
+	if err != nil {
+		t.Fatalf("create february diary entry: %v", err)
+	}
+	march, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest(fmt.Sprintf("march [[mood:%d]]", mood.ID), &marchTime))
+	if err != nil {
+		t.Fatalf("create march diary entry: %v", err)
+	}
+
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter *journal.DiaryEntryFilter
+		want   []uint
+	}{
+		{name: "occurred range", filter: journal.NewDiaryEntryFilter().WithOccurredRange(&from, &to), want: []uint{march.ID}},
+		{name: "occurred before", filter: journal.NewDiaryEntryFilter().WithOccurredRange(nil, &from), want: []uint{february.ID}},
+		{name: "with mood records", filter: journal.NewDiaryEntryFilter().WithHasMoodRecords(true), want: []uint{march.ID}},
+		{name: "without mood records", filter: journal.NewDiaryEntryFilter().WithHasMoodRecords(false), want: []uint{february.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := environment.service.ListDiaryEntries(t.Context(), owner.ID, tt.filter, 10, 0)
+			if err != nil {
+				t.Fatalf("ListDiaryEntries() error = %v", err)
+			}
+			if got := diaryEntryIDs(page.Items); !slices.Equal(got, tt.want) || page.TotalCount != int64(len(tt.want)) {
+				t.Fatalf("ListDiaryEntries() IDs = %v total = %d, want %v", got, page.TotalCount, tt.want)
+			}
+		})
+	}
+
+	if _, err := environment.service.DeleteMoodRecord(t.Context(), owner.ID, mood.ID); err != nil {
+		t.Fatalf("delete linked mood record: %v", err)
+	}
+	page, err := environment.service.ListDiaryEntries(t.Context(), owner.ID, journal.NewDiaryEntryFilter().WithHasMoodRecords(true), 10, 0)
+	if err != nil {
+		t.Fatalf("ListDiaryEntries() after mood delete error = %v", err)
+	}
+	if page.TotalCount != 0 {
+		t.Fatal("deleted mood record still counts as a diary link")
+	}
+}
+
+func TestServiceListDiaryEntriesByCursor(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	occurredAt := time.Date(2026, time.January, 1, 8, 0, 0, 0, time.UTC)
+	earlier := occurredAt.Add(-time.Hour)
+	var sameTime []uint
+	for index := range 3 {
+		entry, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest(fmt.Sprintf("entry %d", index), &occurredAt))
+		if err != nil {
+			t.Fatalf("create diary entry: %v", err)
+		}
+		sameTime = append(sameTime, entry.ID)
+	}
+	oldest, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("oldest", &earlier))
+	if err != nil {
+		t.Fatalf("create oldest diary entry: %v", err)
+	}
+
+	var got []uint
+	cursor := ""
+	for range 3 {
+		page, err := environment.service.ListDiaryEntriesByCursor(t.Context(), owner.ID, journal.NewDiaryEntryFilter(), cursor, 2, false)
+		if err != nil {
+			t.Fatalf("ListDiaryEntriesByCursor() error = %v", err)
+		}
+		got = append(got, diaryEntryIDs(page.Items)...)
+		cursor = page.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+
+	want := []uint{sameTime[2], sameTime[1], sameTime[0], oldest.ID}
+	if !slices.Equal(got, want) {
+		t.Fatalf("cursor walk IDs = %v, want %v", got, want)
+	}
+}
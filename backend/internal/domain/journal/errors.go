@@ -0,0 +1,13 @@
+package journal
+
+import "errors"
+
+var (
+	ErrImportBrokenReferences = errors.New("import has broken record references")
+	ErrTagExists              = errors.New("tag already exists")
+	ErrAttachmentTooLarge     = errors.New("attachment is too large")
+	ErrAttachmentQuota        = errors.New("attachment quota exceeded")
+	ErrAttachmentInUse        = errors.New("attachment is referenced by its diary entry")
+	ErrBulkFailed             = errors.New("bulk action failed for one or more records")
+	ErrPreconditionFailed     = errors.New("record was modified since it was read")
+)
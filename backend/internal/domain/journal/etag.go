@@ -0,0 +1,109 @@
+package journal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+func EntityTag(updatedAt time.Time, representation any) string {
+	version := strconv.FormatInt(updatedAt.UTC().UnixNano(), 36)
+	digest := sha256.New()
+	if err := json.NewEncoder(digest).Encode(representation); err != nil {
+		return `"` + version + `"`
+	}
+	return `"` + version + "." + hex.EncodeToString(digest.Sum(nil)[:8]) + `"`
+}
+
+func entityTagMatches(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func setEntityTag(c echo.Context, updatedAt time.Time, representation any) {
+	c.Response().Header().Set(headerETag, EntityTag(updatedAt, representation))
+}
+
+func notModified(c echo.Context, updatedAt time.Time, representation any) bool {
+	etag := EntityTag(updatedAt, representation)
+	c.Response().Header().Set(headerETag, etag)
+	ifNoneMatch := c.Request().Header.Get(headerIfNoneMatch)
+	return ifNoneMatch != "" && entityTagMatches(ifNoneMatch, etag, true)
+}
+
+func (s *Service) WithIfMatch(ifMatch string) *Service {
+	if ifMatch == "" || strings.TrimSpace(ifMatch) == "*" {
+		return s
+	}
+	service := *s
+	service.repo = &Repository{db: s.repo.db, versions: entityTagVersions(ifMatch)}
+	return &service
+}
+
+func entityTagVersions(header string) []time.Time {
+	versions := []time.Time{}
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if !strings.HasPrefix(candidate, `"`) || !strings.HasSuffix(candidate, `"`) {
+			continue
+		}
+		version, _, _ := strings.Cut(strings.Trim(candidate, `"`), ".")
+		nanos, err := strconv.ParseInt(version, 36, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, time.Unix(0, nanos).UTC())
+	}
+	return versions
+}
+
+func (h *Handler) moodRecordPreconditionFailed(c echo.Context, userID, id uint) error {
+	current, err := h.service.GetMoodRecord(c.Request().Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	response := NewMoodRecordResponse(current)
+	setEntityTag(c, current.UpdatedAt, response)
+	return c.JSON(http.StatusPreconditionFailed, response)
+}
+
+func (h *Handler) diaryEntryPreconditionFailed(c echo.Context, userID, id uint) error {
+	current, err := h.service.GetDiaryEntry(c.Request().Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	response := NewDiaryEntryResponse(current)
+	setEntityTag(c, current.UpdatedAt, response)
+	return c.JSON(http.StatusPreconditionFailed, response)
+}
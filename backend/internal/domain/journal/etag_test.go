@@ -0,0 +1,226 @@
+package journal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+func TestMoodRecordConditionalRequests(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	record := saveMoodRecord(t, environment, owner.ID, "calm", time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC))
+	path := fmt.Sprintf("/api/journal/mood-records/%d", record.ID)
+
+	get := serveConditionalJSON(t, e, http.MethodGet, path, nil, nil, ownerCookie)
+	etag := get.Header().Get("ETag")
+	if get.Code != http.StatusOK || etag == "" {
+		t.Fatalf("GET status = %d ETag = %q, want 200 and a tag", get.Code, etag)
+	}
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag} {
+		response := serveConditionalJSON(t, e, http.MethodGet, path, nil, map[string]string{"If-None-Match": ifNoneMatch}, ownerCookie)
+		if response.Code != http.StatusNotModified || response.Body.Len() != 0 {
+			t.Fatalf("GET If-None-Match %s status = %d body %q, want empty 304", ifNoneMatch, response.Code, response.Body.String())
+		}
+	}
+
+	update := journal.MoodEditRecordRequest{Feeling: "focused"}
+	updated := serveConditionalJSON(t, e, http.MethodPut, path, update, map[string]string{"If-Match": etag}, ownerCookie)
+	newETag := updated.Header().Get("ETag")
+	if updated.Code != http.StatusOK || newETag == "" || newETag == etag {
+		t.Fatalf("PUT If-Match status = %d ETag = %q, want 200 and a new tag", updated.Code, newETag)
+	}
+
+	stale := serveConditionalJSON(t, e, http.MethodPut, path, journal.MoodEditRecordRequest{Feeling: "lost"}, map[string]string{"If-Match": etag}, ownerCookie)
+	if stale.Code != http.StatusPreconditionFailed || stale.Header().Get("ETag") != newETag {
+		t.Fatalf("stale PUT status = %d ETag = %q, want 412 and %q", stale.Code, stale.Header().Get("ETag"), newETag)
+	}
+	var current journal.MoodRecordResponse
+	decodeJournalResponse(t, stale, &current)
+	if current.Feeling != "focused" {
+		t.Fatalf("stale PUT body feeling = %q, want the server copy", current.Feeling)
+	}
+
+	if response := serveConditionalJSON(t, e, http.MethodDelete, path, nil, map[string]string{"If-Match": etag}, ownerCookie); response.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale DELETE status = %d, want %d", response.Code, http.StatusPreconditionFailed)
+	}
+	if response := serveConditionalJSON(t, e, http.MethodDelete, path, nil, map[string]string{"If-Match": newETag}, ownerCookie); response.Code != http.StatusOK {
+		t.Fatalf("DELETE status = %d, want %d", response.Code, http.StatusOK)
+	}
+	if response := serveConditionalJSON(t, e, http.MethodPost, path+"/restore", nil, map[string]string{"If-Match": etag}, ownerCookie); response.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale restore status = %d, want %d", response.Code, http.StatusPreconditionFailed)
+	}
+	if response := serveConditionalJSON(t, e, http.MethodPost, path+"/restore", nil, map[string]string{"If-Match": "*"}, ownerCookie); response.Code != http.StatusOK {
+		t.Fatalf("wildcard restore status = %d, want %d", response.Code, http.StatusOK)
+	}
+	if response := serveConditionalJSON(t, e, http.MethodPut, "/api/journal/mood-records/999", update, map[string]string{"If-Match": etag}, ownerCookie); response.Code != http.StatusNotFound {
+		t.Fatalf("missing PUT status = %d, want %d", response.Code, http.StatusNotFound)
+	}
+}
+
+func TestDiaryEntryConditionalRequests(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	entry, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("first tab", &occurredAt))
+	if err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+	path := fmt.Sprintf("/api/journal/diary-entries/%d", entry.ID)
+	etag := serveConditionalJSON(t, e, http.MethodGet, path, nil, nil, ownerCookie).Header().Get("ETag")
+
+	if response := serveConditionalJSON(t, e, http.MethodGet, path, nil, map[string]string{"If-None-Match": etag}, ownerCookie); response.Code != http.StatusNotModified {
+		t.Fatalf("GET If-None-Match status = %d, want %d", response.Code, http.StatusNotModified)
+	}
+	rendered := serveConditionalJSON(t, e, http.MethodGet, path+"?render=html", nil, map[string]string{"If-None-Match": etag}, ownerCookie)
+	if rendered.Code != http.StatusOK || rendered.Header().Get("ETag") == etag {
+		t.Fatalf("rendered GET status = %d ETag = %q, want 200 and a tag other than %q", rendered.Code, rendered.Header().Get("ETag"), etag)
+	}
+	firstTab := serveConditionalJSON(t, e, http.MethodPut, path, diaryRequest("saved from tab one", &occurredAt), map[string]string{"If-Match": etag}, ownerCookie)
+	if firstTab.Code != http.StatusOK {
+		t.Fatalf("first tab PUT status = %d, want %d", firstTab.Code, http.StatusOK)
+	}
+
+	secondTab := serveConditionalJSON(t, e, http.MethodPut, path, diaryRequest("saved from tab two", &occurredAt), map[string]string{"If-Match": etag}, ownerCookie)
+	if secondTab.Code != http.StatusPreconditionFailed {
+		t.Fatalf("second tab PUT status = %d, want %d", secondTab.Code, http.StatusPreconditionFailed)
+	}
+	var current journal.DiaryEntryResponse
+	decodeJournalResponse(t, secondTab, &current)
+	if current.Markdown != "saved from tab one" || secondTab.Header().Get("ETag") != firstTab.Header().Get("ETag") {
+		t.Fatalf("second tab 412 body = %q ETag = %q, want the first tab copy", current.Markdown, secondTab.Header().Get("ETag"))
+	}
+
+	restore := serveConditionalJSON(t, e, http.MethodPost, path+"/revisions/1/restore", nil, map[string]string{"If-Match": etag}, ownerCookie)
+	if restore.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale revision restore status = %d, want %d", restore.Code, http.StatusPreconditionFailed)
+	}
+}
+
+func TestDiaryEntryETagChangesWithBacklinks(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	target, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("target", &occurredAt))
+	if err != nil {
+		t.Fatalf("create target entry: %v", err)
+	}
+	source, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("source", &occurredAt))
+	if err != nil {
+		t.Fatalf("create source entry: %v", err)
+	}
+	path := fmt.Sprintf("/api/journal/diary-entries/%d", target.ID)
+	etag := serveConditionalJSON(t, e, http.MethodGet, path, nil, nil, ownerCookie).Header().Get("ETag")
+
+	if _, err := environment.service.UpdateDiaryEntry(t.Context(), owner.ID, source.ID, diaryRequest(fmt.Sprintf("see [[diary:%d]]", target.ID), &occurredAt)); err != nil {
+		t.Fatalf("link source entry: %v", err)
+	}
+
+	response := serveConditionalJSON(t, e, http.MethodGet, path, nil, map[string]string{"If-None-Match": etag}, ownerCookie)
+	if response.Code != http.StatusOK || response.Header().Get("ETag") == etag {
+		t.Fatalf("GET after new backlink status = %d ETag = %q, want 200 and a new tag", response.Code, response.Header().Get("ETag"))
+	}
+	var current journal.DiaryEntryResponse
+	decodeJournalResponse(t, response, &current)
+	if len(current.Backlinks) != 1 || current.Backlinks[0].ID != source.ID {
+		t.Fatalf("backlinks = %+v, want the source entry", current.Backlinks)
+	}
+	if current.UpdatedAt != target.UpdatedAt {
+		t.Errorf("updated_at = %v, want %v unchanged by the backlink", current.UpdatedAt, target.UpdatedAt)
+	}
+	if response := serveConditionalJSON(t, e, http.MethodPut, path, diaryRequest("target edited", &occurredAt), map[string]string{"If-Match": etag}, ownerCookie); response.Code != http.StatusOK {
+		t.Fatalf("PUT with tag from before the backlink status = %d, want %d", response.Code, http.StatusOK)
+	}
+}
+
+func TestInterleavedUpdatesWithSameETag(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	record := saveMoodRecord(t, environment, owner.ID, "calm", time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC))
+	path := fmt.Sprintf("/api/journal/mood-records/%d", record.ID)
+	headers := map[string]string{"If-Match": journal.EntityTag(record.UpdatedAt, nil)}
+
+	var second *httptest.ResponseRecorder
+	var interleaved atomic.Bool
+	err := environment.database.Callback().Query().After("gorm:query").Register("test:interleave_update", func(db *gorm.DB) {
+		if db.Statement.Table == "mood_records" && interleaved.CompareAndSwap(false, true) {
+			second = serveConditionalJSON(t, e, http.MethodPut, path, journal.MoodEditRecordRequest{Feeling: "second"}, headers, ownerCookie)
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = environment.database.Callback().Query().Remove("test:interleave_update")
+	})
+
+	first := serveConditionalJSON(t, e, http.MethodPut, path, journal.MoodEditRecordRequest{Feeling: "first"}, headers, ownerCookie)
+	if second == nil || second.Code != http.StatusOK {
+		t.Fatalf("interleaved PUT = %v, want 200", second)
+	}
+	if first.Code != http.StatusPreconditionFailed || first.Header().Get("ETag") != second.Header().Get("ETag") {
+		t.Fatalf("first PUT status = %d ETag = %q, want 412 and %q", first.Code, first.Header().Get("ETag"), second.Header().Get("ETag"))
+	}
+	var current journal.MoodRecordResponse
+	decodeJournalResponse(t, first, &current)
+	if current.Feeling != "second" {
+		t.Fatalf("412 body feeling = %q, want the interleaved update", current.Feeling)
+	}
+
+	stored, err := environment.service.GetMoodRecord(t.Context(), owner.ID, record.ID)
+	if err != nil {
+		t.Fatalf("get mood record: %v", err)
+	}
+	if stored.Feeling != "second" {
+		t.Fatalf("stored feeling = %q, want second", stored.Feeling)
+	}
+}
+
+func serveConditionalJSON(t *testing.T, e *echo.Echo, method, path string, body any, headers map[string]string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encode request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	response := httptest.NewRecorder()
+	e.ServeHTTP(response, request)
+	return response
+}
@@ -0,0 +1,431 @@
+package journal
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	exportBatchSize          = 200
+	exportVersion            = 1
+	exportMoodRecordsCSVName = "mood-records.csv"
+	exportDiaryEntriesDir    = "diary-entries/"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatJSON     ExportFormat = "json"
+	ExportFormatMarkdown ExportFormat = "markdown"
+	ExportFormatCSV      ExportFormat = "csv"
+)
+
+func ParseExportFormat(value string) (ExportFormat, error) {
+	switch ExportFormat(value) {
+	case ExportFormatJSON, ExportFormatMarkdown, ExportFormatCSV:
+		return ExportFormat(value), nil
+	default:
+		return "", fmt.Errorf("unknown export format %q", value)
+	}
+}
+
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatMarkdown:
+		return "application/zip"
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+func (f ExportFormat) FileName(exportedAt time.Time) string {
+	extension := string(f)
+	if f == ExportFormatMarkdown {
+		extension = "zip"
+	}
+	return fmt.Sprintf("null3-journal-%s.%s", exportedAt.UTC().Format("20060102-150405"), extension)
+}
+
+type ExportMoodRecord struct {
+	ID        uint           `json:"id"`
+	Feeling   string         `json:"feeling"`
+	Emoji     string         `json:"emoji,omitempty"`
+	Note      string         `json:"note,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+	Tags      []string       `json:"tags,omitempty"`
+}
+
+type ExportDiaryEntry struct {
+	ID            uint           `json:"id"`
+	Title         string         `json:"title,omitempty"`
+	Markdown      string         `json:"markdown"`
+	OccurredAt    time.Time      `json:"occurred_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at"`
+	MoodRecordIDs []uint         `json:"mood_record_ids"`
+	Tags          []string       `json:"tags,omitempty"`
+}
+
+func NewExportMoodRecord(entry *MoodRecord) ExportMoodRecord {
+	return ExportMoodRecord{
+		ID:        entry.ID,
+		Feeling:   entry.Feeling,
+		Emoji:     entry.Emoji,
+		Note:      entry.Note,
+		CreatedAt: entry.CreatedAt,
+		UpdatedAt: entry.UpdatedAt,
+		DeletedAt: entry.DeletedAt,
+		Tags:      tagNames(entry.Tags),
+	}
+}
+
+func NewExportDiaryEntry(entry *DiaryEntry) ExportDiaryEntry {
+	ids := make([]uint, 0, len(entry.MoodRecords))
+	for _, record := range entry.MoodRecords {
+		ids = append(ids, record.ID)
+	}
+	return ExportDiaryEntry{
+		ID:            entry.ID,
+		Title:         entry.Title,
+		Markdown:      entry.Markdown,
+		OccurredAt:    entry.OccurredAt,
+		CreatedAt:     entry.CreatedAt,
+		UpdatedAt:     entry.UpdatedAt,
+		DeletedAt:     entry.DeletedAt,
+		MoodRecordIDs: ids,
+		Tags:          tagNames(entry.Tags),
+	}
+}
+
+type exportWriter interface {
+	WriteMoodRecord(record ExportMoodRecord) error
+	WriteDiaryEntry(entry ExportDiaryEntry) error
+	Close() error
+}
+
+func newExportWriter(format ExportFormat, w io.Writer, exportedAt time.Time) (exportWriter, error) {
+	switch format {
+	case ExportFormatJSON:
+		return newJSONExportWriter(w, exportedAt)
+	case ExportFormatMarkdown:
+		return newMarkdownExportWriter(w, exportedAt)
+	case ExportFormatCSV:
+		return newCSVExportWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+type jsonExportWriter struct {
+	w            io.Writer
+	encoder      *json.Encoder
+	inDiary      bool
+	sectionItems int
+}
+
+func newJSONExportWriter(w io.Writer, exportedAt time.Time) (*jsonExportWriter, error) {
+	exportedAtJSON, err := json.Marshal(exportedAt.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("encode export time: %w", err)
+	}
+	header := fmt.Sprintf(`{"version":%d,"exported_at":%s,"mood_records":[`, exportVersion, exportedAtJSON)
+	if _, err := io.WriteString(w, header); err != nil {
+		return nil, fmt.Errorf("write export header: %w", err)
+	}
+	return &jsonExportWriter{w: w, encoder: json.NewEncoder(w)}, nil
+}
+
+func (e *jsonExportWriter) WriteMoodRecord(record ExportMoodRecord) error {
+	if e.inDiary {
+		return fmt.Errorf("mood records must be written before diary entries")
+	}
+	return e.writeItem(record)
+}
+
+func (e *jsonExportWriter) WriteDiaryEntry(entry ExportDiaryEntry) error {
+	if err := e.startDiaryEntries(); err != nil {
+		return err
+	}
+	return e.writeItem(entry)
+}
+
+func (e *jsonExportWriter) Close() error {
+	if err := e.startDiaryEntries(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.w, "]}\n"); err != nil {
+		return fmt.Errorf("write export footer: %w", err)
+	}
+	return nil
+}
+
+func (e *jsonExportWriter) startDiaryEntries() error {
+	if e.inDiary {
+		return nil
+	}
+	e.inDiary = true
+	e.sectionItems = 0
+	if _, err := io.WriteString(e.w, `],"diary_entries":[`); err != nil {
+		return fmt.Errorf("write export section: %w", err)
+	}
+	return nil
+}
+
+func (e *jsonExportWriter) writeItem(item any) error {
+	if e.sectionItems > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return fmt.Errorf("write export separator: %w", err)
+		}
+	}
+	e.sectionItems++
+	if err := e.encoder.Encode(item); err != nil {
+		return fmt.Errorf("encode export item: %w", err)
+	}
+	return nil
+}
+
+type markdownExportWriter struct {
+	archive *zip.Writer
+	moods   *csv.Writer
+}
+
+func newMarkdownExportWriter(w io.Writer, exportedAt time.Time) (*markdownExportWriter, error) {
+	archive := zip.NewWriter(w)
+	file, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     exportMoodRecordsCSVName,
+		Method:   zip.Deflate,
+		Modified: exportedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create mood records file: %w", err)
+	}
+	moods := csv.NewWriter(file)
+	if err := moods.Write(exportMoodRecordCSVHeader); err != nil {
+		return nil, fmt.Errorf("write mood records header: %w", err)
+	}
+	return &markdownExportWriter{archive: archive, moods: moods}, nil
+}
+
+func (e *markdownExportWriter) WriteMoodRecord(record ExportMoodRecord) error {
+	if e.moods == nil {
+		return fmt.Errorf("mood records must be written before diary entries")
+	}
+	if err := e.moods.Write(exportMoodRecordCSVRow(record)); err != nil {
+		return fmt.Errorf("write mood record row: %w", err)
+	}
+	return nil
+}
+
+func (e *markdownExportWriter) WriteDiaryEntry(entry ExportDiaryEntry) error {
+	if err := e.finishMoodRecords(); err != nil {
+		return err
+	}
+	file, err := e.archive.CreateHeader(&zip.FileHeader{
+		Name:     ExportDiaryEntryFileName(entry),
+		Method:   zip.Deflate,
+		Modified: entry.UpdatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("create diary entry file: %w", err)
+	}
+	if _, err := io.WriteString(file, RenderDiaryEntryMarkdown(entry)); err != nil {
+		return fmt.Errorf("write diary entry file: %w", err)
+	}
+	return nil
+}
+
+func (e *markdownExportWriter) Close() error {
+	if err := e.finishMoodRecords(); err != nil {
+		return err
+	}
+	if err := e.archive.Close(); err != nil {
+		return fmt.Errorf("close export archive: %w", err)
+	}
+	return nil
+}
+
+func (e *markdownExportWriter) finishMoodRecords() error {
+	if e.moods == nil {
+		return nil
+	}
+	e.moods.Flush()
+	err := e.moods.Error()
+	e.moods = nil
+	if err != nil {
+		return fmt.Errorf("flush mood records: %w", err)
+	}
+	return nil
+}
+
+func ExportDiaryEntryFileName(entry ExportDiaryEntry) string {
+	return fmt.Sprintf("%s%s-%d.md", exportDiaryEntriesDir, entry.OccurredAt.UTC().Format(time.DateOnly), entry.ID)
+}
+
+func RenderDiaryEntryMarkdown(entry ExportDiaryEntry) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "id: %d\n", entry.ID)
+	fmt.Fprintf(&b, "title: %s\n", yamlString(entry.Title))
+	fmt.Fprintf(&b, "occurred_at: %s\n", formatExportTime(entry.OccurredAt))
+	fmt.Fprintf(&b, "created_at: %s\n", formatExportTime(entry.CreatedAt))
+	fmt.Fprintf(&b, "updated_at: %s\n", formatExportTime(entry.UpdatedAt))
+	if entry.DeletedAt.Valid {
+		fmt.Fprintf(&b, "deleted_at: %s\n", formatExportTime(entry.DeletedAt.Time))
+	}
+	fmt.Fprintf(&b, "mood_record_ids: [%s]\n", formatExportIDs(entry.MoodRecordIDs, ", "))
+	if len(entry.Tags) > 0 {
+		tags := make([]string, 0, len(entry.Tags))
+		for _, tag := range entry.Tags {
+			tags = append(tags, yamlString(tag))
+		}
+		fmt.Fprintf(&b, "tags: [%s]\n", strings.Join(tags, ", "))
+	}
+	b.WriteString("---\n\n")
+	b.WriteString(entry.Markdown)
+	if !strings.HasSuffix(entry.Markdown, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func yamlString(value string) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+const csvFormulaPrefixes = "=+-@"
+
+var (
+	exportMoodRecordCSVHeader = []string{"id", "feeling", "emoji", "note", "created_at", "updated_at", "deleted_at"}
+	exportJournalCSVHeader    = []string{"type", "id", "created_at", "updated_at", "deleted_at", "feeling", "emoji", "note", "title", "occurred_at", "markdown", "mood_record_ids"}
+)
+
+func exportMoodRecordCSVRow(record ExportMoodRecord) []string {
+	return []string{
+		strconv.FormatUint(uint64(record.ID), 10),
+		escapeCSVCell(record.Feeling),
+		escapeCSVCell(record.Emoji),
+		escapeCSVCell(record.Note),
+		formatExportTime(record.CreatedAt),
+		formatExportTime(record.UpdatedAt),
+		formatExportDeletedAt(record.DeletedAt),
+	}
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportJournalCSVHeader); err != nil {
+		return nil, fmt.Errorf("write export header: %w", err)
+	}
+	return &csvExportWriter{w: writer}, nil
+}
+
+func (e *csvExportWriter) WriteMoodRecord(record ExportMoodRecord) error {
+	return e.write([]string{
+		string(SearchRecordTypeMoodRecord),
+		strconv.FormatUint(uint64(record.ID), 10),
+		formatExportTime(record.CreatedAt),
+		formatExportTime(record.UpdatedAt),
+		formatExportDeletedAt(record.DeletedAt),
+		escapeCSVCell(record.Feeling),
+		escapeCSVCell(record.Emoji),
+		escapeCSVCell(record.Note),
+		"",
+		"",
+		"",
+		"",
+	})
+}
+
+func (e *csvExportWriter) WriteDiaryEntry(entry ExportDiaryEntry) error {
+	return e.write([]string{
+		string(SearchRecordTypeDiaryEntry),
+		strconv.FormatUint(uint64(entry.ID), 10),
+		formatExportTime(entry.CreatedAt),
+		formatExportTime(entry.UpdatedAt),
+		formatExportDeletedAt(entry.DeletedAt),
+		"",
+		"",
+		"",
+		escapeCSVCell(entry.Title),
+		formatExportTime(entry.OccurredAt),
+		escapeCSVCell(entry.Markdown),
+		formatExportIDs(entry.MoodRecordIDs, " "),
+	})
+}
+
+func (e *csvExportWriter) Close() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return fmt.Errorf("flush export: %w", err)
+	}
+	return nil
+}
+
+func (e *csvExportWriter) write(row []string) error {
+	if err := e.w.Write(row); err != nil {
+		return fmt.Errorf("write export row: %w", err)
+	}
+	return nil
+}
+
+func escapeCSVCell(value string) string {
+	if csvCellNeedsEscape(value) {
+		return "'" + value
+	}
+	return value
+}
+
+func unescapeCSVCell(value string) string {
+	if rest, ok := strings.CutPrefix(value, "'"); ok && csvCellNeedsEscape(rest) {
+		return rest
+	}
+	return value
+}
+
+func csvCellNeedsEscape(value string) bool {
+	switch {
+	case value == "":
+		return false
+	case strings.ContainsRune(csvFormulaPrefixes, rune(value[0])):
+		return true
+	default:
+		return value[0] == '\'' && len(value) > 1 && strings.ContainsRune(csvFormulaPrefixes+"'", rune(value[1]))
+	}
+}
+
+func formatExportTime(value time.Time) string {
+	return value.UTC().Format(time.RFC3339Nano)
+}
+
+func formatExportDeletedAt(value gorm.DeletedAt) string {
+	if !value.Valid {
+		return ""
+	}
+	return formatExportTime(value.Time)
+}
+
+func formatExportIDs(ids []uint, separator string) string {
+	formatted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		formatted = append(formatted, strconv.FormatUint(uint64(id), 10))
+	}
+	return strings.Join(formatted, separator)
+}
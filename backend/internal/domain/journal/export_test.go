@@ -0,0 +1,230 @@
+package journal_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+type exportedJournal struct {
+	Version      int                        `json:"version"`
+	MoodRecords  []journal.ExportMoodRecord `json:"mood_records"`
+	DiaryEntries []journal.ExportDiaryEntry `json:"diary_entries"`
+}
+
+func TestRenderDiaryEntryMarkdown(t *testing.T) {
+	entry := journal.ExportDiaryEntry{
+		ID:            7,
+		Title:         `Quotes "and": colons`,
+		Markdown:      "# Day\n\nFine.",
+		OccurredAt:    time.Date(2026, time.March, 10, 8, 30, 0, 0, time.UTC),
+		CreatedAt:     time.Date(2026, time.March, 10, 9, 0, 0, 0, time.UTC),
+		UpdatedAt:     time.Date(2026, time.March, 11, 9, 0, 0, 0, time.UTC),
+		MoodRecordIDs: []uint{3, 5},
+	}
+
+	want := "---\n" +
+		"id: 7\n" +
+		"title: \"Quotes \\\"and\\\": colons\"\n" +
+		"occurred_at: 2026-03-10T08:30:00Z\n" +
+		"created_at: 2026-03-10T09:00:00Z\n" +
+		"updated_at: 2026-03-11T09:00:00Z\n" +
+		"mood_record_ids: [3, 5]\n" +
+		"---\n\n" +
+		"# Day\n\nFine.\n"
+	if got := journal.RenderDiaryEntryMarkdown(entry); got != want {
+		t.Fatalf("RenderDiaryEntryMarkdown() = %q, want %q", got, want)
+	}
+	if got := journal.ExportDiaryEntryFileName(entry); got != "diary-entries/2026-03-10-7.md" {
+		t.Fatalf("ExportDiaryEntryFileName() = %q", got)
+	}
+}
+
+func TestServiceExportJournal(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	other := createJournalUser(t, environment, "other")
+	createdAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	kept := saveMoodRecord(t, environment, owner.ID, "calm", createdAt)
+	deleted := saveMoodRecord(t, environment, owner.ID, "gone", createdAt.Add(time.Hour))
+	saveMoodRecord(t, environment, other.ID, "foreign", createdAt)
+	entry, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest(fmt.Sprintf("Linked [[mood:%d|calm]]", kept.ID), timePointer(createdAt)))
+	if err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+	if _, err := environment.service.DeleteMoodRecord(t.Context(), owner.ID, deleted.ID); err != nil {
+		t.Fatalf("delete mood record: %v", err)
+	}
+
+	var active bytes.Buffer
+	if err := environment.service.ExportJournal(t.Context(), owner.ID, journal.ExportFormatJSON, false, createdAt, &active); err != nil {
+		t.Fatalf("ExportJournal() JSON error = %v", err)
+	}
+	var activeExport exportedJournal
+	if err := json.Unmarshal(active.Bytes(), &activeExport); err != nil {
+		t.Fatalf("decode JSON export: %v\n%s", err, active.String())
+	}
+	if activeExport.Version != 1 || len(activeExport.MoodRecords) != 1 || activeExport.MoodRecords[0].ID != kept.ID {
+		t.Fatalf("active JSON export moods = %+v, want only %d", activeExport.MoodRecords, kept.ID)
+	}
+	if len(activeExport.DiaryEntries) != 1 || !slices.Equal(activeExport.DiaryEntries[0].MoodRecordIDs, []uint{kept.ID}) {
+		t.Fatalf("active JSON export diary entries = %+v, want entry %d linked to %d", activeExport.DiaryEntries, entry.ID, kept.ID)
+	}
+
+	var withDeleted bytes.Buffer
+	if err := environment.service.ExportJournal(t.Context(), owner.ID, journal.ExportFormatJSON, true, createdAt, &withDeleted); err != nil {
+		t.Fatalf("ExportJournal() JSON with deleted error = %v", err)
+	}
+	var fullExport exportedJournal
+	if err := json.Unmarshal(withDeleted.Bytes(), &fullExport); err != nil {
+		t.Fatalf("decode JSON export with deleted: %v", err)
+	}
+	if len(fullExport.MoodRecords) != 2 || !fullExport.MoodRecords[1].DeletedAt.Valid {
+		t.Fatalf("JSON export with deleted moods = %+v, want both records with the deleted one marked", fullExport.MoodRecords)
+	}
+
+	var unified bytes.Buffer
+	if err := environment.service.ExportJournal(t.Context(), owner.ID, journal.ExportFormatCSV, false, createdAt, &unified); err != nil {
+		t.Fatalf("ExportJournal() CSV error = %v", err)
+	}
+	rows, err := csv.NewReader(&unified).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV export: %v", err)
+	}
+	if len(rows) != 3 || rows[0][0] != "type" || rows[1][0] != "mood_record" || rows[2][0] != "diary_entry" {
+		t.Fatalf("CSV export rows = %q, want header, one mood record and one diary entry", rows)
+	}
+	if rows[2][10] != entry.Markdown || rows[2][11] != fmt.Sprint(kept.ID) {
+		t.Fatalf("CSV diary entry row = %q", rows[2])
+	}
+}
+
+func TestServiceExportEscapesSpreadsheetFormulas(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	source := createJournalUser(t, environment, "source")
+	target := createJournalUser(t, environment, "target")
+	createdAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	record, err := environment.service.CreateMoodRecord(t.Context(), source.ID, journal.MoodEditRecordRequest{
+		Feeling: `=HYPERLINK("https://example.test")`,
+		Note:    "'-quoted",
+	})
+	if err != nil {
+		t.Fatalf("create mood record: %v", err)
+	}
+	if _, err := environment.service.CreateDiaryEntry(t.Context(), source.ID, journal.DiaryEditEntryRequest{
+		Title:      "@home",
+		Markdown:   "+1 for the walk",
+		OccurredAt: &createdAt,
+	}); err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+
+	var unified bytes.Buffer
+	if err := environment.service.ExportJournal(t.Context(), source.ID, journal.ExportFormatCSV, false, createdAt, &unified); err != nil {
+		t.Fatalf("ExportJournal() CSV error = %v", err)
+	}
+	rows, err := csv.NewReader(&unified).ReadAll()
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("CSV export rows = %q, %v", rows, err)
+	}
+	if rows[1][5] != `'=HYPERLINK("https://example.test")` || rows[1][7] != "''-quoted" || rows[2][8] != "'@home" || rows[2][10] != "'+1 for the walk" {
+		t.Fatalf("CSV export rows = %q, want formula cells prefixed with a quote", rows[1:])
+	}
+
+	var archive bytes.Buffer
+	if err := environment.service.ExportJournal(t.Context(), source.ID, journal.ExportFormatMarkdown, false, createdAt, &archive); err != nil {
+		t.Fatalf("ExportJournal() markdown error = %v", err)
+	}
+	document, err := journal.ParseImportDocument(archive.Bytes())
+	if err != nil {
+		t.Fatalf("ParseImportDocument() error = %v", err)
+	}
+	report, err := environment.service.ImportJournal(t.Context(), target.ID, document, false)
+	if err != nil {
+		t.Fatalf("ImportJournal() error = %v", err)
+	}
+	imported, err := environment.service.GetMoodRecord(t.Context(), target.ID, report.MoodRecordIDs[record.ID])
+	if err != nil || imported.Feeling != record.Feeling || imported.Note != record.Note {
+		t.Fatalf("imported mood record = %+v, %v, want the original text", imported, err)
+	}
+}
+
+func TestExportHTTPContract(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	createdAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	record := saveMoodRecord(t, environment, owner.ID, "calm", createdAt)
+	entry, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, journal.DiaryEditEntryRequest{
+		Title:      "Spring",
+		Markdown:   fmt.Sprintf("See /mood-records/%d", record.ID),
+		OccurredAt: &createdAt,
+		Tags:       []string{"outdoors"},
+	})
+	if err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+
+	for _, path := range []string{"/api/journal/export?format=pdf", "/api/journal/export?deleted=maybe"} {
+		if response := serveJournalJSON(t, e, http.MethodGet, path, nil, ownerCookie); response.Code != http.StatusBadRequest {
+			t.Fatalf("GET %s status = %d, want %d", path, response.Code, http.StatusBadRequest)
+		}
+	}
+
+	response := serveJournalJSON(t, e, http.MethodGet, "/api/journal/export?format=markdown", nil, ownerCookie)
+	if response.Code != http.StatusOK {
+		t.Fatalf("markdown export status = %d, want %d", response.Code, http.StatusOK)
+	}
+	if got := response.Header().Get("Content-Type"); got != "application/zip" {
+		t.Fatalf("markdown export content type = %q", got)
+	}
+	if got := response.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment; ") || !strings.HasSuffix(got, `.zip"`) {
+		t.Fatalf("markdown export content disposition = %q", got)
+	}
+
+	body := response.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("open markdown export archive: %v", err)
+	}
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", file.Name, err)
+		}
+		files[file.Name] = string(content)
+	}
+
+	moods, ok := files["mood-records.csv"]
+	if !ok || !strings.Contains(moods, fmt.Sprintf("%d,calm,", record.ID)) {
+		t.Fatalf("archive mood records = %q", moods)
+	}
+	markdown, ok := files[journal.ExportDiaryEntryFileName(journal.NewExportDiaryEntry(entry))]
+	if !ok {
+		t.Fatalf("archive files = %v, want diary entry %d", len(files), entry.ID)
+	}
+	if !strings.Contains(markdown, "title: \"Spring\"\n") || !strings.Contains(markdown, fmt.Sprintf("mood_record_ids: [%d]\n", record.ID)) || !strings.Contains(markdown, "tags: [\"outdoors\"]\n") {
+		t.Fatalf("archive diary entry = %q", markdown)
+	}
+}
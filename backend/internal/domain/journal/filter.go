@@ -1,14 +1,24 @@
 package journal
 
 import (
+	"strings"
+	"time"
+
 	"github.com/azaviyalov/null3/backend/internal/core"
 	"gorm.io/gorm"
 )
 
 type MoodRecordFilter struct {
-	ID          *uint
-	UserID      *uint
-	DeletedMode core.DeletedFilterMode
+	ID              *uint
+	UserID          *uint
+	DeletedMode     core.DeletedFilterMode
+	From            *time.Time
+	To              *time.Time
+	Feeling         *string
+	Emoji           *string
+	HasDiaryEntries *bool
+	Tag             *string
+	DeletedBefore   *time.Time
 }
 
 func NewMoodRecordFilter() *MoodRecordFilter {
@@ -30,6 +40,37 @@ func (f *MoodRecordFilter) WithDeletedMode(mode core.DeletedFilterMode) *MoodRec
 	return f
 }
 
+func (f *MoodRecordFilter) WithCreatedRange(from, to *time.Time) *MoodRecordFilter {
+	f.From = from
+	f.To = to
+	return f
+}
+
+func (f *MoodRecordFilter) WithFeeling(feeling string) *MoodRecordFilter {
+	f.Feeling = &feeling
+	return f
+}
+
+func (f *MoodRecordFilter) WithEmoji(emoji string) *MoodRecordFilter {
+	f.Emoji = &emoji
+	return f
+}
+
+func (f *MoodRecordFilter) WithHasDiaryEntries(hasDiaryEntries bool) *MoodRecordFilter {
+	f.HasDiaryEntries = &hasDiaryEntries
+	return f
+}
+
+func (f *MoodRecordFilter) WithTag(tag string) *MoodRecordFilter {
+	f.Tag = &tag
+	return f
+}
+
+func (f *MoodRecordFilter) WithDeletedBefore(before time.Time) *MoodRecordFilter {
+	f.DeletedBefore = &before
+	return f
+}
+
 func (f MoodRecordFilter) Apply(db *gorm.DB) *gorm.DB {
 	if f.ID != nil {
 		db = db.Where("id = ?", *f.ID)
@@ -37,6 +78,32 @@ func (f MoodRecordFilter) Apply(db *gorm.DB) *gorm.DB {
 	if f.UserID != nil {
 		db = db.Where("user_id = ?", *f.UserID)
 	}
+	if f.From != nil {
+		db = db.Where("created_at >= ?", f.From.UTC())
+	}
+	if f.To != nil {
+		db = db.Where("created_at < ?", f.To.UTC())
+	}
+	if f.Feeling != nil {
+		db = db.Where("LOWER(feeling) = ?", strings.ToLower(strings.TrimSpace(*f.Feeling)))
+	}
+	if f.Emoji != nil {
+		db = db.Where("emoji = ?", *f.Emoji)
+	}
+	if f.HasDiaryEntries != nil {
+		linked := linkedDiaryEntriesQuery(db)
+		if *f.HasDiaryEntries {
+			db = db.Where("EXISTS (?)", linked)
+		} else {
+			db = db.Where("NOT EXISTS (?)", linked)
+		}
+	}
+	if f.Tag != nil {
+		db = db.Where("EXISTS (?)", taggedRecordsQuery(db, moodRecordTagLink, *f.Tag))
+	}
+	if f.DeletedBefore != nil {
+		db = db.Where("deleted_at < ?", f.DeletedBefore.UTC())
+	}
 	switch f.DeletedMode {
 	case core.DeletedModeNonDeleted:
 	case core.DeletedModeDeletedOnly:
@@ -47,10 +114,24 @@ func (f MoodRecordFilter) Apply(db *gorm.DB) *gorm.DB {
 	return db
 }
 
+func linkedDiaryEntriesQuery(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).
+		Table("mood_record_diary_entries").
+		Select("1").
+		Joins("JOIN diary_entries ON diary_entries.id = mood_record_diary_entries.diary_entry_id").
+		Where("mood_record_diary_entries.mood_record_id = mood_records.id").
+		Where("diary_entries.deleted_at IS NULL")
+}
+
 type DiaryEntryFilter struct {
-	ID          *uint
-	UserID      *uint
-	DeletedMode core.DeletedFilterMode
+	ID             *uint
+	UserID         *uint
+	DeletedMode    core.DeletedFilterMode
+	From           *time.Time
+	To             *time.Time
+	HasMoodRecords *bool
+	Tag            *string
+	DeletedBefore  *time.Time
 }
 
 func NewDiaryEntryFilter() *DiaryEntryFilter {
@@ -72,6 +153,27 @@ func (f *DiaryEntryFilter) WithDeletedMode(mode core.DeletedFilterMode) *DiaryEn
 	return f
 }
 
+func (f *DiaryEntryFilter) WithOccurredRange(from, to *time.Time) *DiaryEntryFilter {
+	f.From = from
+	f.To = to
+	return f
+}
+
+func (f *DiaryEntryFilter) WithHasMoodRecords(hasMoodRecords bool) *DiaryEntryFilter {
+	f.HasMoodRecords = &hasMoodRecords
+	return f
+}
+
+func (f *DiaryEntryFilter) WithTag(tag string) *DiaryEntryFilter {
+	f.Tag = &tag
+	return f
+}
+
+func (f *DiaryEntryFilter) WithDeletedBefore(before time.Time) *DiaryEntryFilter {
+	f.DeletedBefore = &before
+	return f
+}
+
 func (f DiaryEntryFilter) Apply(db *gorm.DB) *gorm.DB {
 	if f.ID != nil {
 		db = db.Where("id = ?", *f.ID)
@@ -79,6 +181,26 @@ func (f DiaryEntryFilter) Apply(db *gorm.DB) *gorm.DB {
 	if f.UserID != nil {
 		db = db.Where("user_id = ?", *f.UserID)
 	}
+	if f.From != nil {
+		db = db.Where("occurred_at >= ?", f.From.UTC())
+	}
+	if f.To != nil {
+		db = db.Where("occurred_at < ?", f.To.UTC())
+	}
+	if f.HasMoodRecords != nil {
+		linked := linkedMoodRecordsQuery(db)
+		if *f.HasMoodRecords {
+			db = db.Where("EXISTS (?)", linked)
+		} else {
+			db = db.Where("NOT EXISTS (?)", linked)
+		}
+	}
+	if f.Tag != nil {
+		db = db.Where("EXISTS (?)", taggedRecordsQuery(db, diaryEntryTagLink, *f.Tag))
+	}
+	if f.DeletedBefore != nil {
+		db = db.Where("deleted_at < ?", f.DeletedBefore.UTC())
+	}
 	switch f.DeletedMode {
 	case core.DeletedModeNonDeleted:
 	case core.DeletedModeDeletedOnly:
@@ -88,3 +210,177 @@ func (f DiaryEntryFilter) Apply(db *gorm.DB) *gorm.DB {
 	}
 	return db
 }
+
+func linkedMoodRecordsQuery(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).
+		Table("mood_record_diary_entries").
+		Select("1").
+		Joins("JOIN mood_records ON mood_records.id = mood_record_diary_entries.mood_record_id").
+		Where("mood_record_diary_entries.diary_entry_id = diary_entries.id").
+		Where("mood_records.deleted_at IS NULL")
+}
+
+type tagLink struct {
+	joinTable    string
+	recordColumn string
+	recordTable  string
+}
+
+var (
+	moodRecordTagLink = tagLink{joinTable: "mood_record_tags", recordColumn: "mood_record_id", recordTable: "mood_records"}
+	diaryEntryTagLink = tagLink{joinTable: "diary_entry_tags", recordColumn: "diary_entry_id", recordTable: "diary_entries"}
+)
+
+func taggedRecordsQuery(db *gorm.DB, link tagLink, tag string) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).
+		Table(link.joinTable).
+		Select("1").
+		Joins("JOIN tags ON tags.id = "+link.joinTable+".tag_id").
+		Where(link.joinTable+"."+link.recordColumn+" = "+link.recordTable+".id").
+		Where("tags.name = ?", tag)
+}
+
+type TagFilter struct {
+	ID     *uint
+	UserID *uint
+	Name   *string
+}
+
+func NewTagFilter() *TagFilter {
+	return &TagFilter{}
+}
+
+func (f *TagFilter) WithID(id uint) *TagFilter {
+	f.ID = &id
+	return f
+}
+
+func (f *TagFilter) WithUserID(userID uint) *TagFilter {
+	f.UserID = &userID
+	return f
+}
+
+func (f *TagFilter) WithName(name string) *TagFilter {
+	f.Name = &name
+	return f
+}
+
+func (f TagFilter) Apply(db *gorm.DB) *gorm.DB {
+	if f.ID != nil {
+		db = db.Where("tags.id = ?", *f.ID)
+	}
+	if f.UserID != nil {
+		db = db.Where("tags.user_id = ?", *f.UserID)
+	}
+	if f.Name != nil {
+		db = db.Where("tags.name = ?", *f.Name)
+	}
+	return db
+}
+
+type AttachmentFilter struct {
+	ID           *uint
+	UserID       *uint
+	DiaryEntryID *uint
+}
+
+func NewAttachmentFilter() *AttachmentFilter {
+	return &AttachmentFilter{}
+}
+
+func (f *AttachmentFilter) WithID(id uint) *AttachmentFilter {
+	f.ID = &id
+	return f
+}
+
+func (f *AttachmentFilter) WithUserID(userID uint) *AttachmentFilter {
+	f.UserID = &userID
+	return f
+}
+
+func (f *AttachmentFilter) WithDiaryEntryID(diaryEntryID uint) *AttachmentFilter {
+	f.DiaryEntryID = &diaryEntryID
+	return f
+}
+
+func (f AttachmentFilter) Apply(db *gorm.DB) *gorm.DB {
+	if f.ID != nil {
+		db = db.Where("attachments.id = ?", *f.ID)
+	}
+	if f.UserID != nil {
+		db = db.Where("attachments.user_id = ?", *f.UserID)
+	}
+	if f.DiaryEntryID != nil {
+		db = db.Where("attachments.diary_entry_id = ?", *f.DiaryEntryID)
+	}
+	return db
+}
+
+type SearchFilter struct {
+	Query       string
+	UserID      *uint
+	RecordType  *SearchRecordType
+	DeletedMode core.DeletedFilterMode
+}
+
+func NewSearchFilter(query string) *SearchFilter {
+	return &SearchFilter{Query: query, DeletedMode: core.DeletedModeNonDeleted}
+}
+
+func (f *SearchFilter) WithUserID(userID uint) *SearchFilter {
+	f.UserID = &userID
+	return f
+}
+
+func (f *SearchFilter) WithRecordType(recordType SearchRecordType) *SearchFilter {
+	f.RecordType = &recordType
+	return f
+}
+
+func (f *SearchFilter) WithDeletedMode(mode core.DeletedFilterMode) *SearchFilter {
+	f.DeletedMode = mode
+	return f
+}
+
+func (f SearchFilter) MoodRecordFilter() *MoodRecordFilter {
+	filter := NewMoodRecordFilter().WithDeletedMode(f.DeletedMode)
+	if f.UserID != nil {
+		filter = filter.WithUserID(*f.UserID)
+	}
+	return filter
+}
+
+func (f SearchFilter) DiaryEntryFilter() *DiaryEntryFilter {
+	filter := NewDiaryEntryFilter().WithDeletedMode(f.DeletedMode)
+	if f.UserID != nil {
+		filter = filter.WithUserID(*f.UserID)
+	}
+	return filter
+}
+
+func (f SearchFilter) Apply(db *gorm.DB) *gorm.DB {
+	if isPostgres(db) {
+		db = db.Where("document @@ to_tsquery('simple', ?)", postgresSearchQuery(f.Query))
+	} else {
+		db = db.Where(searchIndexTable+" MATCH ?", f.Query)
+	}
+	if f.UserID != nil {
+		db = db.Where("user_id = ?", *f.UserID)
+	}
+
+	scope := db.Session(&gorm.Session{NewDB: true})
+	moodRecords := f.MoodRecordFilter().Apply(scope.Model(&MoodRecord{}).Select("id"))
+	diaryEntries := f.DiaryEntryFilter().Apply(scope.Model(&DiaryEntry{}).Select("id"))
+	moodRecordScope := scope.Where("record_type = ? AND record_id IN (?)", SearchRecordTypeMoodRecord, moodRecords)
+	diaryEntryScope := scope.Where("record_type = ? AND record_id IN (?)", SearchRecordTypeDiaryEntry, diaryEntries)
+
+	switch {
+	case f.RecordType == nil:
+		db = db.Where(moodRecordScope.Or(diaryEntryScope))
+	case *f.RecordType == SearchRecordTypeMoodRecord:
+		db = db.Where(moodRecordScope)
+	case *f.RecordType == SearchRecordTypeDiaryEntry:
+		db = db.Where(diaryEntryScope)
+	}
+	return db
+}
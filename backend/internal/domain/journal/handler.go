@@ -1,9 +1,15 @@
 package journal
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/azaviyalov/null3/backend/internal/core"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
@@ -23,6 +29,8 @@ func RegisterRoutes(e *echo.Echo, h *Handler, jwt echo.MiddlewareFunc) {
 	e.GET("/api/journal/mood-records/:id", h.GetMoodRecord, jwt)
 	e.POST("/api/journal/mood-records", h.CreateMoodRecord, jwt)
 	e.PUT("/api/journal/mood-records/:id", h.UpdateMoodRecord, jwt)
+	e.POST("/api/journal/mood-records/bulk", h.BulkMoodRecords, jwt)
+	e.DELETE("/api/journal/mood-records/trash", h.EmptyMoodRecordTrash, jwt)
 	e.DELETE("/api/journal/mood-records/:id", h.DeleteMoodRecord, jwt)
 	e.POST("/api/journal/mood-records/:id/restore", h.RestoreMoodRecord, jwt)
 
@@ -30,8 +38,33 @@ func RegisterRoutes(e *echo.Echo, h *Handler, jwt echo.MiddlewareFunc) {
 	e.GET("/api/journal/diary-entries/:id", h.GetDiaryEntry, jwt)
 	e.POST("/api/journal/diary-entries", h.CreateDiaryEntry, jwt)
 	e.PUT("/api/journal/diary-entries/:id", h.UpdateDiaryEntry, jwt)
+	e.POST("/api/journal/diary-entries/bulk", h.BulkDiaryEntries, jwt)
+	e.DELETE("/api/journal/diary-entries/trash", h.EmptyDiaryEntryTrash, jwt)
 	e.DELETE("/api/journal/diary-entries/:id", h.DeleteDiaryEntry, jwt)
 	e.POST("/api/journal/diary-entries/:id/restore", h.RestoreDiaryEntry, jwt)
+	e.GET("/api/journal/diary-entries/:id/revisions", h.ListDiaryEntryRevisions, jwt)
+	e.GET("/api/journal/diary-entries/:id/revisions/diff", h.DiffDiaryEntryRevisions, jwt)
+	e.GET("/api/journal/diary-entries/:id/revisions/:revision", h.GetDiaryEntryRevision, jwt)
+	e.POST("/api/journal/diary-entries/:id/revisions/:revision/restore", h.RestoreDiaryEntryRevision, jwt)
+	e.GET("/api/journal/diary-entries/:id/attachments", h.ListAttachments, jwt)
+	e.POST("/api/journal/diary-entries/:id/attachments", h.CreateAttachment, jwt)
+
+	e.GET("/api/journal/attachments/usage", h.GetAttachmentUsage, jwt)
+	e.GET("/api/journal/attachments/:id", h.GetAttachment, jwt)
+	e.GET("/api/journal/attachments/:id/content", h.DownloadAttachment, jwt)
+	e.DELETE("/api/journal/attachments/:id", h.DeleteAttachment, jwt)
+
+	e.GET("/api/journal/tags", h.ListTags, jwt)
+	e.GET("/api/journal/tags/:id", h.GetTag, jwt)
+	e.POST("/api/journal/tags", h.CreateTag, jwt)
+	e.PUT("/api/journal/tags/:id", h.RenameTag, jwt)
+	e.DELETE("/api/journal/tags/:id", h.DeleteTag, jwt)
+	e.POST("/api/journal/tags/:id/merge", h.MergeTag, jwt)
+
+	e.GET("/api/journal/search", h.Search, jwt)
+	e.GET("/api/journal/stats", h.GetMoodStats, jwt)
+	e.GET("/api/journal/export", h.ExportJournal, jwt)
+	e.POST("/api/journal/import", h.ImportJournal, jwt)
 }
 
 func (h *Handler) GetMoodRecord(c echo.Context) error {
@@ -48,19 +81,53 @@ func (h *Handler) GetMoodRecord(c echo.Context) error {
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
 
-	return c.JSON(http.StatusOK, NewMoodRecordResponse(entry))
+	response := NewMoodRecordResponse(entry)
+	if notModified(c, entry.UpdatedAt, response) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(http.StatusOK, response)
 }
 
 func (h *Handler) ListMoodRecords(c echo.Context) error {
-	limit, offset, deleted := parsePagination(c)
+	limit, offset, deleted, err := parsePagination(c)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	filter, err := parseMoodRecordFilter(c, deleted)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	cursor, cursorMode, err := parseCursorQuery(c)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if cursorMode {
+		return h.listMoodRecordsByCursor(c, filter, cursor, limit)
+	}
 	userID := session.GetUserID(c)
-	entries, err := h.service.ListMoodRecords(c.Request().Context(), userID, limit, offset, deleted)
+	entries, err := h.service.ListMoodRecords(c.Request().Context(), userID, filter, limit, offset)
 	if err != nil {
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
 	return c.JSON(http.StatusOK, entries)
 }
 
+func (h *Handler) listMoodRecordsByCursor(c echo.Context, filter *MoodRecordFilter, cursor string, limit int) error {
+	includeTotal, err := parseBoolQueryParam(c, "total")
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	userID := session.GetUserID(c)
+	page, err := h.service.ListMoodRecordsByCursor(c.Request().Context(), userID, filter, cursor, limit, includeTotal != nil && *includeTotal)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidItem) {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
 func (h *Handler) CreateMoodRecord(c echo.Context) error {
 	userID := session.GetUserID(c)
 	var req MoodEditRecordRequest
@@ -77,7 +144,9 @@ func (h *Handler) CreateMoodRecord(c echo.Context) error {
 		}
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
-	return c.JSON(http.StatusCreated, NewMoodRecordResponse(entry))
+	response := NewMoodRecordResponse(entry)
+	setEntityTag(c, entry.UpdatedAt, response)
+	return c.JSON(http.StatusCreated, response)
 }
 
 func (h *Handler) UpdateMoodRecord(c echo.Context) error {
@@ -94,8 +163,12 @@ func (h *Handler) UpdateMoodRecord(c echo.Context) error {
 		return echo.ErrBadRequest.WithInternal(err)
 	}
 
-	entry, err := h.service.UpdateMoodRecord(c.Request().Context(), userID, id, req)
+	service := h.service.WithIfMatch(c.Request().Header.Get(headerIfMatch))
+	entry, err := service.UpdateMoodRecord(c.Request().Context(), userID, id, req)
 	if err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return h.moodRecordPreconditionFailed(c, userID, id)
+		}
 		if errors.Is(err, core.ErrInvalidItem) {
 			return echo.ErrBadRequest.WithInternal(err)
 		}
@@ -105,7 +178,9 @@ func (h *Handler) UpdateMoodRecord(c echo.Context) error {
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
 
-	return c.JSON(http.StatusOK, NewMoodRecordResponse(entry))
+	response := NewMoodRecordResponse(entry)
+	setEntityTag(c, entry.UpdatedAt, response)
+	return c.JSON(http.StatusOK, response)
 }
 
 func (h *Handler) DeleteMoodRecord(c echo.Context) error {
@@ -113,15 +188,46 @@ func (h *Handler) DeleteMoodRecord(c echo.Context) error {
 	if err != nil {
 		return err
 	}
+	permanent, err := parseBoolQueryParam(c, "permanent")
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	service := h.service.WithIfMatch(c.Request().Header.Get(headerIfMatch))
 
-	entry, err := h.service.DeleteMoodRecord(c.Request().Context(), userID, id)
+	purge := permanent != nil && *permanent
+	var entry *MoodRecord
+	if purge {
+		entry, err = service.PurgeMoodRecord(c.Request().Context(), userID, id)
+	} else {
+		entry, err = service.DeleteMoodRecord(c.Request().Context(), userID, id)
+	}
 	if err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return h.moodRecordPreconditionFailed(c, userID, id)
+		}
 		if errors.Is(err, core.ErrItemNotFound) {
 			return echo.ErrNotFound.WithInternal(err)
 		}
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
-	return c.JSON(http.StatusOK, NewMoodRecordResponse(entry))
+	response := NewMoodRecordResponse(entry)
+	if !purge {
+		setEntityTag(c, entry.UpdatedAt, response)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) EmptyMoodRecordTrash(c echo.Context) error {
+	userID := session.GetUserID(c)
+	purged, err := h.service.EmptyMoodRecordTrash(c.Request().Context(), userID)
+	if err != nil {
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, TrashPurgeResponse{Purged: purged})
+}
+
+func (h *Handler) BulkMoodRecords(c echo.Context) error {
+	return h.runBulk(c, h.service.BulkMoodRecords)
 }
 
 func (h *Handler) RestoreMoodRecord(c echo.Context) error {
@@ -129,15 +235,21 @@ func (h *Handler) RestoreMoodRecord(c echo.Context) error {
 	if err != nil {
 		return err
 	}
+	service := h.service.WithIfMatch(c.Request().Header.Get(headerIfMatch))
 
-	entry, err := h.service.RestoreMoodRecord(c.Request().Context(), userID, id)
+	entry, err := service.RestoreMoodRecord(c.Request().Context(), userID, id)
 	if err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return h.moodRecordPreconditionFailed(c, userID, id)
+		}
 		if errors.Is(err, core.ErrItemNotFound) {
 			return echo.ErrNotFound.WithInternal(err)
 		}
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
-	return c.JSON(http.StatusOK, NewMoodRecordResponse(entry))
+	response := NewMoodRecordResponse(entry)
+	setEntityTag(c, entry.UpdatedAt, response)
+	return c.JSON(http.StatusOK, response)
 }
 
 func (h *Handler) GetDiaryEntry(c echo.Context) error {
@@ -146,6 +258,11 @@ func (h *Handler) GetDiaryEntry(c echo.Context) error {
 		return err
 	}
 
+	render := c.QueryParam("render")
+	if render != "" && render != RenderFormatHTML {
+		return echo.ErrBadRequest.WithInternal(fmt.Errorf("unknown render format %q", render))
+	}
+
 	entry, err := h.service.GetDiaryEntry(c.Request().Context(), userID, id)
 	if err != nil {
 		if errors.Is(err, core.ErrItemNotFound) {
@@ -154,19 +271,59 @@ func (h *Handler) GetDiaryEntry(c echo.Context) error {
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
 
-	return c.JSON(http.StatusOK, NewDiaryEntryResponse(entry))
+	response := NewDiaryEntryResponse(entry)
+	if render == RenderFormatHTML {
+		response.HTML, err = h.service.RenderDiaryEntryHTML(entry)
+		if err != nil {
+			return echo.ErrInternalServerError.WithInternal(err)
+		}
+	}
+	if notModified(c, entry.UpdatedAt, response) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(http.StatusOK, response)
 }
 
 func (h *Handler) ListDiaryEntries(c echo.Context) error {
-	limit, offset, deleted := parsePagination(c)
+	limit, offset, deleted, err := parsePagination(c)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	filter, err := parseDiaryEntryFilter(c, deleted)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	cursor, cursorMode, err := parseCursorQuery(c)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if cursorMode {
+		return h.listDiaryEntriesByCursor(c, filter, cursor, limit)
+	}
 	userID := session.GetUserID(c)
-	entries, err := h.service.ListDiaryEntries(c.Request().Context(), userID, limit, offset, deleted)
+	entries, err := h.service.ListDiaryEntries(c.Request().Context(), userID, filter, limit, offset)
 	if err != nil {
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
 	return c.JSON(http.StatusOK, entries)
 }
 
+func (h *Handler) listDiaryEntriesByCursor(c echo.Context, filter *DiaryEntryFilter, cursor string, limit int) error {
+	includeTotal, err := parseBoolQueryParam(c, "total")
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	userID := session.GetUserID(c)
+	page, err := h.service.ListDiaryEntriesByCursor(c.Request().Context(), userID, filter, cursor, limit, includeTotal != nil && *includeTotal)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidItem) {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
 func (h *Handler) CreateDiaryEntry(c echo.Context) error {
 	userID := session.GetUserID(c)
 	var req DiaryEditEntryRequest
@@ -183,7 +340,9 @@ func (h *Handler) CreateDiaryEntry(c echo.Context) error {
 		}
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
-	return c.JSON(http.StatusCreated, NewDiaryEntryResponse(entry))
+	response := NewDiaryEntryResponse(entry)
+	setEntityTag(c, entry.UpdatedAt, response)
+	return c.JSON(http.StatusCreated, response)
 }
 
 func (h *Handler) UpdateDiaryEntry(c echo.Context) error {
@@ -200,8 +359,12 @@ func (h *Handler) UpdateDiaryEntry(c echo.Context) error {
 		return echo.ErrBadRequest.WithInternal(err)
 	}
 
-	entry, err := h.service.UpdateDiaryEntry(c.Request().Context(), userID, id, req)
+	service := h.service.WithIfMatch(c.Request().Header.Get(headerIfMatch))
+	entry, err := service.UpdateDiaryEntry(c.Request().Context(), userID, id, req)
 	if err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return h.diaryEntryPreconditionFailed(c, userID, id)
+		}
 		if errors.Is(err, core.ErrInvalidItem) {
 			return echo.ErrBadRequest.WithInternal(err)
 		}
@@ -211,7 +374,9 @@ func (h *Handler) UpdateDiaryEntry(c echo.Context) error {
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
 
-	return c.JSON(http.StatusOK, NewDiaryEntryResponse(entry))
+	response := NewDiaryEntryResponse(entry)
+	setEntityTag(c, entry.UpdatedAt, response)
+	return c.JSON(http.StatusOK, response)
 }
 
 func (h *Handler) DeleteDiaryEntry(c echo.Context) error {
@@ -219,15 +384,69 @@ func (h *Handler) DeleteDiaryEntry(c echo.Context) error {
 	if err != nil {
 		return err
 	}
+	permanent, err := parseBoolQueryParam(c, "permanent")
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	service := h.service.WithIfMatch(c.Request().Header.Get(headerIfMatch))
 
-	entry, err := h.service.DeleteDiaryEntry(c.Request().Context(), userID, id)
+	purge := permanent != nil && *permanent
+	var entry *DiaryEntry
+	if purge {
+		entry, err = service.PurgeDiaryEntry(c.Request().Context(), userID, id)
+	} else {
+		entry, err = service.DeleteDiaryEntry(c.Request().Context(), userID, id)
+	}
 	if err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return h.diaryEntryPreconditionFailed(c, userID, id)
+		}
 		if errors.Is(err, core.ErrItemNotFound) {
 			return echo.ErrNotFound.WithInternal(err)
 		}
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
-	return c.JSON(http.StatusOK, NewDiaryEntryResponse(entry))
+	response := NewDiaryEntryResponse(entry)
+	if !purge {
+		setEntityTag(c, entry.UpdatedAt, response)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) EmptyDiaryEntryTrash(c echo.Context) error {
+	userID := session.GetUserID(c)
+	purged, err := h.service.EmptyDiaryEntryTrash(c.Request().Context(), userID)
+	if err != nil {
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, TrashPurgeResponse{Purged: purged})
+}
+
+func (h *Handler) BulkDiaryEntries(c echo.Context) error {
+	return h.runBulk(c, h.service.BulkDiaryEntries)
+}
+
+func (h *Handler) runBulk(c echo.Context, run func(context.Context, uint, BulkRequest) (*BulkReport, error)) error {
+	userID := session.GetUserID(c)
+	var req BulkRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	report, err := run(c.Request().Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, ErrBulkFailed) {
+			return c.JSON(http.StatusUnprocessableEntity, report)
+		}
+		if errors.Is(err, core.ErrInvalidItem) {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, report)
 }
 
 func (h *Handler) RestoreDiaryEntry(c echo.Context) error {
@@ -235,9 +454,13 @@ func (h *Handler) RestoreDiaryEntry(c echo.Context) error {
 	if err != nil {
 		return err
 	}
+	service := h.service.WithIfMatch(c.Request().Header.Get(headerIfMatch))
 
-	entry, err := h.service.RestoreDiaryEntry(c.Request().Context(), userID, id)
+	entry, err := service.RestoreDiaryEntry(c.Request().Context(), userID, id)
 	if err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return h.diaryEntryPreconditionFailed(c, userID, id)
+		}
 		if errors.Is(err, core.ErrItemNotFound) {
 			return echo.ErrNotFound.WithInternal(err)
 		}
@@ -246,23 +469,647 @@ func (h *Handler) RestoreDiaryEntry(c echo.Context) error {
 		}
 		return echo.ErrInternalServerError.WithInternal(err)
 	}
-	return c.JSON(http.StatusOK, NewDiaryEntryResponse(entry))
+	response := NewDiaryEntryResponse(entry)
+	setEntityTag(c, entry.UpdatedAt, response)
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) ListDiaryEntryRevisions(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+	limit, offset, _, err := parsePagination(c)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	page, err := h.service.ListDiaryEntryRevisions(c.Request().Context(), userID, id, limit, offset)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, page)
 }
 
-func parsePagination(c echo.Context) (int, int, bool) {
-	limit, err := strconv.Atoi(c.QueryParam("limit"))
-	if err != nil || limit <= 0 {
-		limit = 10
+func (h *Handler) GetDiaryEntryRevision(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
 	}
-	offset, err := strconv.Atoi(c.QueryParam("offset"))
-	if err != nil || offset < 0 {
-		offset = 0
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	entry, err := h.service.GetDiaryEntryRevision(c.Request().Context(), userID, id, revision)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, entry)
+}
+
+func (h *Handler) DiffDiaryEntryRevisions(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+	from, err := parseIntQueryParam(c, "from", 0)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	to, err := parseIntQueryParam(c, "to", 0)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if from <= 0 || to <= 0 {
+		return echo.ErrBadRequest.WithInternal(fmt.Errorf("from and to revisions are required"))
+	}
+
+	diff, err := h.service.DiffDiaryEntryRevisions(c.Request().Context(), userID, id, from, to)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, diff)
+}
+
+func (h *Handler) RestoreDiaryEntryRevision(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	service := h.service.WithIfMatch(c.Request().Header.Get(headerIfMatch))
+	entry, err := service.RestoreDiaryEntryRevision(c.Request().Context(), userID, id, revision)
+	if err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return h.diaryEntryPreconditionFailed(c, userID, id)
+		}
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		if errors.Is(err, core.ErrInvalidItem) {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	response := NewDiaryEntryResponse(entry)
+	setEntityTag(c, entry.UpdatedAt, response)
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) ListTags(c echo.Context) error {
+	limit, offset, _, err := parsePagination(c)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	userID := session.GetUserID(c)
+	page, err := h.service.ListTags(c.Request().Context(), userID, limit, offset)
+	if err != nil {
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) GetTag(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+
+	tag, err := h.service.GetTag(c.Request().Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, tag)
+}
+
+func (h *Handler) CreateTag(c echo.Context) error {
+	userID := session.GetUserID(c)
+	var req TagEditRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	tag, err := h.service.CreateTag(c.Request().Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidItem) {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		if errors.Is(err, ErrTagExists) {
+			return echo.ErrConflict.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusCreated, tag)
+}
+
+func (h *Handler) RenameTag(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+	var req TagEditRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	tag, err := h.service.RenameTag(c.Request().Context(), userID, id, req)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidItem) {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		if errors.Is(err, ErrTagExists) {
+			return echo.ErrConflict.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, tag)
+}
+
+func (h *Handler) DeleteTag(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+
+	tag, err := h.service.DeleteTag(c.Request().Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, tag)
+}
+
+func (h *Handler) MergeTag(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+	var req TagMergeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	tag, err := h.service.MergeTag(c.Request().Context(), userID, id, req)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidItem) {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, tag)
+}
+
+func (h *Handler) Search(c echo.Context) error {
+	limit, offset, deleted, err := parsePagination(c)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	userID := session.GetUserID(c)
+
+	var recordType SearchRecordType
+	if typeParam := c.QueryParam("type"); typeParam != "" {
+		parsed, err := ParseSearchRecordType(typeParam)
+		if err != nil {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		recordType = parsed
+	}
+
+	page, err := h.service.Search(c.Request().Context(), userID, c.QueryParam("q"), recordType, limit, offset, deleted)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidItem) {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, NewSearchResultPageResponse(page))
+}
+
+func (h *Handler) GetMoodStats(c echo.Context) error {
+	location := time.UTC
+	if tz := c.QueryParam("tz"); tz != "" {
+		loaded, err := time.LoadLocation(tz)
+		if err != nil {
+			return echo.ErrBadRequest.WithInternal(fmt.Errorf("parse tz: %w", err))
+		}
+		location = loaded
+	}
+	interval := MoodStatsIntervalDay
+	if intervalParam := c.QueryParam("interval"); intervalParam != "" {
+		parsed, err := ParseMoodStatsInterval(intervalParam)
+		if err != nil {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		interval = parsed
+	}
+	from, to, err := parseTimeRange(c, location)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	userID := session.GetUserID(c)
+	filter := NewMoodRecordFilter().WithCreatedRange(from, to)
+	stats, err := h.service.GetMoodStats(c.Request().Context(), userID, filter, interval, location)
+	if err != nil {
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+func (h *Handler) ExportJournal(c echo.Context) error {
+	format := ExportFormatJSON
+	if formatParam := c.QueryParam("format"); formatParam != "" {
+		parsed, err := ParseExportFormat(formatParam)
+		if err != nil {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		format = parsed
+	}
+	deleted, err := parseBoolQueryParam(c, "deleted")
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	userID := session.GetUserID(c)
+	exportedAt := time.Now()
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, format.ContentType())
+	response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", format.FileName(exportedAt)))
+	response.WriteHeader(http.StatusOK)
+
+	if err := h.service.ExportJournal(c.Request().Context(), userID, format, deleted != nil && *deleted, exportedAt, response); err != nil {
+		return fmt.Errorf("export journal: %w", err)
+	}
+	return nil
+}
+
+func (h *Handler) ListAttachments(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+
+	attachments, err := h.service.ListAttachments(c.Request().Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, attachments)
+}
+
+func (h *Handler) CreateAttachment(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	defer file.Close()
+
+	attachment, err := h.service.CreateAttachment(c.Request().Context(), userID, id, fileHeader.Filename, fileHeader.Size, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, core.ErrItemNotFound):
+			return echo.ErrNotFound.WithInternal(err)
+		case errors.Is(err, core.ErrInvalidItem):
+			return echo.ErrBadRequest.WithInternal(err)
+		case errors.Is(err, ErrAttachmentTooLarge), errors.Is(err, ErrAttachmentQuota):
+			return echo.ErrStatusRequestEntityTooLarge.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusCreated, attachment)
+}
+
+func (h *Handler) GetAttachmentUsage(c echo.Context) error {
+	userID := session.GetUserID(c)
+	usage, err := h.service.GetAttachmentUsage(c.Request().Context(), userID)
+	if err != nil {
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, usage)
+}
+
+func (h *Handler) GetAttachment(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+
+	attachment, err := h.service.GetAttachment(c.Request().Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, attachment)
+}
+
+func (h *Handler) DownloadAttachment(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+
+	attachment, content, err := h.service.OpenAttachment(c.Request().Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return echo.ErrNotFound.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	defer content.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.MIMEType, "image/") {
+		disposition = "inline"
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(attachment.Size, 10))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	header.Set(echo.HeaderContentSecurityPolicy, "sandbox")
+	header.Set("Cache-Control", "private, max-age=31536000, immutable")
+	header.Set("ETag", `"`+attachment.SHA256+`"`)
+	return c.Stream(http.StatusOK, attachment.MIMEType, content)
+}
+
+func (h *Handler) DeleteAttachment(c echo.Context) error {
+	id, userID, err := parseIDAndUserID(c)
+	if err != nil {
+		return err
+	}
+
+	attachment, err := h.service.DeleteAttachment(c.Request().Context(), userID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, core.ErrItemNotFound):
+			return echo.ErrNotFound.WithInternal(err)
+		case errors.Is(err, ErrAttachmentInUse):
+			return echo.ErrConflict.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	return c.JSON(http.StatusOK, attachment)
+}
+
+func (h *Handler) ImportJournal(c echo.Context) error {
+	dryRun, err := parseBoolQueryParam(c, "dry_run")
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+	data, err := readImportBody(c)
+	if err != nil {
+		return err
+	}
+	document, err := ParseImportDocument(data)
+	if err != nil {
+		return echo.ErrBadRequest.WithInternal(err)
+	}
+
+	userID := session.GetUserID(c)
+	report, err := h.service.ImportJournal(c.Request().Context(), userID, document, dryRun != nil && *dryRun)
+	if err != nil {
+		if errors.Is(err, ErrImportBrokenReferences) {
+			return c.JSON(http.StatusUnprocessableEntity, report)
+		}
+		if errors.Is(err, core.ErrInvalidItem) {
+			return echo.ErrBadRequest.WithInternal(err)
+		}
+		return echo.ErrInternalServerError.WithInternal(err)
+	}
+	if report.DryRun {
+		return c.JSON(http.StatusOK, report)
+	}
+	return c.JSON(http.StatusCreated, report)
+}
+
+func readImportBody(c echo.Context) ([]byte, error) {
+	body := c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, echo.ErrBadRequest.WithInternal(err)
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, echo.ErrBadRequest.WithInternal(err)
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, ImportMaxBytes+1))
+	if err != nil {
+		return nil, echo.ErrBadRequest.WithInternal(err)
+	}
+	if len(data) > ImportMaxBytes {
+		return nil, echo.ErrStatusRequestEntityTooLarge
+	}
+	return data, nil
+}
+
+const maxPageLimit = 100
+
+func parsePagination(c echo.Context) (int, int, bool, error) {
+	limit, err := parseIntQueryParam(c, "limit", 10)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if limit <= 0 {
+		return 0, 0, false, fmt.Errorf("limit must be positive")
+	}
+	if limit > maxPageLimit {
+		return 0, 0, false, fmt.Errorf("limit must not exceed %d", maxPageLimit)
+	}
+	offset, err := parseIntQueryParam(c, "offset", 0)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if offset < 0 {
+		return 0, 0, false, fmt.Errorf("offset cannot be negative")
+	}
+	deleted, err := parseBoolQueryParam(c, "deleted")
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return limit, offset, deleted != nil && *deleted, nil
+}
+
+func parseCursorQuery(c echo.Context) (string, bool, error) {
+	if !c.QueryParams().Has("cursor") {
+		return "", false, nil
+	}
+	if c.QueryParam("offset") != "" {
+		return "", false, fmt.Errorf("offset cannot be combined with cursor")
+	}
+	return c.QueryParam("cursor"), true, nil
+}
+
+func parseMoodRecordFilter(c echo.Context, deleted bool) (*MoodRecordFilter, error) {
+	filter := NewMoodRecordFilter()
+	if deleted {
+		filter = filter.WithDeletedMode(core.DeletedModeDeletedOnly)
+	}
+
+	from, to, err := parseTimeRange(c, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	filter = filter.WithCreatedRange(from, to)
+
+	if feeling := strings.TrimSpace(c.QueryParam("feeling")); feeling != "" {
+		filter = filter.WithFeeling(feeling)
+	}
+	if emoji := strings.TrimSpace(c.QueryParam("emoji")); emoji != "" {
+		filter = filter.WithEmoji(emoji)
+	}
+	if tag := c.QueryParam("tag"); tag != "" {
+		name, err := NormalizeTagName(tag)
+		if err != nil {
+			return nil, err
+		}
+		filter = filter.WithTag(name)
+	}
+
+	hasDiaryEntries, err := parseBoolQueryParam(c, "has_diary_entries")
+	if err != nil {
+		return nil, err
+	}
+	if hasDiaryEntries != nil {
+		filter = filter.WithHasDiaryEntries(*hasDiaryEntries)
+	}
+	return filter, nil
+}
+
+func parseDiaryEntryFilter(c echo.Context, deleted bool) (*DiaryEntryFilter, error) {
+	filter := NewDiaryEntryFilter()
+	if deleted {
+		filter = filter.WithDeletedMode(core.DeletedModeDeletedOnly)
+	}
+
+	from, to, err := parseTimeRange(c, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	filter = filter.WithOccurredRange(from, to)
+
+	if tag := c.QueryParam("tag"); tag != "" {
+		name, err := NormalizeTagName(tag)
+		if err != nil {
+			return nil, err
+		}
+		filter = filter.WithTag(name)
+	}
+
+	hasMoodRecords, err := parseBoolQueryParam(c, "has_mood_records")
+	if err != nil {
+		return nil, err
+	}
+	if hasMoodRecords != nil {
+		filter = filter.WithHasMoodRecords(*hasMoodRecords)
+	}
+	return filter, nil
+}
+
+func parseTimeRange(c echo.Context, location *time.Location) (*time.Time, *time.Time, error) {
+	from, err := parseTimeQueryParam(c, "from", location, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := parseTimeQueryParam(c, "to", location, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
+
+func parseTimeQueryParam(c echo.Context, name string, location *time.Location, endOfDay bool) (*time.Time, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+	parsed, err := time.ParseInLocation(time.DateOnly, value, location)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: expected RFC 3339 timestamp or YYYY-MM-DD date", name)
+	}
+	if endOfDay {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return &parsed, nil
+}
+
+func parseIntQueryParam(c echo.Context, name string, fallback int) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return parsed, nil
+}
+
+func parseBoolQueryParam(c echo.Context, name string) (*bool, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
 	}
-	deleted, err := strconv.ParseBool(c.QueryParam("deleted"))
+	parsed, err := strconv.ParseBool(value)
 	if err != nil {
-		deleted = false
+		return nil, fmt.Errorf("parse %s: %w", name, err)
 	}
-	return limit, offset, deleted
+	return &parsed, nil
 }
 
 func parseIDAndUserID(c echo.Context) (uint, uint, error) {
@@ -229,17 +229,105 @@ func TestDiaryEntryHTTPContracts(t *testing.T) {
 	}
 }
 
+func TestJournalListQueryValidation(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	saveMoodRecord(t, environment, owner.ID, "anxious", time.Date(2026, time.March, 31, 23, 0, 0, 0, time.UTC))
+	saveMoodRecord(t, environment, owner.ID, "anxious", time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC))
+
+	for _, path := range []string{
+		"/api/journal/mood-records?limit=ten",
+		"/api/journal/mood-records?limit=0",
+		"/api/journal/mood-records?limit=101",
+		"/api/journal/diary-entries?limit=1000000",
+		"/api/journal/mood-records?offset=-1",
+		"/api/journal/mood-records?deleted=maybe",
+		"/api/journal/mood-records?from=March",
+		"/api/journal/mood-records?from=2026-04-01&to=2026-03-01",
+		"/api/journal/mood-records?has_diary_entries=sometimes",
+		"/api/journal/diary-entries?to=2026-13-01",
+		"/api/journal/diary-entries?has_mood_records=1x",
+	} {
+		response := serveJournalJSON(t, e, http.MethodGet, path, nil, ownerCookie)
+		if response.Code != http.StatusBadRequest {
+			t.Fatalf("GET %s status = %d, want %d", path, response.Code, http.StatusBadRequest)
+		}
+	}
+
+	if response := serveJournalJSON(t, e, http.MethodGet, "/api/journal/mood-records?limit=100", nil, ownerCookie); response.Code != http.StatusOK {
+		t.Fatalf("list with the largest limit status = %d, want %d", response.Code, http.StatusOK)
+	}
+
+	response := serveJournalJSON(t, e, http.MethodGet, "/api/journal/mood-records?from=2026-03-01&to=2026-03-31&feeling=Anxious", nil, ownerCookie)
+	if response.Code != http.StatusOK {
+		t.Fatalf("filtered list status = %d, want %d", response.Code, http.StatusOK)
+	}
+	var page core.Page[journal.MoodRecord]
+	decodeJournalResponse(t, response, &page)
+	if page.TotalCount != 1 || len(page.Items) != 1 {
+		t.Fatalf("date-only range returned %d records, want the whole last day only", page.TotalCount)
+	}
+}
+
+func TestJournalCursorPaginationHTTPContract(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	createdAt := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	saveMoodRecord(t, environment, owner.ID, "first", createdAt)
+	saveMoodRecord(t, environment, owner.ID, "second", createdAt.Add(time.Hour))
+
+	for _, path := range []string{
+		"/api/journal/mood-records?cursor=%25%25",
+		"/api/journal/mood-records?cursor=&offset=1",
+		"/api/journal/diary-entries?cursor=&total=often",
+	} {
+		response := serveJournalJSON(t, e, http.MethodGet, path, nil, ownerCookie)
+		if response.Code != http.StatusBadRequest {
+			t.Fatalf("GET %s status = %d, want %d", path, response.Code, http.StatusBadRequest)
+		}
+	}
+
+	firstResponse := serveJournalJSON(t, e, http.MethodGet, "/api/journal/mood-records?cursor=&limit=1", nil, ownerCookie)
+	if firstResponse.Code != http.StatusOK {
+		t.Fatalf("first cursor page status = %d, want %d", firstResponse.Code, http.StatusOK)
+	}
+	if body := firstResponse.Body.String(); strings.Contains(body, "total_count") {
+		t.Fatalf("cursor page without total=true contains total_count: %s", body)
+	}
+	var firstPage core.CursorPage[journal.MoodRecord]
+	decodeJournalResponse(t, firstResponse, &firstPage)
+	if len(firstPage.Items) != 1 || firstPage.Items[0].Feeling != "second" || firstPage.NextCursor == "" {
+		t.Fatal("first cursor page does not contain the newest record and a next cursor")
+	}
+
+	secondResponse := serveJournalJSON(t, e, http.MethodGet, "/api/journal/mood-records?limit=1&total=true&cursor="+firstPage.NextCursor, nil, ownerCookie)
+	var secondPage core.CursorPage[journal.MoodRecord]
+	decodeJournalResponse(t, secondResponse, &secondPage)
+	if len(secondPage.Items) != 1 || secondPage.Items[0].Feeling != "first" || secondPage.NextCursor != "" {
+		t.Fatal("second cursor page does not contain the oldest record without a next cursor")
+	}
+	if secondPage.TotalCount == nil || *secondPage.TotalCount != 2 {
+		t.Fatalf("second cursor page total = %v, want 2", secondPage.TotalCount)
+	}
+}
+
 func newJournalTestServer(t *testing.T, environment *journalTestEnvironment) (*echo.Echo, *session.Service) {
 	t.Helper()
 
 	testutil.DiscardLogs(t)
 
-	tokenService := session.NewService(session.NewRepository(environment.database), session.Config{
+	tokenService := session.NewService(session.NewRepository(environment.database), nil, session.Config{
 		JWTSecret:              journalTestJWTSecret,
 		JWTExpiration:          time.Hour,
 		RefreshTokenExpiration: 7 * 24 * time.Hour,
 	})
-	accountService := account.NewService(account.NewRepository(environment.database), tokenService, account.Config{
+	accountService := account.NewService(account.NewRepository(environment.database), tokenService, nil, nil, account.Config{
 		PasswordResetTokenExpiration: time.Hour,
 	})
 	validateUser := func(ctx context.Context, userID uint) error {
@@ -0,0 +1,308 @@
+package journal
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"gorm.io/gorm"
+)
+
+const ImportMaxBytes = 32 << 20
+
+var errImportDryRun = errors.New("import dry run")
+
+type ImportDocument struct {
+	MoodRecords  []ExportMoodRecord
+	DiaryEntries []ExportDiaryEntry
+}
+
+type ImportReport struct {
+	DryRun           bool          `json:"dry_run"`
+	MoodRecords      ImportCounts  `json:"mood_records"`
+	DiaryEntries     ImportCounts  `json:"diary_entries"`
+	MoodRecordIDs    map[uint]uint `json:"mood_record_ids"`
+	DiaryEntryIDs    map[uint]uint `json:"diary_entry_ids"`
+	Conflicts        []ImportIssue `json:"conflicts"`
+	BrokenReferences []ImportIssue `json:"broken_references"`
+}
+
+type ImportCounts struct {
+	Created int `json:"created"`
+	Skipped int `json:"skipped"`
+}
+
+type ImportIssue struct {
+	Type         SearchRecordType `json:"type"`
+	ID           uint             `json:"id"`
+	ExistingID   uint             `json:"existing_id,omitempty"`
+	MoodRecordID uint             `json:"mood_record_id,omitempty"`
+	DiaryEntryID uint             `json:"diary_entry_id,omitempty"`
+	AttachmentID uint             `json:"attachment_id,omitempty"`
+	Message      string           `json:"message"`
+}
+
+func newImportReport(dryRun bool) *ImportReport {
+	return &ImportReport{
+		DryRun:           dryRun,
+		MoodRecordIDs:    make(map[uint]uint),
+		DiaryEntryIDs:    make(map[uint]uint),
+		Conflicts:        []ImportIssue{},
+		BrokenReferences: []ImportIssue{},
+	}
+}
+
+func ParseImportDocument(data []byte) (*ImportDocument, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return parseMarkdownArchive(data)
+	}
+	return parseJSONImport(data)
+}
+
+func parseJSONImport(data []byte) (*ImportDocument, error) {
+	var document struct {
+		Version      int                `json:"version"`
+		MoodRecords  []ExportMoodRecord `json:"mood_records"`
+		DiaryEntries []ExportDiaryEntry `json:"diary_entries"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%w: parse JSON import: %w", core.ErrInvalidItem, err)
+	}
+	if document.Version != exportVersion {
+		return nil, fmt.Errorf("%w: unsupported import version %d", core.ErrInvalidItem, document.Version)
+	}
+	return &ImportDocument{MoodRecords: document.MoodRecords, DiaryEntries: document.DiaryEntries}, nil
+}
+
+func parseMarkdownArchive(data []byte) (*ImportDocument, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: open import archive: %w", core.ErrInvalidItem, err)
+	}
+
+	document := &ImportDocument{}
+	remaining := int64(ImportMaxBytes)
+	for _, file := range archive.File {
+		isMoodRecords := path.Base(file.Name) == exportMoodRecordsCSVName
+		if file.FileInfo().IsDir() || !isMoodRecords && path.Ext(file.Name) != ".md" {
+			continue
+		}
+
+		content, err := readArchiveFile(file, remaining)
+		if err != nil {
+			return nil, err
+		}
+		remaining -= int64(len(content))
+
+		if isMoodRecords {
+			records, err := parseMoodRecordsCSV(content)
+			if err != nil {
+				return nil, fmt.Errorf("%w: parse %s: %w", core.ErrInvalidItem, file.Name, err)
+			}
+			document.MoodRecords = append(document.MoodRecords, records...)
+			continue
+		}
+
+		entry, err := ParseDiaryEntryMarkdown(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("%w: parse %s: %w", core.ErrInvalidItem, file.Name, err)
+		}
+		if entry.OccurredAt.IsZero() {
+			entry.OccurredAt = file.Modified
+		}
+		document.DiaryEntries = append(document.DiaryEntries, entry)
+	}
+	return document, nil
+}
+
+func readArchiveFile(file *zip.File, limit int64) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: open %s: %w", core.ErrInvalidItem, file.Name, err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: read %s: %w", core.ErrInvalidItem, file.Name, err)
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("%w: import archive is too large", core.ErrInvalidItem)
+	}
+	return content, nil
+}
+
+func parseMoodRecordsCSV(content []byte) ([]ExportMoodRecord, error) {
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for index, name := range rows[0] {
+		columns[name] = index
+	}
+	value := func(row []string, name string) string {
+		if index, ok := columns[name]; ok && index < len(row) {
+			return row[index]
+		}
+		return ""
+	}
+
+	records := make([]ExportMoodRecord, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := ExportMoodRecord{
+			Feeling: unescapeCSVCell(value(row, "feeling")),
+			Emoji:   unescapeCSVCell(value(row, "emoji")),
+			Note:    unescapeCSVCell(value(row, "note")),
+		}
+		if record.ID, err = parseImportID(value(row, "id")); err != nil {
+			return nil, err
+		}
+		if record.CreatedAt, err = parseImportTime(value(row, "created_at")); err != nil {
+			return nil, err
+		}
+		if record.UpdatedAt, err = parseImportTime(value(row, "updated_at")); err != nil {
+			return nil, err
+		}
+		if record.DeletedAt, err = parseImportDeletedAt(value(row, "deleted_at")); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func ParseDiaryEntryMarkdown(content string) (ExportDiaryEntry, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	entry := ExportDiaryEntry{Markdown: content}
+
+	rest, ok := strings.CutPrefix(content, "---\n")
+	if !ok {
+		return entry, nil
+	}
+	frontMatter, markdown, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		return entry, fmt.Errorf("front matter is not closed")
+	}
+	entry.Markdown = markdown
+
+	for line := range strings.SplitSeq(frontMatter, "\n") {
+		key, rawValue, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		value, err := parseYAMLScalar(strings.TrimSpace(rawValue))
+		if err != nil {
+			return entry, fmt.Errorf("parse %s: %w", key, err)
+		}
+
+		switch strings.TrimSpace(key) {
+		case "id":
+			entry.ID, err = parseImportID(value)
+		case "title":
+			entry.Title = value
+		case "occurred_at":
+			entry.OccurredAt, err = parseImportTime(value)
+		case "created_at":
+			entry.CreatedAt, err = parseImportTime(value)
+		case "updated_at":
+			entry.UpdatedAt, err = parseImportTime(value)
+		case "deleted_at":
+			entry.DeletedAt, err = parseImportDeletedAt(value)
+		case "tags":
+			entry.Tags, err = parseYAMLStringList(value)
+		}
+		if err != nil {
+			return entry, fmt.Errorf("parse %s: %w", key, err)
+		}
+	}
+	return entry, nil
+}
+
+func parseYAMLScalar(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		var decoded string
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			return "", err
+		}
+		return decoded, nil
+	case strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") && len(value) >= 2:
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	case value == "~" || value == "null":
+		return "", nil
+	default:
+		return value, nil
+	}
+}
+
+func parseYAMLStringList(value string) ([]string, error) {
+	inner, ok := strings.CutPrefix(value, "[")
+	if !ok {
+		return nil, fmt.Errorf("expected a [list]")
+	}
+	inner, ok = strings.CutSuffix(inner, "]")
+	if !ok {
+		return nil, fmt.Errorf("list is not closed")
+	}
+
+	var items []string
+	if err := json.Unmarshal([]byte("["+inner+"]"), &items); err == nil {
+		return items, nil
+	}
+	items = nil
+	for item := range strings.SplitSeq(inner, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		parsed, err := parseYAMLScalar(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, parsed)
+	}
+	return items, nil
+}
+
+func parseImportID(value string) (uint, error) {
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse id %q: %w", value, err)
+	}
+	return uint(id), nil
+}
+
+func parseImportTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse time %q: %w", value, err)
+	}
+	return parsed, nil
+}
+
+func parseImportDeletedAt(value string) (gorm.DeletedAt, error) {
+	parsed, err := parseImportTime(value)
+	if err != nil || parsed.IsZero() {
+		return gorm.DeletedAt{}, err
+	}
+	return gorm.DeletedAt{Time: parsed, Valid: true}, nil
+}
@@ -0,0 +1,323 @@
+package journal_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestParseDiaryEntryMarkdownRoundTrip(t *testing.T) {
+	entry := journal.ExportDiaryEntry{
+		ID:            7,
+		Title:         `Quotes "and": colons`,
+		Markdown:      "# Day\n\n---\n\nFine.\n",
+		OccurredAt:    time.Date(2026, time.March, 10, 8, 30, 0, 0, time.UTC),
+		CreatedAt:     time.Date(2026, time.March, 10, 9, 0, 0, 0, time.UTC),
+		UpdatedAt:     time.Date(2026, time.March, 11, 9, 0, 0, 0, time.UTC),
+		MoodRecordIDs: []uint{3},
+		Tags:          []string{"rest", `quoted "tag"`},
+	}
+	entry.DeletedAt.Time = time.Date(2026, time.March, 12, 9, 0, 0, 0, time.UTC)
+	entry.DeletedAt.Valid = true
+
+	parsed, err := journal.ParseDiaryEntryMarkdown(journal.RenderDiaryEntryMarkdown(entry))
+	if err != nil {
+		t.Fatalf("ParseDiaryEntryMarkdown() error = %v", err)
+	}
+	if parsed.ID != entry.ID || parsed.Title != entry.Title || parsed.Markdown != "\n"+entry.Markdown {
+		t.Fatalf("ParseDiaryEntryMarkdown() = %+v, want fields of %+v", parsed, entry)
+	}
+	if !parsed.OccurredAt.Equal(entry.OccurredAt) || !parsed.CreatedAt.Equal(entry.CreatedAt) || !parsed.DeletedAt.Time.Equal(entry.DeletedAt.Time) {
+		t.Fatalf("ParseDiaryEntryMarkdown() times = %+v", parsed)
+	}
+	if !slices.Equal(parsed.Tags, entry.Tags) {
+		t.Fatalf("ParseDiaryEntryMarkdown() tags = %q, want %q", parsed.Tags, entry.Tags)
+	}
+	handwritten, err := journal.ParseDiaryEntryMarkdown("---\ntags: [rest, 'late night']\n---\nbody")
+	if err != nil || !slices.Equal(handwritten.Tags, []string{"rest", "late night"}) {
+		t.Fatalf("ParseDiaryEntryMarkdown() hand-written tags = %q, %v", handwritten.Tags, err)
+	}
+
+	plain, err := journal.ParseDiaryEntryMarkdown("no front matter")
+	if err != nil || plain.Markdown != "no front matter" {
+		t.Fatalf("ParseDiaryEntryMarkdown() without front matter = %+v, %v", plain, err)
+	}
+	if _, err := journal.ParseDiaryEntryMarkdown("---\ntitle: open\n"); err == nil {
+		t.Fatal("ParseDiaryEntryMarkdown() accepted unterminated front matter")
+	}
+}
+
+func TestServiceImportJournal(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	source := createJournalUser(t, environment, "source")
+	target := createJournalUser(t, environment, "target")
+	createdAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	saveMoodRecord(t, environment, target.ID, "padding", createdAt.AddDate(0, -1, 0))
+	calm := saveMoodRecord(t, environment, source.ID, "calm", createdAt)
+	gone := saveMoodRecord(t, environment, source.ID, "gone", createdAt.Add(time.Hour))
+	markdown := fmt.Sprintf("[[mood:%d|Calm]] then /mood-records/%d and `[[mood:%d]]`", calm.ID, gone.ID, calm.ID)
+	if _, err := environment.service.CreateDiaryEntry(t.Context(), source.ID, diaryRequest(markdown, timePointer(createdAt))); err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+	if _, err := environment.service.DeleteMoodRecord(t.Context(), source.ID, gone.ID); err != nil {
+		t.Fatalf("delete mood record: %v", err)
+	}
+
+	var exported bytes.Buffer
+	if err := environment.service.ExportJournal(t.Context(), source.ID, journal.ExportFormatJSON, true, createdAt, &exported); err != nil {
+		t.Fatalf("export journal: %v", err)
+	}
+	document, err := journal.ParseImportDocument(exported.Bytes())
+	if err != nil {
+		t.Fatalf("ParseImportDocument() error = %v", err)
+	}
+
+	dryRun, err := environment.service.ImportJournal(t.Context(), target.ID, document, true)
+	if err != nil {
+		t.Fatalf("ImportJournal() dry run error = %v", err)
+	}
+	if !dryRun.DryRun || dryRun.MoodRecords.Created != 2 || dryRun.DiaryEntries.Created != 1 {
+		t.Fatalf("dry run report = %+v, want 2 moods and 1 diary entry", dryRun)
+	}
+	assertMoodRecordCount(t, environment, target.ID, 1)
+
+	report, err := environment.service.ImportJournal(t.Context(), target.ID, document, false)
+	if err != nil {
+		t.Fatalf("ImportJournal() error = %v", err)
+	}
+	newCalm, newGone := report.MoodRecordIDs[calm.ID], report.MoodRecordIDs[gone.ID]
+	if newCalm == 0 || newGone == 0 || newCalm == calm.ID {
+		t.Fatalf("import ID map = %v, want new IDs for %d and %d", report.MoodRecordIDs, calm.ID, gone.ID)
+	}
+	imported, err := environment.service.ListDiaryEntries(t.Context(), target.ID, journal.NewDiaryEntryFilter(), 10, 0)
+	if err != nil || len(imported.Items) != 1 {
+		t.Fatalf("imported diary entries = %+v, %v", imported.Items, err)
+	}
+	wantMarkdown := fmt.Sprintf("[[mood:%d|Calm]] then /mood-records/%d and `[[mood:%d]]`", newCalm, newGone, calm.ID)
+	if imported.Items[0].Markdown != wantMarkdown {
+		t.Fatalf("imported markdown = %q, want %q", imported.Items[0].Markdown, wantMarkdown)
+	}
+	importedGone, err := environment.service.GetMoodRecord(t.Context(), target.ID, newGone)
+	if err != nil || !importedGone.DeletedAt.Valid || importedGone.UserID != target.ID {
+		t.Fatalf("imported deleted mood record = %+v, %v", importedGone, err)
+	}
+	importedCalm, err := environment.service.GetMoodRecord(t.Context(), target.ID, newCalm)
+	if err != nil || !importedCalm.CreatedAt.Equal(createdAt) || len(importedCalm.DiaryEntries) != 1 {
+		t.Fatalf("imported mood record = %+v, %v, want original time and one diary link", importedCalm, err)
+	}
+
+	repeated, err := environment.service.ImportJournal(t.Context(), target.ID, document, false)
+	if err != nil {
+		t.Fatalf("repeated ImportJournal() error = %v", err)
+	}
+	if repeated.MoodRecords.Created != 0 || repeated.DiaryEntries.Created != 0 || repeated.DiaryEntries.Skipped != 1 {
+		t.Fatalf("repeated import report = %+v, want everything skipped", repeated)
+	}
+	if len(repeated.Conflicts) != 3 || repeated.Conflicts[0].ExistingID != newCalm || repeated.Conflicts[1].ExistingID != newGone {
+		t.Fatalf("repeated import conflicts = %+v", repeated.Conflicts)
+	}
+}
+
+func TestServiceImportJournalRemapsDiaryEntryLinks(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	source := createJournalUser(t, environment, "source")
+	target := createJournalUser(t, environment, "target")
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	if _, err := environment.service.CreateDiaryEntry(t.Context(), target.ID, diaryRequest("padding", &occurredAt)); err != nil {
+		t.Fatalf("create padding diary entry: %v", err)
+	}
+	first, err := environment.service.CreateDiaryEntry(t.Context(), source.ID, diaryRequest("first", &occurredAt))
+	if err != nil {
+		t.Fatalf("create first diary entry: %v", err)
+	}
+	second, err := environment.service.CreateDiaryEntry(t.Context(), source.ID, diaryRequest(fmt.Sprintf("back to [[diary:%d]]", first.ID), &occurredAt))
+	if err != nil {
+		t.Fatalf("create second diary entry: %v", err)
+	}
+	if _, err := environment.service.UpdateDiaryEntry(t.Context(), source.ID, first.ID, diaryRequest(fmt.Sprintf("on to [[diary:%d]]", second.ID), &occurredAt)); err != nil {
+		t.Fatalf("link first diary entry: %v", err)
+	}
+
+	var exported bytes.Buffer
+	if err := environment.service.ExportJournal(t.Context(), source.ID, journal.ExportFormatJSON, false, occurredAt, &exported); err != nil {
+		t.Fatalf("export journal: %v", err)
+	}
+	document, err := journal.ParseImportDocument(exported.Bytes())
+	if err != nil {
+		t.Fatalf("ParseImportDocument() error = %v", err)
+	}
+
+	report, err := environment.service.ImportJournal(t.Context(), target.ID, document, false)
+	if err != nil {
+		t.Fatalf("ImportJournal() error = %v", err)
+	}
+	newFirst, newSecond := report.DiaryEntryIDs[first.ID], report.DiaryEntryIDs[second.ID]
+	imported, err := environment.service.GetDiaryEntry(t.Context(), target.ID, newFirst)
+	if err != nil {
+		t.Fatalf("GetDiaryEntry() error = %v", err)
+	}
+	if imported.Markdown != fmt.Sprintf("on to [[diary:%d]]", newSecond) || !slices.Equal(diaryEntryIDs(imported.Links), []uint{newSecond}) || !slices.Equal(diaryEntryIDs(imported.Backlinks), []uint{newSecond}) {
+		t.Fatalf("imported entry = %q links %v backlinks %v, want both pointing at %d", imported.Markdown, diaryEntryIDs(imported.Links), diaryEntryIDs(imported.Backlinks), newSecond)
+	}
+	revisions, err := environment.service.ListDiaryEntryRevisions(t.Context(), target.ID, newFirst, 10, 0)
+	if err != nil {
+		t.Fatalf("ListDiaryEntryRevisions() error = %v", err)
+	}
+	if revisions.TotalCount != 1 || revisions.Items[0].Markdown != imported.Markdown {
+		t.Fatalf("imported revisions = %+v, want one revision with the remapped markdown", revisions.Items)
+	}
+
+	repeated, err := environment.service.ImportJournal(t.Context(), target.ID, document, false)
+	if err != nil {
+		t.Fatalf("repeated ImportJournal() error = %v", err)
+	}
+	if repeated.DiaryEntries.Created != 0 || repeated.DiaryEntries.Skipped != 2 {
+		t.Fatalf("repeated import report = %+v, want both diary entries skipped", repeated)
+	}
+
+	document.DiaryEntries = document.DiaryEntries[1:]
+	fresh := createJournalUser(t, environment, "fresh")
+	broken, err := environment.service.ImportJournal(t.Context(), fresh.ID, document, true)
+	if err != nil {
+		t.Fatalf("ImportJournal() dry run error = %v", err)
+	}
+	if len(broken.BrokenReferences) != 1 || broken.BrokenReferences[0].DiaryEntryID == 0 || broken.DiaryEntries.Created != 0 {
+		t.Fatalf("partial import report = %+v, want one broken diary link", broken)
+	}
+}
+
+func TestServiceImportJournalRejectsBrokenReferences(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	createdAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	document := &journal.ImportDocument{
+		MoodRecords: []journal.ExportMoodRecord{{ID: 1, Feeling: "calm", CreatedAt: createdAt}},
+		DiaryEntries: []journal.ExportDiaryEntry{
+			{ID: 5, Markdown: "[[mood:1]] and [[mood:2]]", OccurredAt: createdAt},
+			{ID: 6, Markdown: "![lake](attachment:3)", OccurredAt: createdAt},
+		},
+	}
+
+	dryRun, err := environment.service.ImportJournal(t.Context(), owner.ID, document, true)
+	if err != nil {
+		t.Fatalf("ImportJournal() dry run error = %v", err)
+	}
+	want := []journal.ImportIssue{{
+		Type:         journal.SearchRecordTypeDiaryEntry,
+		ID:           5,
+		MoodRecordID: 2,
+		Message:      "links to a mood record that is not part of the import",
+	}, {
+		Type:         journal.SearchRecordTypeDiaryEntry,
+		ID:           6,
+		AttachmentID: 3,
+		Message:      "references an attachment that is not part of the import",
+	}}
+	if !slices.Equal(dryRun.BrokenReferences, want) {
+		t.Fatalf("dry run broken references = %+v, want %+v", dryRun.BrokenReferences, want)
+	}
+
+	if _, err := environment.service.ImportJournal(t.Context(), owner.ID, document, false); !errors.Is(err, journal.ErrImportBrokenReferences) {
+		t.Fatalf("ImportJournal() error = %v, want ErrImportBrokenReferences", err)
+	}
+	assertMoodRecordCount(t, environment, owner.ID, 0)
+
+	document.DiaryEntries[0].Markdown = ""
+	if _, err := environment.service.ImportJournal(t.Context(), owner.ID, document, false); !errors.Is(err, core.ErrInvalidItem) {
+		t.Fatalf("ImportJournal() invalid entry error = %v, want ErrInvalidItem", err)
+	}
+}
+
+func TestImportHTTPContract(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	source := createJournalUser(t, environment, "source")
+	target := createJournalUser(t, environment, "target")
+	e, tokenService := newJournalTestServer(t, environment)
+	sourceCookie := journalUserCookie(t, tokenService, source.ID)
+	targetCookie := journalUserCookie(t, tokenService, target.ID)
+	createdAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	record := saveMoodRecord(t, environment, source.ID, "calm", createdAt)
+	if _, err := environment.service.CreateDiaryEntry(t.Context(), source.ID, journal.DiaryEditEntryRequest{
+		Title:      "Spring",
+		Markdown:   fmt.Sprintf("See [[mood:%d]]", record.ID),
+		OccurredAt: &createdAt,
+	}); err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+
+	archive := serveJournalJSON(t, e, http.MethodGet, "/api/journal/export?format=markdown", nil, sourceCookie)
+	if archive.Code != http.StatusOK {
+		t.Fatalf("export status = %d", archive.Code)
+	}
+
+	importArchive := func(path string, body []byte) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		request.Header.Set("Content-Type", "application/zip")
+		request.AddCookie(targetCookie)
+		response := httptest.NewRecorder()
+		e.ServeHTTP(response, request)
+		return response
+	}
+
+	if response := importArchive("/api/journal/import", []byte("{not json")); response.Code != http.StatusBadRequest {
+		t.Fatalf("malformed import status = %d, want %d", response.Code, http.StatusBadRequest)
+	}
+	dryRun := importArchive("/api/journal/import?dry_run=true", archive.Body.Bytes())
+	if dryRun.Code != http.StatusOK {
+		t.Fatalf("dry run import status = %d, want %d: %s", dryRun.Code, http.StatusOK, dryRun.Body.String())
+	}
+	assertMoodRecordCount(t, environment, target.ID, 0)
+
+	response := importArchive("/api/journal/import", archive.Body.Bytes())
+	if response.Code != http.StatusCreated {
+		t.Fatalf("import status = %d, want %d: %s", response.Code, http.StatusCreated, response.Body.String())
+	}
+	var report journal.ImportReport
+	decodeJournalResponse(t, response, &report)
+	if report.MoodRecords.Created != 1 || report.DiaryEntries.Created != 1 {
+		t.Fatalf("import report = %+v, want one mood record and one diary entry", report)
+	}
+	entries, err := environment.service.ListDiaryEntries(t.Context(), target.ID, journal.NewDiaryEntryFilter(), 10, 0)
+	if err != nil || len(entries.Items) != 1 {
+		t.Fatalf("imported diary entries = %+v, %v", entries.Items, err)
+	}
+	if got, want := entries.Items[0].Markdown, fmt.Sprintf("See [[mood:%d]]", report.MoodRecordIDs[record.ID]); got != want || entries.Items[0].Title != "Spring" {
+		t.Fatalf("imported diary entry = %q/%q, want Spring/%q", entries.Items[0].Title, got, want)
+	}
+
+	broken := []byte(`{"version":1,"mood_records":[],"diary_entries":[{"id":1,"markdown":"[[mood:9]]","occurred_at":"2026-03-10T12:00:00Z"}]}`)
+	rejected := importArchive("/api/journal/import", broken)
+	if rejected.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("broken import status = %d, want %d", rejected.Code, http.StatusUnprocessableEntity)
+	}
+	var rejectedReport journal.ImportReport
+	decodeJournalResponse(t, rejected, &rejectedReport)
+	if len(rejectedReport.BrokenReferences) != 1 || rejectedReport.BrokenReferences[0].MoodRecordID != 9 {
+		t.Fatalf("broken import report = %+v", rejectedReport)
+	}
+}
+
+func assertMoodRecordCount(t *testing.T, environment *journalTestEnvironment, userID uint, want int64) {
+	t.Helper()
+
+	count, err := environment.repository.CountMoodRecords(t.Context(), journal.NewMoodRecordFilter().WithUserID(userID).WithDeletedMode(core.DeletedModeAll))
+	if err != nil {
+		t.Fatalf("count mood records: %v", err)
+	}
+	if count != want {
+		t.Fatalf("mood record count = %d, want %d", count, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -14,22 +15,39 @@ const previewMaxLength = 180
 var (
 	customMoodRecordLinkPattern = regexp.MustCompile(`\[\[mood:(\d+)(?:\|([^\]]+))?\]\]`)
 	moodRecordPageLinkPattern   = regexp.MustCompile(`(?:https?://[^\s)]+)?/mood-records/(\d+)(?:[?#][^\s)]*)?`)
+	customDiaryEntryLinkPattern = regexp.MustCompile(`\[\[diary:(\d+)(?:\|([^\]]+))?\]\]`)
+	diaryEntryPageLinkPattern   = regexp.MustCompile(`(?:https?://[^\s)]+)?/diary-entries/(\d+)(?:[?#][^\s)]*)?`)
+	attachmentReferencePattern  = regexp.MustCompile(`\battachment:(\d+)\b`)
 	markdownLinkPattern         = regexp.MustCompile(`\[(.*?)\]\((.*?)\)`)
+	markdownLinkTargetPattern   = regexp.MustCompile(`\]\([^)]*\)`)
+	hashtagPattern              = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/])#([\p{L}\p{N}_]+(?:-[\p{L}\p{N}_]+)*)`)
 	markdownHeadingPattern      = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s*`)
 	markdownQuotePattern        = regexp.MustCompile(`(?m)^\s{0,3}>\s?`)
 	markdownListPattern         = regexp.MustCompile(`(?m)^\s*([-+*]|\d+\.)\s+`)
 	markdownTokenPattern        = regexp.MustCompile("[*_`~]")
 	whitespacePattern           = regexp.MustCompile(`\s+`)
+
+	moodRecordLinkPatterns = []*regexp.Regexp{customMoodRecordLinkPattern, moodRecordPageLinkPattern}
+	diaryEntryLinkPatterns = []*regexp.Regexp{customDiaryEntryLinkPattern, diaryEntryPageLinkPattern}
 )
 
 func ExtractMoodRecordIDs(markdown string) ([]uint, error) {
+	return extractLinkIDs(markdown, "mood record", moodRecordLinkPatterns)
+}
+
+func ExtractDiaryEntryIDs(markdown string) ([]uint, error) {
+	return extractLinkIDs(markdown, "diary entry", diaryEntryLinkPatterns)
+}
+
+func ExtractAttachmentIDs(markdown string) ([]uint, error) {
+	return extractLinkIDs(markdown, "attachment", []*regexp.Regexp{attachmentReferencePattern})
+}
+
+func extractLinkIDs(markdown, kind string, patterns []*regexp.Regexp) ([]uint, error) {
 	seen := make(map[uint]struct{})
 	searchableMarkdown := stripCodeSections(markdown)
 
-	for _, pattern := range []*regexp.Regexp{
-		customMoodRecordLinkPattern,
-		moodRecordPageLinkPattern,
-	} {
+	for _, pattern := range patterns {
 		for _, match := range pattern.FindAllStringSubmatch(searchableMarkdown, -1) {
 			if len(match) < 2 {
 				continue
@@ -37,7 +55,7 @@ func ExtractMoodRecordIDs(markdown string) ([]uint, error) {
 
 			parsed, err := strconv.ParseUint(match[1], 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("parse mood record link %q: %w", match[1], err)
+				return nil, fmt.Errorf("parse %s link %q: %w", kind, match[1], err)
 			}
 			seen[uint(parsed)] = struct{}{}
 		}
@@ -51,21 +69,121 @@ func ExtractMoodRecordIDs(markdown string) ([]uint, error) {
 	return ids, nil
 }
 
-func MarkdownPreview(markdown string) string {
-	preview := strings.TrimSpace(markdown)
-	if preview == "" {
-		return ""
+func RewriteMoodRecordLinks(markdown string, ids map[uint]uint) (string, []uint) {
+	return rewriteLinkIDs(markdown, moodRecordLinkPatterns, ids)
+}
+
+func RewriteDiaryEntryLinks(markdown string, ids map[uint]uint) (string, []uint) {
+	return rewriteLinkIDs(markdown, diaryEntryLinkPatterns, ids)
+}
+
+func maskDiaryEntryLinks(markdown string) string {
+	masked, _ := rewriteLinkIDs(markdown, diaryEntryLinkPatterns, nil)
+	return masked
+}
+
+func rewriteLinkIDs(markdown string, patterns []*regexp.Regexp, ids map[uint]uint) (string, []uint) {
+	searchableMarkdown := stripCodeSections(markdown)
+	var spans [][]int
+	for _, pattern := range patterns {
+		for _, match := range pattern.FindAllStringSubmatchIndex(searchableMarkdown, -1) {
+			spans = append(spans, match[2:4])
+		}
+	}
+	slices.SortFunc(spans, func(a, b []int) int {
+		return a[0] - b[0]
+	})
+
+	missing := make(map[uint]struct{})
+	var result strings.Builder
+	position := 0
+	for _, span := range spans {
+		result.WriteString(markdown[position:span[0]])
+		position = span[1]
+		if ids == nil {
+			result.WriteString("0")
+			continue
+		}
+
+		oldID, err := strconv.ParseUint(markdown[span[0]:span[1]], 10, 64)
+		newID, ok := ids[uint(oldID)]
+		if err != nil || !ok {
+			missing[uint(oldID)] = struct{}{}
+			result.WriteString(markdown[span[0]:span[1]])
+			continue
+		}
+		result.WriteString(strconv.FormatUint(uint64(newID), 10))
+	}
+	result.WriteString(markdown[position:])
+
+	missingIDs := make([]uint, 0, len(missing))
+	for id := range missing {
+		missingIDs = append(missingIDs, id)
+	}
+	slices.Sort(missingIDs)
+	return result.String(), missingIDs
+}
+
+func ExtractHashtags(markdown string) []string {
+	seen := make(map[string]struct{})
+	for _, span := range hashtagSpans(markdown) {
+		seen[strings.ToLower(markdown[span[0]:span[1]])] = struct{}{}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func RewriteHashtag(markdown, from, to string) string {
+	var result strings.Builder
+	position := 0
+	for _, span := range hashtagSpans(markdown) {
+		if strings.ToLower(markdown[span[0]:span[1]]) != from {
+			continue
+		}
+		result.WriteString(markdown[position:span[0]])
+		result.WriteString(to)
+		position = span[1]
 	}
+	result.WriteString(markdown[position:])
+	return result.String()
+}
 
-	preview = customMoodRecordLinkPattern.ReplaceAllStringFunc(preview, moodRecordLinkPreviewText)
-	preview = markdownLinkPattern.ReplaceAllString(preview, "$1")
-	preview = markdownHeadingPattern.ReplaceAllString(preview, "")
-	preview = markdownQuotePattern.ReplaceAllString(preview, "")
-	preview = markdownListPattern.ReplaceAllString(preview, "")
-	preview = markdownTokenPattern.ReplaceAllString(preview, "")
-	preview = whitespacePattern.ReplaceAllString(preview, " ")
-	preview = strings.TrimSpace(preview)
+func StripHashtag(markdown, name string) string {
+	var result strings.Builder
+	position := 0
+	for _, span := range hashtagSpans(markdown) {
+		if strings.ToLower(markdown[span[0]:span[1]]) != name {
+			continue
+		}
+		result.WriteString(markdown[position : span[0]-1])
+		position = span[0]
+	}
+	result.WriteString(markdown[position:])
+	return result.String()
+}
 
+func hashtagSpans(markdown string) [][]int {
+	searchableMarkdown := markdownLinkTargetPattern.ReplaceAllStringFunc(stripCodeSections(markdown), func(target string) string {
+		return strings.Repeat(" ", len(target))
+	})
+
+	var spans [][]int
+	for _, match := range hashtagPattern.FindAllStringSubmatchIndex(searchableMarkdown, -1) {
+		if strings.IndexFunc(searchableMarkdown[match[2]:match[3]], unicode.IsLetter) < 0 {
+			continue
+		}
+		spans = append(spans, match[2:4])
+	}
+	return spans
+}
+
+func MarkdownPreview(markdown string) string {
+	preview := markdownPlainText(markdown)
 	if preview == "" {
 		return ""
 	}
@@ -78,6 +196,23 @@ func MarkdownPreview(markdown string) string {
 	return strings.TrimSpace(string(runes[:previewMaxLength-1])) + "..."
 }
 
+func markdownPlainText(markdown string) string {
+	text := strings.TrimSpace(markdown)
+	if text == "" {
+		return ""
+	}
+
+	text = customMoodRecordLinkPattern.ReplaceAllStringFunc(text, moodRecordLinkPreviewText)
+	text = customDiaryEntryLinkPattern.ReplaceAllStringFunc(text, diaryEntryLinkPreviewText)
+	text = markdownLinkPattern.ReplaceAllString(text, "$1")
+	text = markdownHeadingPattern.ReplaceAllString(text, "")
+	text = markdownQuotePattern.ReplaceAllString(text, "")
+	text = markdownListPattern.ReplaceAllString(text, "")
+	text = markdownTokenPattern.ReplaceAllString(text, "")
+	text = whitespacePattern.ReplaceAllString(text, " ")
+	return strings.TrimSpace(text)
+}
+
 func moodRecordLinkPreviewText(raw string) string {
 	match := customMoodRecordLinkPattern.FindStringSubmatch(raw)
 	if len(match) < 2 {
@@ -91,6 +226,19 @@ func moodRecordLinkPreviewText(raw string) string {
 	return fmt.Sprintf("Mood record #%s", match[1])
 }
 
+func diaryEntryLinkPreviewText(raw string) string {
+	match := customDiaryEntryLinkPattern.FindStringSubmatch(raw)
+	if len(match) < 2 {
+		return raw
+	}
+
+	if len(match) >= 3 && strings.TrimSpace(match[2]) != "" {
+		return strings.TrimSpace(match[2])
+	}
+
+	return fmt.Sprintf("Diary entry #%s", match[1])
+}
+
 func stripCodeSections(markdown string) string {
 	var result strings.Builder
 	inFence := false
@@ -103,11 +251,7 @@ func stripCodeSections(markdown string) string {
 			if isClosingFence(line, fenceCharacter, fenceLength) {
 				inFence = false
 			}
-			if hasNewline {
-				result.WriteByte('\n')
-			} else {
-				result.WriteByte(' ')
-			}
+			writeBlank(&result, len(line), hasNewline)
 			continue
 		}
 
@@ -115,11 +259,7 @@ func stripCodeSections(markdown string) string {
 			inFence = true
 			fenceCharacter = character
 			fenceLength = length
-			if hasNewline {
-				result.WriteByte('\n')
-			} else {
-				result.WriteByte(' ')
-			}
+			writeBlank(&result, len(line), hasNewline)
 			continue
 		}
 
@@ -185,12 +325,19 @@ func stripInlineCode(markdown string) string {
 			continue
 		}
 
-		result.WriteByte(' ')
+		result.WriteString(strings.Repeat(" ", end+length-start))
 		position = end + length
 	}
 	return result.String()
 }
 
+func writeBlank(result *strings.Builder, length int, hasNewline bool) {
+	result.WriteString(strings.Repeat(" ", length))
+	if hasNewline {
+		result.WriteByte('\n')
+	}
+}
+
 func findBacktickRun(line string, position, length int) int {
 	for position < len(line) {
 		index := strings.IndexByte(line[position:], '`')
@@ -72,6 +72,24 @@ func TestExtractMoodRecordIDs(t *testing.T) {
 	}
 }
 
+func TestExtractDiaryEntryIDs(t *testing.T) {
+	markdown := "[[diary:4|Monday]] [[mood:9]] [again](/diary-entries/2#top) `[[diary:7]]` https://example.test/diary-entries/4"
+
+	got, err := journal.ExtractDiaryEntryIDs(markdown)
+	if err != nil {
+		t.Fatalf("ExtractDiaryEntryIDs() error = %v", err)
+	}
+	if !slices.Equal(got, []uint{2, 4}) {
+		t.Fatalf("ExtractDiaryEntryIDs() = %v, want [2 4]", got)
+	}
+
+	rewritten, missing := journal.RewriteDiaryEntryLinks(markdown, map[uint]uint{4: 40})
+	want := "[[diary:40|Monday]] [[mood:9]] [again](/diary-entries/2#top) `[[diary:7]]` https://example.test/diary-entries/40"
+	if rewritten != want || !slices.Equal(missing, []uint{2}) {
+		t.Fatalf("RewriteDiaryEntryLinks() = %q, %v, want %q, [2]", rewritten, missing, want)
+	}
+}
+
 func TestExtractMoodRecordIDsReportsOverflow(t *testing.T) {
 	_, err := journal.ExtractMoodRecordIDs(`[[mood:18446744073709551616]]`)
 
@@ -100,6 +118,11 @@ func TestMarkdownPreview(t *testing.T) {
 			markdown: `[[mood:4| Peaceful ]] and [[mood:8]]`,
 			want:     "Peaceful and Mood record #8",
 		},
+		{
+			name:     "diary link labels",
+			markdown: `See [[diary:3|last week]] and [[diary:5]]`,
+			want:     "See last week and Diary entry #5",
+		},
 		{name: "blank", markdown: " \n\t ", want: ""},
 	}
 
@@ -122,3 +145,65 @@ func TestMarkdownPreviewTruncatesByRunes(t *testing.T) {
 		t.Fatalf("MarkdownPreview() rune count = %d, want 182", got)
 	}
 }
+
+func TestRewriteMoodRecordLinks(t *testing.T) {
+	markdown := strings.Join([]string{
+		"[[mood:1|Calm]] and [[mood:2]]",
+		"`[[mood:1]]` stays literal",
+		"```",
+		"/mood-records/2",
+		"```",
+		"[link](/mood-records/1#details) https://example.test/mood-records/3?view=full",
+	}, "\n")
+
+	got, missing := journal.RewriteMoodRecordLinks(markdown, map[uint]uint{1: 10, 2: 20})
+	want := strings.Join([]string{
+		"[[mood:10|Calm]] and [[mood:20]]",
+		"`[[mood:1]]` stays literal",
+		"```",
+		"/mood-records/2",
+		"```",
+		"[link](/mood-records/10#details) https://example.test/mood-records/3?view=full",
+	}, "\n")
+	if got != want {
+		t.Fatalf("RewriteMoodRecordLinks() = %q, want %q", got, want)
+	}
+	if !slices.Equal(missing, []uint{3}) {
+		t.Fatalf("RewriteMoodRecordLinks() missing = %v, want [3]", missing)
+	}
+}
+
+func TestExtractHashtags(t *testing.T) {
+	markdown := strings.Join([]string{
+		"# Heading",
+		"Long day at #Work, then #self-care (#sleep) and #work again.",
+		"Issue #42, anchor [jump](#section), page https://example.test/page#frag and &#35;",
+		"`#inline` code",
+		"```",
+		"#fenced",
+		"```",
+		"#café_notes-",
+	}, "\n")
+
+	got := journal.ExtractHashtags(markdown)
+	want := []string{"café_notes", "self-care", "sleep", "work"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ExtractHashtags() = %v, want %v", got, want)
+	}
+}
+
+func TestRewriteHashtag(t *testing.T) {
+	got := journal.RewriteHashtag("#Work and #workout, `#work` #work.", "work", "job")
+	want := "#job and #workout, `#work` #job."
+	if got != want {
+		t.Fatalf("RewriteHashtag() = %q, want %q", got, want)
+	}
+}
+
+func TestStripHashtag(t *testing.T) {
+	got := journal.StripHashtag("#Work and #workout, `#work` #work.", "work")
+	want := "Work and #workout, `#work` work."
+	if got != want {
+		t.Fatalf("StripHashtag() = %q, want %q", got, want)
+	}
+}
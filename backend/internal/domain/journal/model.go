@@ -16,6 +16,7 @@ type MoodRecord struct {
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 	DiaryEntries []DiaryEntry   `gorm:"many2many:mood_record_diary_entries;joinForeignKey:MoodRecordID;joinReferences:DiaryEntryID;->" json:"diary_entries,omitempty"`
+	Tags         []Tag          `gorm:"many2many:mood_record_tags;joinForeignKey:MoodRecordID;joinReferences:TagID" json:"tags,omitempty"`
 }
 
 func (MoodRecord) TableName() string {
@@ -32,4 +33,50 @@ type DiaryEntry struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 	MoodRecords []MoodRecord   `gorm:"many2many:mood_record_diary_entries;joinForeignKey:DiaryEntryID;joinReferences:MoodRecordID" json:"mood_records,omitempty"`
+	Tags        []Tag          `gorm:"many2many:diary_entry_tags;joinForeignKey:DiaryEntryID;joinReferences:TagID" json:"tags,omitempty"`
+	Links       []DiaryEntry   `gorm:"many2many:diary_entry_links;joinForeignKey:SourceID;joinReferences:TargetID" json:"links,omitempty"`
+	Backlinks   []DiaryEntry   `gorm:"many2many:diary_entry_links;joinForeignKey:TargetID;joinReferences:SourceID;->" json:"backlinks,omitempty"`
+	Attachments []Attachment   `gorm:"foreignKey:DiaryEntryID;->" json:"attachments,omitempty"`
+}
+
+type DiaryEntryRevision struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	DiaryEntryID uint      `gorm:"uniqueIndex:idx_diary_entry_revision" json:"diary_entry_id"`
+	Revision     int       `gorm:"uniqueIndex:idx_diary_entry_revision" json:"revision"`
+	Title        string    `json:"title,omitempty"`
+	Markdown     string    `json:"markdown"`
+	OccurredAt   time.Time `json:"occurred_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (DiaryEntryRevision) TableName() string {
+	return "diary_entry_revisions"
+}
+
+type Tag struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_tag_user_name" json:"user_id"`
+	Name      string    `gorm:"uniqueIndex:idx_tag_user_name" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
+
+type Attachment struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	UserID       uint      `gorm:"index" json:"user_id"`
+	DiaryEntryID uint      `gorm:"index" json:"diary_entry_id"`
+	FileName     string    `json:"file_name"`
+	MIMEType     string    `json:"mime_type"`
+	Size         int64     `json:"size"`
+	SHA256       string    `gorm:"column:sha256" json:"sha256"`
+	StorageKey   string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (Attachment) TableName() string {
+	return "attachments"
 }
@@ -2,6 +2,7 @@ package journal_test
 
 import (
 	"errors"
+	"fmt"
 	"slices"
 	"testing"
 	"time"
@@ -102,21 +103,21 @@ func TestServiceListMoodRecords(t *testing.T) {
 		t.Fatalf("delete mood record: %v", err)
 	}
 
-	firstPage, err := environment.service.ListMoodRecords(t.Context(), owner.ID, 1, 0, false)
+	firstPage, err := environment.service.ListMoodRecords(t.Context(), owner.ID, journal.NewMoodRecordFilter(), 1, 0)
 	if err != nil {
 		t.Fatalf("ListMoodRecords() error = %v", err)
 	}
 	if firstPage.TotalCount != 2 || !slices.Equal(moodRecordIDs(firstPage.Items), []uint{newest.ID}) {
 		t.Fatalf("first active page IDs = %v total = %d, want [%d] and 2", moodRecordIDs(firstPage.Items), firstPage.TotalCount, newest.ID)
 	}
-	secondPage, err := environment.service.ListMoodRecords(t.Context(), owner.ID, 1, 1, false)
+	secondPage, err := environment.service.ListMoodRecords(t.Context(), owner.ID, journal.NewMoodRecordFilter(), 1, 1)
 	if err != nil {
 		t.Fatalf("ListMoodRecords() second page error = %v", err)
 	}
 	if secondPage.TotalCount != 2 || !slices.Equal(moodRecordIDs(secondPage.Items), []uint{oldest.ID}) {
 		t.Fatalf("second active page IDs = %v total = %d, want [%d] and 2", moodRecordIDs(secondPage.Items), secondPage.TotalCount, oldest.ID)
 	}
-	deletedPage, err := environment.service.ListMoodRecords(t.Context(), owner.ID, 10, 0, true)
+	deletedPage, err := environment.service.ListMoodRecords(t.Context(), owner.ID, journal.NewMoodRecordFilter().WithDeletedMode(core.DeletedModeDeletedOnly), 10, 0)
 	if err != nil {
 		t.Fatalf("ListMoodRecords() deleted error = %v", err)
 	}
@@ -124,3 +125,86 @@ func TestServiceListMoodRecords(t *testing.T) {
 		t.Fatalf("deleted page IDs = %v total = %d, want [%d] and 1", moodRecordIDs(deletedPage.Items), deletedPage.TotalCount, deleted.ID)
 	}
 }
+
+func TestServiceListMoodRecordsFilters(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	march := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	february := saveMoodRecord(t, environment, owner.ID, "Anxious", march.AddDate(0, -1, 0))
+	anxious := saveMoodRecord(t, environment, owner.ID, "anxious", march)
+	calm, err := environment.repository.SaveMoodRecord(t.Context(), &journal.MoodRecord{
+		UserID:    owner.ID,
+		Feeling:   "calm",
+		Emoji:     "🙂",
+		CreatedAt: march.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("save calm mood record: %v", err)
+	}
+	occurredAt := march.Add(2 * time.Hour)
+	if _, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest(fmt.Sprintf("[[mood:%d]]", calm.ID), &occurredAt)); err != nil {
+		t.Fatalf("create linking diary entry: %v", err)
+	}
+
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter *journal.MoodRecordFilter
+		want   []uint
+	}{
+		{name: "created range", filter: journal.NewMoodRecordFilter().WithCreatedRange(&from, &to), want: []uint{calm.ID, anxious.ID}},
+		{name: "open range end", filter: journal.NewMoodRecordFilter().WithCreatedRange(nil, &from), want: []uint{february.ID}},
+		{name: "feeling ignores case", filter: journal.NewMoodRecordFilter().WithFeeling(" ANXIOUS "), want: []uint{anxious.ID, february.ID}},
+		{name: "emoji", filter: journal.NewMoodRecordFilter().WithEmoji("🙂"), want: []uint{calm.ID}},
+		{name: "linked to diary entries", filter: journal.NewMoodRecordFilter().WithHasDiaryEntries(true), want: []uint{calm.ID}},
+		{name: "not linked to diary entries", filter: journal.NewMoodRecordFilter().WithHasDiaryEntries(false), want: []uint{anxious.ID, february.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := environment.service.ListMoodRecords(t.Context(), owner.ID, tt.filter, 10, 0)
+			if err != nil {
+				t.Fatalf("ListMoodRecords() error = %v", err)
+			}
+			if got := moodRecordIDs(page.Items); !slices.Equal(got, tt.want) || page.TotalCount != int64(len(tt.want)) {
+				t.Fatalf("ListMoodRecords() IDs = %v total = %d, want %v", got, page.TotalCount, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceListMoodRecordsByCursor(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	createdAt := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	first := saveMoodRecord(t, environment, owner.ID, "first", createdAt)
+	second := saveMoodRecord(t, environment, owner.ID, "second", createdAt)
+	third := saveMoodRecord(t, environment, owner.ID, "third", createdAt.Add(time.Hour))
+
+	firstPage, err := environment.service.ListMoodRecordsByCursor(t.Context(), owner.ID, journal.NewMoodRecordFilter(), "", 2, false)
+	if err != nil {
+		t.Fatalf("ListMoodRecordsByCursor() error = %v", err)
+	}
+	if got := moodRecordIDs(firstPage.Items); !slices.Equal(got, []uint{third.ID, second.ID}) || firstPage.NextCursor == "" || firstPage.TotalCount != nil {
+		t.Fatalf("first cursor page IDs = %v next = %q, want [%d %d] with a next cursor and no total", got, firstPage.NextCursor, third.ID, second.ID)
+	}
+
+	saveMoodRecord(t, environment, owner.ID, "inserted while scrolling", createdAt.Add(2*time.Hour))
+	secondPage, err := environment.service.ListMoodRecordsByCursor(t.Context(), owner.ID, journal.NewMoodRecordFilter(), firstPage.NextCursor, 2, true)
+	if err != nil {
+		t.Fatalf("ListMoodRecordsByCursor() second page error = %v", err)
+	}
+	if got := moodRecordIDs(secondPage.Items); !slices.Equal(got, []uint{first.ID}) || secondPage.NextCursor != "" {
+		t.Fatalf("second cursor page IDs = %v next = %q, want [%d] and no next cursor", got, secondPage.NextCursor, first.ID)
+	}
+	if secondPage.TotalCount == nil || *secondPage.TotalCount != 4 {
+		t.Fatalf("second cursor page total = %v, want 4", secondPage.TotalCount)
+	}
+
+	if _, err := environment.service.ListMoodRecordsByCursor(t.Context(), owner.ID, journal.NewMoodRecordFilter(), "not a cursor", 2, false); !errors.Is(err, core.ErrInvalidItem) {
+		t.Fatalf("ListMoodRecordsByCursor() malformed cursor error = %v, want ErrInvalidItem", err)
+	}
+}
@@ -0,0 +1,157 @@
+package journal
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const (
+	RenderFormatHTML = "html"
+
+	renderedHTMLCacheSize = 512
+	markdownPunctuation   = "\\`*_{}[]()<>#+-.!|~&\""
+)
+
+var (
+	markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+	htmlPolicy       = newHTMLPolicy()
+)
+
+type renderedHTML struct {
+	updatedAt time.Time
+	html      string
+}
+
+type renderedHTMLCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[uint]renderedHTML
+}
+
+func newRenderedHTMLCache(size int) *renderedHTMLCache {
+	return &renderedHTMLCache{size: size, entries: make(map[uint]renderedHTML, size)}
+}
+
+func (c *renderedHTMLCache) get(id uint, updatedAt time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.entries[id]
+	if !ok || !cached.updatedAt.Equal(updatedAt) {
+		return "", false
+	}
+	return cached.html, true
+}
+
+func (c *renderedHTMLCache) put(id uint, updatedAt time.Time, html string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[id]; !ok && len(c.entries) >= c.size {
+		for evicted := range c.entries {
+			delete(c.entries, evicted)
+			break
+		}
+	}
+	c.entries[id] = renderedHTML{updatedAt: updatedAt, html: html}
+}
+
+func (c *renderedHTMLCache) forget(ids []uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		delete(c.entries, id)
+	}
+}
+
+func newHTMLPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	return policy
+}
+
+func RenderMarkdownHTML(markdown string) (string, error) {
+	var rendered bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(expandCustomLinks(markdown)), &rendered); err != nil {
+		return "", fmt.Errorf("render markdown: %w", err)
+	}
+	return htmlPolicy.Sanitize(rendered.String()), nil
+}
+
+type markdownReplacement struct {
+	start int
+	end   int
+	text  string
+}
+
+func expandCustomLinks(markdown string) string {
+	searchableMarkdown := stripCodeSections(markdown)
+
+	var replacements []markdownReplacement
+	for _, match := range customMoodRecordLinkPattern.FindAllStringSubmatchIndex(searchableMarkdown, -1) {
+		text := moodRecordLinkPreviewText(markdown[match[0]:match[1]])
+		replacements = append(replacements, markdownReplacement{
+			start: match[0],
+			end:   match[1],
+			text:  fmt.Sprintf("[%s](/mood-records/%s)", escapeMarkdownText(text), markdown[match[2]:match[3]]),
+		})
+	}
+	for _, match := range customDiaryEntryLinkPattern.FindAllStringSubmatchIndex(searchableMarkdown, -1) {
+		text := diaryEntryLinkPreviewText(markdown[match[0]:match[1]])
+		replacements = append(replacements, markdownReplacement{
+			start: match[0],
+			end:   match[1],
+			text:  fmt.Sprintf("[%s](/diary-entries/%s)", escapeMarkdownText(text), markdown[match[2]:match[3]]),
+		})
+	}
+	for _, match := range attachmentReferencePattern.FindAllStringSubmatchIndex(searchableMarkdown, -1) {
+		replacements = append(replacements, markdownReplacement{
+			start: match[0],
+			end:   match[1],
+			text:  attachmentContentPath(markdown[match[2]:match[3]]),
+		})
+	}
+	if len(replacements) == 0 {
+		return markdown
+	}
+	slices.SortFunc(replacements, func(a, b markdownReplacement) int { return a.start - b.start })
+
+	var result strings.Builder
+	last := 0
+	for _, replacement := range replacements {
+		if replacement.start < last {
+			continue
+		}
+		result.WriteString(markdown[last:replacement.start])
+		result.WriteString(replacement.text)
+		last = replacement.end
+	}
+	result.WriteString(markdown[last:])
+	return result.String()
+}
+
+func attachmentContentPath(id string) string {
+	return "/api/journal/attachments/" + id + "/content"
+}
+
+func escapeMarkdownText(text string) string {
+	var result strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune(markdownPunctuation, r) {
+			result.WriteByte('\\')
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
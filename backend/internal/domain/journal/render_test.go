@@ -0,0 +1,119 @@
+package journal_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestRenderMarkdownHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+		dontWant []string
+	}{
+		{
+			name:     "commonmark and gfm",
+			markdown: "# Day\n\n**bold** ~~gone~~\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n- [x] walk",
+			want:     []string{"<h1>Day</h1>", "<strong>bold</strong>", "<del>gone</del>", "<table>", `<input checked="" disabled="" type="checkbox"`},
+		},
+		{
+			name:     "mood and diary links",
+			markdown: "Felt [[mood:4|calm *and* rested]] after [[mood:7]] and [[diary:2]]",
+			want:     []string{`<a href="/mood-records/4" rel="nofollow">calm *and* rested</a>`, `<a href="/mood-records/7" rel="nofollow">Mood record #7</a>`, `<a href="/diary-entries/2" rel="nofollow">Diary entry #2</a>`},
+		},
+		{
+			name:     "attachment references",
+			markdown: "![lake](attachment:12) and [notes](attachment:13)",
+			want:     []string{`<img src="/api/journal/attachments/12/content" alt="lake">`, `<a href="/api/journal/attachments/13/content" rel="nofollow">notes</a>`},
+		},
+		{
+			name:     "nested and overlapping links",
+			markdown: "[[mood:1|[[diary:2]] x [[diary:3|[[mood:4]]]] y",
+			want:     []string{`<a href="/mood-records/1" rel="nofollow">[[diary:2</a> x`, `<a href="/diary-entries/3" rel="nofollow">[[mood:4</a>]] y`},
+			dontWant: []string{"/diary-entries/2", "/mood-records/4"},
+		},
+		{
+			name:     "attachment reference inside a link label",
+			markdown: "see [[mood:1|photo attachment:3]] ok",
+			want:     []string{`<a href="/mood-records/1" rel="nofollow">photo attachment:3</a> ok`},
+			dontWant: []string{"/api/journal/attachments/3/content"},
+		},
+		{
+			name:     "links in code stay literal",
+			markdown: "`[[mood:4]]`",
+			want:     []string{"<code>[[mood:4]]</code>"},
+			dontWant: []string{"<a "},
+		},
+		{
+			name:     "unsafe html is removed",
+			markdown: "<script>alert(1)</script>\n\n[click](javascript:alert(1)) <img src=x onerror=alert(1)>",
+			dontWant: []string{"<script", "javascript:", "onerror"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := journal.RenderMarkdownHTML(tt.markdown)
+			if err != nil {
+				t.Fatalf("RenderMarkdownHTML() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Fatalf("RenderMarkdownHTML() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if strings.Contains(got, dontWant) {
+					t.Fatalf("RenderMarkdownHTML() = %q, want no %q", got, dontWant)
+				}
+			}
+		})
+	}
+}
+
+func TestGetDiaryEntryRendersHTML(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	entry, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("*first*", &occurredAt))
+	if err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+	path := fmt.Sprintf("/api/journal/diary-entries/%d", entry.ID)
+
+	plain := serveJournalJSON(t, e, http.MethodGet, path, nil, ownerCookie)
+	var plainResponse journal.DiaryEntryResponse
+	decodeJournalResponse(t, plain, &plainResponse)
+	if plainResponse.HTML != "" {
+		t.Fatalf("GET %s html = %q, want none without render", path, plainResponse.HTML)
+	}
+
+	rendered := serveJournalJSON(t, e, http.MethodGet, path+"?render=html", nil, ownerCookie)
+	var renderedResponse journal.DiaryEntryResponse
+	decodeJournalResponse(t, rendered, &renderedResponse)
+	if renderedResponse.HTML != "<p><em>first</em></p>\n" {
+		t.Fatalf("GET %s?render=html html = %q", path, renderedResponse.HTML)
+	}
+
+	if _, err := environment.service.UpdateDiaryEntry(t.Context(), owner.ID, entry.ID, diaryRequest("**second**", &occurredAt)); err != nil {
+		t.Fatalf("update diary entry: %v", err)
+	}
+	rendered = serveJournalJSON(t, e, http.MethodGet, path+"?render=html", nil, ownerCookie)
+	decodeJournalResponse(t, rendered, &renderedResponse)
+	if renderedResponse.HTML != "<p><strong>second</strong></p>\n" {
+		t.Fatalf("GET %s?render=html after update html = %q, want the new markdown rendered", path, renderedResponse.HTML)
+	}
+
+	if response := serveJournalJSON(t, e, http.MethodGet, path+"?render=pdf", nil, ownerCookie); response.Code != http.StatusBadRequest {
+		t.Fatalf("GET %s?render=pdf status = %d, want %d", path, response.Code, http.StatusBadRequest)
+	}
+}
@@ -5,19 +5,31 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"time"
 
 	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const purgeBatchSize = 500
+
 type Repository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	versions []time.Time
 }
 
 func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
+func (r *Repository) WithTx(ctx context.Context, fn func(repo *Repository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Repository{db: tx, versions: r.versions})
+	})
+}
+
 func (r *Repository) GetMoodRecord(ctx context.Context, filter *MoodRecordFilter) (*MoodRecord, error) {
 	var entry MoodRecord
 	query := filter.Apply(r.db.WithContext(ctx)).
@@ -26,7 +38,8 @@ func (r *Repository) GetMoodRecord(ctx context.Context, filter *MoodRecordFilter
 				Where("diary_entries.deleted_at IS NULL").
 				Order("occurred_at DESC").
 				Order("created_at DESC")
-		})
+		}).
+		Preload("Tags", orderTagsByName)
 	if err := query.First(&entry).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("%w: mood record not found", core.ErrItemNotFound)
@@ -39,7 +52,9 @@ func (r *Repository) GetMoodRecord(ctx context.Context, filter *MoodRecordFilter
 func (r *Repository) ListMoodRecords(ctx context.Context, filter *MoodRecordFilter, limit, offset int) ([]MoodRecord, error) {
 	var entries []MoodRecord
 	err := filter.Apply(r.db.WithContext(ctx)).
+		Preload("Tags", orderTagsByName).
 		Order("created_at DESC").
+		Order("id DESC").
 		Limit(limit).
 		Offset(offset).
 		Find(&entries).Error
@@ -49,6 +64,25 @@ func (r *Repository) ListMoodRecords(ctx context.Context, filter *MoodRecordFilt
 	return entries, nil
 }
 
+func (r *Repository) ListMoodRecordsAfter(ctx context.Context, filter *MoodRecordFilter, cursor *MoodRecordCursor, limit int) ([]MoodRecord, error) {
+	query := filter.Apply(r.db.WithContext(ctx))
+	if cursor != nil {
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	var entries []MoodRecord
+	err := query.
+		Preload("Tags", orderTagsByName).
+		Order("created_at DESC").
+		Order("id DESC").
+		Limit(limit).
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("list mood records after cursor: %w", err)
+	}
+	return entries, nil
+}
+
 func (r *Repository) CountMoodRecords(ctx context.Context, filter *MoodRecordFilter) (int64, error) {
 	var count int64
 	err := filter.Apply(r.db.WithContext(ctx).Model(&MoodRecord{})).Count(&count).Error
@@ -58,36 +92,131 @@ func (r *Repository) CountMoodRecords(ctx context.Context, filter *MoodRecordFil
 	return count, nil
 }
 
+func (r *Repository) ListMoodStatsRows(ctx context.Context, filter *MoodRecordFilter) ([]MoodStatsRow, error) {
+	db := r.db.WithContext(ctx)
+	var rows []MoodStatsRow
+	err := filter.Apply(db.Model(&MoodRecord{})).
+		Select("feeling, emoji, created_at, EXISTS (?) AS linked", linkedDiaryEntriesQuery(db)).
+		Order("created_at ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("list mood stats rows: %w", err)
+	}
+	return rows, nil
+}
+
 func (r *Repository) SaveMoodRecord(ctx context.Context, entry *MoodRecord) (*MoodRecord, error) {
-	if err := r.db.WithContext(ctx).Save(entry).Error; err != nil {
-		return nil, fmt.Errorf("save mood record: %w", err)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if entry.ID != 0 {
+			if err := r.checkVersion(tx, "mood_records", entry.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit("Tags").Save(entry).Error; err != nil {
+			return fmt.Errorf("save mood record: %w", err)
+		}
+		if err := tx.Model(entry).Association("Tags").Replace(entry.Tags); err != nil {
+			return fmt.Errorf("replace mood record tags: %w", err)
+		}
+		return indexMoodRecord(tx, entry)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return entry, nil
 }
 
 func (r *Repository) DeleteMoodRecord(ctx context.Context, filter *MoodRecordFilter) (*MoodRecord, error) {
 	var entry MoodRecord
-	q := filter.Apply(r.db.WithContext(ctx)).First(&entry)
-	if err := q.Error; err != nil {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := filter.Apply(tx).First(&entry).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: mood record not found", core.ErrItemNotFound)
+			}
+			return fmt.Errorf("find mood record to delete: %w", err)
+		}
+		if err := r.checkVersion(tx, "mood_records", entry.ID); err != nil {
+			return err
+		}
+		if err := tx.Delete(&entry).Error; err != nil {
+			return fmt.Errorf("delete mood record: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *Repository) PurgeMoodRecords(ctx context.Context, filter *MoodRecordFilter) (int64, error) {
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := filter.Apply(tx.Model(&MoodRecord{})).Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("find mood records to purge: %w", err)
+		}
+		if err := r.checkVersion(tx, "mood_records", ids...); err != nil {
+			return err
+		}
+		for batch := range slices.Chunk(ids, purgeBatchSize) {
+			if err := purgeMoodRecords(tx, batch); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(ids))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+func (r *Repository) FindMatchingMoodRecord(ctx context.Context, entry *MoodRecord, includeDeleted bool) (*MoodRecord, error) {
+	query := r.db.WithContext(ctx)
+	if includeDeleted {
+		query = query.Unscoped()
+	}
+
+	var existing MoodRecord
+	err := query.
+		Where("user_id = ?", entry.UserID).
+		Where("created_at = ?", entry.CreatedAt.UTC()).
+		Where("feeling = ? AND emoji = ? AND note = ?", entry.Feeling, entry.Emoji, entry.Note).
+		First(&existing).Error
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("%w: mood record not found", core.ErrItemNotFound)
+			return nil, fmt.Errorf("%w: matching mood record not found", core.ErrItemNotFound)
 		}
-		return nil, fmt.Errorf("find mood record to delete: %w", err)
+		return nil, fmt.Errorf("find matching mood record: %w", err)
 	}
-	if err := r.db.WithContext(ctx).Delete(&entry).Error; err != nil {
-		return nil, fmt.Errorf("delete mood record: %w", err)
+	return &existing, nil
+}
+
+func (r *Repository) MarkMoodRecordDeleted(ctx context.Context, id uint, deletedAt time.Time) error {
+	err := r.db.WithContext(ctx).Model(&MoodRecord{}).Where("id = ?", id).Update("deleted_at", deletedAt.UTC()).Error
+	if err != nil {
+		return fmt.Errorf("mark mood record deleted: %w", err)
 	}
-	return &entry, nil
+	return nil
 }
 
 func (r *Repository) GetDiaryEntry(ctx context.Context, filter *DiaryEntryFilter) (*DiaryEntry, error) {
+	ctx, span := tracing.Start(ctx, "journal.Repository.GetDiaryEntry")
+	defer span.End()
+
 	var entry DiaryEntry
 	query := filter.Apply(r.db.WithContext(ctx)).
 		Preload("MoodRecords", func(db *gorm.DB) *gorm.DB {
 			return db.
 				Where("mood_records.deleted_at IS NULL").
 				Order("created_at DESC")
-		})
+		}).
+		Preload("Tags", orderTagsByName).
+		Preload("Links", linkedDiaryEntriesPreload).
+		Preload("Backlinks", linkedDiaryEntriesPreload).
+		Preload("Attachments", orderAttachments)
 	if err := query.First(&entry).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("%w: diary entry not found", core.ErrItemNotFound)
@@ -100,8 +229,10 @@ func (r *Repository) GetDiaryEntry(ctx context.Context, filter *DiaryEntryFilter
 func (r *Repository) ListDiaryEntries(ctx context.Context, filter *DiaryEntryFilter, limit, offset int) ([]DiaryEntry, error) {
 	var entries []DiaryEntry
 	err := filter.Apply(r.db.WithContext(ctx)).
+		Preload("Tags", orderTagsByName).
 		Order("occurred_at DESC").
 		Order("created_at DESC").
+		Order("id DESC").
 		Limit(limit).
 		Offset(offset).
 		Find(&entries).Error
@@ -111,6 +242,26 @@ func (r *Repository) ListDiaryEntries(ctx context.Context, filter *DiaryEntryFil
 	return entries, nil
 }
 
+func (r *Repository) ListDiaryEntriesAfter(ctx context.Context, filter *DiaryEntryFilter, cursor *DiaryEntryCursor, limit int) ([]DiaryEntry, error) {
+	query := filter.Apply(r.db.WithContext(ctx))
+	if cursor != nil {
+		query = query.Where("(occurred_at, created_at, id) < (?, ?, ?)", cursor.OccurredAt, cursor.CreatedAt, cursor.ID)
+	}
+
+	var entries []DiaryEntry
+	err := query.
+		Preload("Tags", orderTagsByName).
+		Order("occurred_at DESC").
+		Order("created_at DESC").
+		Order("id DESC").
+		Limit(limit).
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("list diary entries after cursor: %w", err)
+	}
+	return entries, nil
+}
+
 func (r *Repository) CountDiaryEntries(ctx context.Context, filter *DiaryEntryFilter) (int64, error) {
 	var count int64
 	err := filter.Apply(r.db.WithContext(ctx).Model(&DiaryEntry{})).Count(&count).Error
@@ -121,14 +272,49 @@ func (r *Repository) CountDiaryEntries(ctx context.Context, filter *DiaryEntryFi
 }
 
 func (r *Repository) SaveDiaryEntry(ctx context.Context, entry *DiaryEntry) (*DiaryEntry, error) {
+	ctx, span := tracing.Start(ctx, "journal.Repository.SaveDiaryEntry")
+	defer span.End()
+
+	return r.saveDiaryEntry(ctx, entry, entry.ID == 0)
+}
+
+func (r *Repository) CreateDiaryEntry(ctx context.Context, entry *DiaryEntry) (*DiaryEntry, error) {
+	ctx, span := tracing.Start(ctx, "journal.Repository.CreateDiaryEntry")
+	defer span.End()
+
+	return r.saveDiaryEntry(ctx, entry, true)
+}
+
+func (r *Repository) saveDiaryEntry(ctx context.Context, entry *DiaryEntry, create bool) (*DiaryEntry, error) {
 	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Omit("MoodRecords").Save(entry).Error; err != nil {
+		write := tx.Omit("MoodRecords", "Tags", "Links", "Backlinks")
+		if create {
+			write = write.Create(entry)
+		} else {
+			if err := r.checkVersion(tx, "diary_entries", entry.ID); err != nil {
+				return err
+			}
+			if err := snapshotDiaryEntryHistory(tx, entry.ID); err != nil {
+				return err
+			}
+			write = write.Save(entry)
+		}
+		if err := write.Error; err != nil {
 			return fmt.Errorf("save diary entry: %w", err)
 		}
 		if err := tx.Model(entry).Association("MoodRecords").Replace(entry.MoodRecords); err != nil {
 			return fmt.Errorf("replace diary mood links: %w", err)
 		}
-		return nil
+		if err := tx.Model(entry).Association("Tags").Replace(entry.Tags); err != nil {
+			return fmt.Errorf("replace diary entry tags: %w", err)
+		}
+		if err := tx.Model(entry).Association("Links").Replace(entry.Links); err != nil {
+			return fmt.Errorf("replace diary entry links: %w", err)
+		}
+		if err := recordDiaryEntryRevision(tx, entry); err != nil {
+			return err
+		}
+		return indexDiaryEntry(tx, entry)
 	})
 	if err != nil {
 		return nil, err
@@ -147,6 +333,149 @@ func (r *Repository) SaveDiaryEntry(ctx context.Context, entry *DiaryEntry) (*Di
 	return updatedEntry, nil
 }
 
+func (r *Repository) FindMatchingDiaryEntry(ctx context.Context, entry *DiaryEntry, includeDeleted bool) (*DiaryEntry, error) {
+	query := r.db.WithContext(ctx)
+	if includeDeleted {
+		query = query.Unscoped()
+	}
+
+	var candidates []DiaryEntry
+	err := query.
+		Where("user_id = ?", entry.UserID).
+		Where("occurred_at = ?", entry.OccurredAt.UTC()).
+		Where("title = ?", entry.Title).
+		Order("id ASC").
+		Find(&candidates).Error
+	if err != nil {
+		return nil, fmt.Errorf("find matching diary entry: %w", err)
+	}
+
+	markdown := maskDiaryEntryLinks(entry.Markdown)
+	for i := range candidates {
+		if maskDiaryEntryLinks(candidates[i].Markdown) == markdown {
+			return &candidates[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: matching diary entry not found", core.ErrItemNotFound)
+}
+
+func (r *Repository) ReserveDiaryEntryIDs(ctx context.Context, count int) ([]uint, error) {
+	if count == 0 {
+		return nil, nil
+	}
+
+	db := r.db.WithContext(ctx)
+	if isPostgres(db) {
+		var ids []uint
+		err := db.Raw("SELECT nextval(pg_get_serial_sequence('diary_entries', 'id')) FROM generate_series(1, ?)", count).Scan(&ids).Error
+		if err != nil {
+			return nil, fmt.Errorf("reserve diary entry ids: %w", err)
+		}
+		return ids, nil
+	}
+
+	var sequences []uint
+	err := db.Raw("UPDATE sqlite_sequence SET seq = seq + ? WHERE name = 'diary_entries' RETURNING seq", count).Scan(&sequences).Error
+	if err == nil && len(sequences) == 0 {
+		sequences = []uint{uint(count)}
+		err = db.Exec("INSERT INTO sqlite_sequence (name, seq) VALUES ('diary_entries', ?)", count).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reserve diary entry ids: %w", err)
+	}
+
+	last := sequences[0]
+	ids := make([]uint, count)
+	for index := range ids {
+		ids[index] = last - uint(count-1-index)
+	}
+	return ids, nil
+}
+
+func (r *Repository) AddDiaryEntryLinks(ctx context.Context, entry *DiaryEntry, links []DiaryEntry) error {
+	if err := r.db.WithContext(ctx).Model(entry).Association("Links").Append(links); err != nil {
+		return fmt.Errorf("add diary entry links: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) MarkDiaryEntryDeleted(ctx context.Context, id uint, deletedAt time.Time) error {
+	err := r.db.WithContext(ctx).Model(&DiaryEntry{}).Where("id = ?", id).Update("deleted_at", deletedAt.UTC()).Error
+	if err != nil {
+		return fmt.Errorf("mark diary entry deleted: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) EachMoodRecordBatch(ctx context.Context, filter *MoodRecordFilter, batchSize int, fn func([]MoodRecord) error) error {
+	var batch []MoodRecord
+	err := filter.Apply(r.db.WithContext(ctx)).
+		Preload("Tags", orderTagsByName).
+		FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+	if err != nil {
+		return fmt.Errorf("iterate mood records: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) EachDiaryEntryBatch(ctx context.Context, filter *DiaryEntryFilter, batchSize int, fn func([]DiaryEntry) error) error {
+	var batch []DiaryEntry
+	err := filter.Apply(r.db.WithContext(ctx)).
+		Preload("MoodRecords", func(db *gorm.DB) *gorm.DB {
+			if filter.DeletedMode == core.DeletedModeAll {
+				db = db.Unscoped()
+			}
+			return db.Order("mood_records.id ASC")
+		}).
+		Preload("Tags", orderTagsByName).
+		FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+	if err != nil {
+		return fmt.Errorf("iterate diary entries: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) ListDiaryEntryRevisions(ctx context.Context, entryID uint, limit, offset int) ([]DiaryEntryRevision, error) {
+	var revisions []DiaryEntryRevision
+	err := r.db.WithContext(ctx).
+		Where("diary_entry_id = ?", entryID).
+		Order("revision DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&revisions).Error
+	if err != nil {
+		return nil, fmt.Errorf("list diary entry revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+func (r *Repository) CountDiaryEntryRevisions(ctx context.Context, entryID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&DiaryEntryRevision{}).Where("diary_entry_id = ?", entryID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("count diary entry revisions: %w", err)
+	}
+	return count, nil
+}
+
+func (r *Repository) GetDiaryEntryRevision(ctx context.Context, entryID uint, revision int) (*DiaryEntryRevision, error) {
+	var entry DiaryEntryRevision
+	err := r.db.WithContext(ctx).
+		Where("diary_entry_id = ? AND revision = ?", entryID, revision).
+		First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: diary entry revision not found", core.ErrItemNotFound)
+		}
+		return nil, fmt.Errorf("get diary entry revision: %w", err)
+	}
+	return &entry, nil
+}
+
 func (r *Repository) ListMoodRecordsByIDs(ctx context.Context, userID uint, ids []uint) ([]MoodRecord, error) {
 	if len(ids) == 0 {
 		return []MoodRecord{}, nil
@@ -175,17 +504,506 @@ func (r *Repository) ListMoodRecordsByIDs(ctx context.Context, userID uint, ids
 	return entries, nil
 }
 
+func (r *Repository) ListDiaryEntriesByIDs(ctx context.Context, userID uint, ids []uint) ([]DiaryEntry, error) {
+	if len(ids) == 0 {
+		return []DiaryEntry{}, nil
+	}
+
+	var entries []DiaryEntry
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("id IN ?", ids).
+		Order("id ASC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("list referenced diary entries: %w", err)
+	}
+	return entries, nil
+}
+
 func (r *Repository) DeleteDiaryEntry(ctx context.Context, filter *DiaryEntryFilter) (*DiaryEntry, error) {
 	var entry DiaryEntry
-	q := filter.Apply(r.db.WithContext(ctx)).First(&entry)
-	if err := q.Error; err != nil {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := filter.Apply(tx).First(&entry).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: diary entry not found", core.ErrItemNotFound)
+			}
+			return fmt.Errorf("find diary entry to delete: %w", err)
+		}
+		if err := r.checkVersion(tx, "diary_entries", entry.ID); err != nil {
+			return err
+		}
+		if err := tx.Delete(&entry).Error; err != nil {
+			return fmt.Errorf("delete diary entry: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *Repository) PurgeDiaryEntries(ctx context.Context, filter *DiaryEntryFilter) ([]uint, []Attachment, error) {
+	var ids []uint
+	var attachments []Attachment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := filter.Apply(tx.Model(&DiaryEntry{})).Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("find diary entries to purge: %w", err)
+		}
+		if err := r.checkVersion(tx, "diary_entries", ids...); err != nil {
+			return err
+		}
+		for batch := range slices.Chunk(ids, purgeBatchSize) {
+			var batchAttachments []Attachment
+			if err := tx.Where("diary_entry_id IN ?", batch).Find(&batchAttachments).Error; err != nil {
+				return fmt.Errorf("find diary entry attachments to purge: %w", err)
+			}
+			if err := purgeDiaryEntries(tx, batch); err != nil {
+				return err
+			}
+			attachments = append(attachments, batchAttachments...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ids, attachments, nil
+}
+
+func (r *Repository) Search(ctx context.Context, filter *SearchFilter, limit, offset int) ([]SearchResult, error) {
+	db := r.db.WithContext(ctx)
+
+	query := filter.Apply(db.Table(searchIndexTable))
+	if isPostgres(db) {
+		tsQuery := postgresSearchQuery(filter.Query)
+		query = query.Select(
+			"record_type, record_id, "+
+				"ts_headline('simple', title, to_tsquery('simple', ?), ?) AS title, "+
+				"ts_headline('simple', body, to_tsquery('simple', ?), ?) AS snippet, "+
+				"-ts_rank('{0, 0, 1.0, 4.0}', document, to_tsquery('simple', ?)) AS score",
+			tsQuery, fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true", searchHighlightStart, searchHighlightEnd),
+			tsQuery, fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=%d, MinWords=%d, MaxFragments=1, FragmentDelimiter=%q",
+				searchHighlightStart, searchHighlightEnd, searchSnippetTokens, searchSnippetTokens/2, searchSnippetEllipsis),
+			tsQuery,
+		)
+	} else {
+		query = query.Select(
+			"record_type, record_id, "+
+				"highlight("+searchIndexTable+", 3, ?, ?) AS title, "+
+				"snippet("+searchIndexTable+", 4, ?, ?, ?, ?) AS snippet, "+
+				"bm25("+searchIndexTable+", 0, 0, 0, 4.0, 1.0) AS score",
+			searchHighlightStart, searchHighlightEnd,
+			searchHighlightStart, searchHighlightEnd, searchSnippetEllipsis, searchSnippetTokens,
+		)
+	}
+
+	var results []SearchResult
+	err := query.
+		Order("score").
+		Limit(limit).
+		Offset(offset).
+		Scan(&results).Error
+	if err != nil {
+		return nil, fmt.Errorf("search journal: %w", err)
+	}
+	if err := r.loadSearchRecords(ctx, filter, results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *Repository) CountSearchResults(ctx context.Context, filter *SearchFilter) (int64, error) {
+	db := r.db.WithContext(ctx)
+
+	var count int64
+	if err := filter.Apply(db.Table(searchIndexTable)).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count search results: %w", err)
+	}
+	return count, nil
+}
+
+func (r *Repository) loadSearchRecords(ctx context.Context, filter *SearchFilter, results []SearchResult) error {
+	var moodRecordIDs, diaryEntryIDs []uint
+	for _, result := range results {
+		switch result.RecordType {
+		case SearchRecordTypeMoodRecord:
+			moodRecordIDs = append(moodRecordIDs, result.RecordID)
+		case SearchRecordTypeDiaryEntry:
+			diaryEntryIDs = append(diaryEntryIDs, result.RecordID)
+		}
+	}
+
+	moodRecords := make(map[uint]*MoodRecord, len(moodRecordIDs))
+	if len(moodRecordIDs) > 0 {
+		var entries []MoodRecord
+		err := filter.MoodRecordFilter().Apply(r.db.WithContext(ctx)).
+			Where("id IN ?", moodRecordIDs).
+			Find(&entries).Error
+		if err != nil {
+			return fmt.Errorf("load mood record search results: %w", err)
+		}
+		for index := range entries {
+			moodRecords[entries[index].ID] = &entries[index]
+		}
+	}
+
+	diaryEntries := make(map[uint]*DiaryEntry, len(diaryEntryIDs))
+	if len(diaryEntryIDs) > 0 {
+		var entries []DiaryEntry
+		err := filter.DiaryEntryFilter().Apply(r.db.WithContext(ctx)).
+			Where("id IN ?", diaryEntryIDs).
+			Find(&entries).Error
+		if err != nil {
+			return fmt.Errorf("load diary entry search results: %w", err)
+		}
+		for index := range entries {
+			diaryEntries[entries[index].ID] = &entries[index]
+		}
+	}
+
+	for index := range results {
+		switch results[index].RecordType {
+		case SearchRecordTypeMoodRecord:
+			results[index].MoodRecord = moodRecords[results[index].RecordID]
+		case SearchRecordTypeDiaryEntry:
+			results[index].DiaryEntry = diaryEntries[results[index].RecordID]
+		}
+	}
+	return nil
+}
+
+func (r *Repository) ListTags(ctx context.Context, filter *TagFilter, limit, offset int) ([]TagUsage, error) {
+	var tags []TagUsage
+	err := filter.Apply(tagUsageQuery(r.db.WithContext(ctx))).
+		Order("tags.name ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&tags).Error
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (r *Repository) CountTags(ctx context.Context, filter *TagFilter) (int64, error) {
+	var count int64
+	err := filter.Apply(r.db.WithContext(ctx).Model(&Tag{})).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("count tags: %w", err)
+	}
+	return count, nil
+}
+
+func (r *Repository) GetTag(ctx context.Context, filter *TagFilter) (*TagUsage, error) {
+	var tag TagUsage
+	result := filter.Apply(tagUsageQuery(r.db.WithContext(ctx))).Limit(1).Scan(&tag)
+	if result.Error != nil {
+		return nil, fmt.Errorf("get tag: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: tag not found", core.ErrItemNotFound)
+	}
+	return &tag, nil
+}
+
+func (r *Repository) SaveTag(ctx context.Context, tag *Tag) (*Tag, error) {
+	if err := r.db.WithContext(ctx).Save(tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("%w: %s", ErrTagExists, tag.Name)
+		}
+		return nil, fmt.Errorf("save tag: %w", err)
+	}
+	return tag, nil
+}
+
+func (r *Repository) FindOrCreateTags(ctx context.Context, userID uint, names []string) ([]Tag, error) {
+	if len(names) == 0 {
+		return []Tag{}, nil
+	}
+
+	db := r.db.WithContext(ctx)
+	missing := make([]Tag, 0, len(names))
+	for _, name := range names {
+		missing = append(missing, Tag{UserID: userID, Name: name})
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&missing).Error; err != nil {
+		return nil, fmt.Errorf("create tags: %w", err)
+	}
+
+	var tags []Tag
+	err := db.
+		Where("user_id = ?", userID).
+		Where("name IN ?", names).
+		Order("name ASC").
+		Find(&tags).Error
+	if err != nil {
+		return nil, fmt.Errorf("list tags by name: %w", err)
+	}
+	return tags, nil
+}
+
+func (r *Repository) ListTaggedDiaryEntries(ctx context.Context, tagID uint) ([]DiaryEntry, error) {
+	db := r.db.WithContext(ctx)
+	tagged := db.Session(&gorm.Session{NewDB: true}).
+		Table(diaryEntryTagLink.joinTable).
+		Select(diaryEntryTagLink.recordColumn).
+		Where("tag_id = ?", tagID)
+
+	var entries []DiaryEntry
+	if err := db.Unscoped().Where("id IN (?)", tagged).Order("id ASC").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("list tagged diary entries: %w", err)
+	}
+	return entries, nil
+}
+
+func (r *Repository) UpdateDiaryEntryMarkdown(ctx context.Context, entry *DiaryEntry, markdown string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := snapshotDiaryEntryHistory(tx, entry.ID); err != nil {
+			return err
+		}
+		entry.Markdown = markdown
+		if err := tx.Unscoped().Model(entry).Update("markdown", markdown).Error; err != nil {
+			return fmt.Errorf("update diary entry markdown: %w", err)
+		}
+		if err := recordDiaryEntryRevision(tx, entry); err != nil {
+			return err
+		}
+		return indexDiaryEntry(tx, entry)
+	})
+}
+
+func (r *Repository) MergeTags(ctx context.Context, source, target *Tag) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := touchTaggedRecords(tx, source.ID); err != nil {
+			return err
+		}
+		for _, link := range []tagLink{moodRecordTagLink, diaryEntryTagLink} {
+			err := tx.Exec(
+				"INSERT INTO "+link.joinTable+" ("+link.recordColumn+", tag_id) "+
+					"SELECT "+link.recordColumn+", ? FROM "+link.joinTable+" AS source "+
+					"WHERE source.tag_id = ? AND NOT EXISTS ("+
+					"SELECT 1 FROM "+link.joinTable+" AS target "+
+					"WHERE target.tag_id = ? AND target."+link.recordColumn+" = source."+link.recordColumn+")",
+				target.ID, source.ID, target.ID,
+			).Error
+			if err != nil {
+				return fmt.Errorf("move %s: %w", link.joinTable, err)
+			}
+		}
+		return deleteTag(tx, source.ID)
+	})
+}
+
+func (r *Repository) DeleteTag(ctx context.Context, tag *Tag) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := touchTaggedRecords(tx, tag.ID); err != nil {
+			return err
+		}
+		return deleteTag(tx, tag.ID)
+	})
+}
+
+func (r *Repository) TouchTaggedRecords(ctx context.Context, tagID uint) error {
+	return touchTaggedRecords(r.db.WithContext(ctx), tagID)
+}
+
+func touchTaggedRecords(db *gorm.DB, tagID uint) error {
+	now := db.NowFunc()
+	for _, link := range []tagLink{moodRecordTagLink, diaryEntryTagLink} {
+		tagged := db.Session(&gorm.Session{NewDB: true}).
+			Table(link.joinTable).
+			Select(link.recordColumn).
+			Where("tag_id = ?", tagID)
+		err := db.Session(&gorm.Session{NewDB: true}).
+			Table(link.recordTable).
+			Where("id IN (?)", tagged).
+			UpdateColumn("updated_at", now).Error
+		if err != nil {
+			return fmt.Errorf("touch tagged %s: %w", link.recordTable, err)
+		}
+	}
+	return nil
+}
+
+func deleteTag(db *gorm.DB, tagID uint) error {
+	for _, link := range []tagLink{moodRecordTagLink, diaryEntryTagLink} {
+		if err := db.Exec("DELETE FROM "+link.joinTable+" WHERE tag_id = ?", tagID).Error; err != nil {
+			return fmt.Errorf("delete %s: %w", link.joinTable, err)
+		}
+	}
+	if err := db.Delete(&Tag{}, tagID).Error; err != nil {
+		return fmt.Errorf("delete tag: %w", err)
+	}
+	return nil
+}
+
+func tagUsageQuery(db *gorm.DB) *gorm.DB {
+	scope := db.Session(&gorm.Session{NewDB: true})
+	counts := make([]any, 0, 2)
+	for _, link := range []tagLink{moodRecordTagLink, diaryEntryTagLink} {
+		counts = append(counts, scope.
+			Table(link.joinTable).
+			Select("COUNT(*)").
+			Joins("JOIN "+link.recordTable+" ON "+link.recordTable+".id = "+link.joinTable+"."+link.recordColumn).
+			Where(link.joinTable+".tag_id = tags.id").
+			Where(link.recordTable+".deleted_at IS NULL"))
+	}
+	return db.Model(&Tag{}).Select("tags.*, (?) AS mood_record_count, (?) AS diary_entry_count", counts...)
+}
+
+func linkedDiaryEntriesPreload(db *gorm.DB) *gorm.DB {
+	return db.
+		Where("diary_entries.deleted_at IS NULL").
+		Order("diary_entries.occurred_at DESC").
+		Order("diary_entries.created_at DESC")
+}
+
+func orderTagsByName(db *gorm.DB) *gorm.DB {
+	return db.Order("tags.name ASC")
+}
+
+func (r *Repository) GetAttachment(ctx context.Context, filter *AttachmentFilter) (*Attachment, error) {
+	var attachment Attachment
+	if err := filter.Apply(r.db.WithContext(ctx)).First(&attachment).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("%w: diary entry not found", core.ErrItemNotFound)
+			return nil, fmt.Errorf("%w: attachment not found", core.ErrItemNotFound)
 		}
-		return nil, fmt.Errorf("find diary entry to delete: %w", err)
+		return nil, fmt.Errorf("get attachment: %w", err)
 	}
-	if err := r.db.WithContext(ctx).Delete(&entry).Error; err != nil {
-		return nil, fmt.Errorf("delete diary entry: %w", err)
+	return &attachment, nil
+}
+
+func (r *Repository) ListAttachments(ctx context.Context, filter *AttachmentFilter) ([]Attachment, error) {
+	var attachments []Attachment
+	if err := orderAttachments(filter.Apply(r.db.WithContext(ctx))).Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("list attachments: %w", err)
 	}
-	return &entry, nil
+	return attachments, nil
+}
+
+func (r *Repository) CountAttachmentsByIDs(ctx context.Context, filter *AttachmentFilter, ids []uint) (int64, error) {
+	var count int64
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := filter.Apply(r.db.WithContext(ctx).Model(&Attachment{})).Where("attachments.id IN ?", ids).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count attachments: %w", err)
+	}
+	return count, nil
+}
+
+func (r *Repository) SumAttachmentSizes(ctx context.Context, userID uint) (int64, error) {
+	return sumAttachmentSizes(r.db.WithContext(ctx), userID)
+}
+
+func (r *Repository) SaveAttachment(ctx context.Context, attachment *Attachment, quota int64) (*Attachment, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE users SET updated_at = updated_at WHERE id = ?", attachment.UserID).Error; err != nil {
+			return fmt.Errorf("lock attachment quota: %w", err)
+		}
+		used, err := sumAttachmentSizes(tx, attachment.UserID)
+		if err != nil {
+			return err
+		}
+		if used+attachment.Size > quota {
+			return ErrAttachmentQuota
+		}
+		if err := tx.Create(attachment).Error; err != nil {
+			return fmt.Errorf("save attachment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (r *Repository) DeleteAttachment(ctx context.Context, attachment *Attachment) error {
+	if err := r.db.WithContext(ctx).Delete(attachment).Error; err != nil {
+		return fmt.Errorf("delete attachment: %w", err)
+	}
+	return nil
+}
+
+func sumAttachmentSizes(db *gorm.DB, userID uint) (int64, error) {
+	var used int64
+	err := db.Model(&Attachment{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&used).Error
+	if err != nil {
+		return 0, fmt.Errorf("sum attachment sizes: %w", err)
+	}
+	return used, nil
+}
+
+func orderAttachments(db *gorm.DB) *gorm.DB {
+	return db.Order("attachments.created_at ASC").Order("attachments.id ASC")
+}
+
+func (r *Repository) checkVersion(tx *gorm.DB, table string, ids ...uint) error {
+	if r.versions == nil || len(ids) == 0 {
+		return nil
+	}
+	result := tx.Table(table).
+		Where("id IN ? AND updated_at IN ?", ids, r.versions).
+		UpdateColumn("updated_at", gorm.Expr("updated_at"))
+	if result.Error != nil {
+		return fmt.Errorf("check %s version: %w", table, result.Error)
+	}
+	if result.RowsAffected != int64(len(ids)) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+func purgeMoodRecords(tx *gorm.DB, ids []uint) error {
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{name: "mood record diary links", query: "DELETE FROM mood_record_diary_entries WHERE mood_record_id IN ?"},
+		{name: "mood record tags", query: "DELETE FROM mood_record_tags WHERE mood_record_id IN ?"},
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement.query, ids).Error; err != nil {
+			return fmt.Errorf("purge %s: %w", statement.name, err)
+		}
+	}
+	if err := removeSearchDocuments(tx, SearchRecordTypeMoodRecord, ids); err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("id IN ?", ids).Delete(&MoodRecord{}).Error; err != nil {
+		return fmt.Errorf("purge mood records: %w", err)
+	}
+	return nil
+}
+
+func purgeDiaryEntries(tx *gorm.DB, ids []uint) error {
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{name: "diary entry mood links", query: "DELETE FROM mood_record_diary_entries WHERE diary_entry_id IN ?"},
+		{name: "diary entry tags", query: "DELETE FROM diary_entry_tags WHERE diary_entry_id IN ?"},
+		{name: "diary entry links", query: "DELETE FROM diary_entry_links WHERE source_id IN ?"},
+		{name: "diary entry backlinks", query: "DELETE FROM diary_entry_links WHERE target_id IN ?"},
+		{name: "diary entry revisions", query: "DELETE FROM diary_entry_revisions WHERE diary_entry_id IN ?"},
+		{name: "diary entry attachments", query: "DELETE FROM attachments WHERE diary_entry_id IN ?"},
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement.query, ids).Error; err != nil {
+			return fmt.Errorf("purge %s: %w", statement.name, err)
+		}
+	}
+	if err := removeSearchDocuments(tx, SearchRecordTypeDiaryEntry, ids); err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("id IN ?", ids).Delete(&DiaryEntry{}).Error; err != nil {
+		return fmt.Errorf("purge diary entries: %w", err)
+	}
+	return nil
 }
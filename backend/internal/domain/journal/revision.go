@@ -0,0 +1,163 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const maxDiffCells = 4_000_000
+
+type DiffOperation string
+
+const (
+	DiffOperationEqual  DiffOperation = "equal"
+	DiffOperationInsert DiffOperation = "insert"
+	DiffOperationDelete DiffOperation = "delete"
+)
+
+type DiffLine struct {
+	Operation DiffOperation `json:"op"`
+	Text      string        `json:"text"`
+	OldLine   int           `json:"old_line,omitempty"`
+	NewLine   int           `json:"new_line,omitempty"`
+}
+
+type DiaryEntryRevisionDiff struct {
+	FromRevision int        `json:"from_revision"`
+	ToRevision   int        `json:"to_revision"`
+	FromTitle    string     `json:"from_title"`
+	ToTitle      string     `json:"to_title"`
+	Lines        []DiffLine `json:"lines"`
+}
+
+func NewDiaryEntryRevisionDiff(from, to *DiaryEntryRevision) DiaryEntryRevisionDiff {
+	return DiaryEntryRevisionDiff{
+		FromRevision: from.Revision,
+		ToRevision:   to.Revision,
+		FromTitle:    from.Title,
+		ToTitle:      to.Title,
+		Lines:        DiffLines(from.Markdown, to.Markdown),
+	}
+}
+
+func DiffLines(oldText, newText string) []DiffLine {
+	oldLines := splitDiffLines(oldText)
+	newLines := splitDiffLines(newText)
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]DiffLine, 0, max(len(oldLines), len(newLines)))
+	for i := range prefix {
+		result = append(result, DiffLine{Operation: DiffOperationEqual, Text: oldLines[i], OldLine: i + 1, NewLine: i + 1})
+	}
+	result = append(result, diffMiddle(oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix], prefix, prefix)...)
+	for i := range suffix {
+		oldIndex := len(oldLines) - suffix + i
+		newIndex := len(newLines) - suffix + i
+		result = append(result, DiffLine{Operation: DiffOperationEqual, Text: oldLines[oldIndex], OldLine: oldIndex + 1, NewLine: newIndex + 1})
+	}
+	return result
+}
+
+func diffMiddle(oldLines, newLines []string, oldOffset, newOffset int) []DiffLine {
+	var result []DiffLine
+	if len(oldLines)*len(newLines) > maxDiffCells {
+		for i, line := range oldLines {
+			result = append(result, DiffLine{Operation: DiffOperationDelete, Text: line, OldLine: oldOffset + i + 1})
+		}
+		for i, line := range newLines {
+			result = append(result, DiffLine{Operation: DiffOperationInsert, Text: line, NewLine: newOffset + i + 1})
+		}
+		return result
+	}
+
+	width := len(newLines) + 1
+	lengths := make([]int32, (len(oldLines)+1)*width)
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lengths[i*width+j] = lengths[(i+1)*width+j+1] + 1
+			} else {
+				lengths[i*width+j] = max(lengths[(i+1)*width+j], lengths[i*width+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			result = append(result, DiffLine{Operation: DiffOperationEqual, Text: oldLines[i], OldLine: oldOffset + i + 1, NewLine: newOffset + j + 1})
+			i++
+			j++
+		case i < len(oldLines) && (j == len(newLines) || lengths[(i+1)*width+j] >= lengths[i*width+j+1]):
+			result = append(result, DiffLine{Operation: DiffOperationDelete, Text: oldLines[i], OldLine: oldOffset + i + 1})
+			i++
+		default:
+			result = append(result, DiffLine{Operation: DiffOperationInsert, Text: newLines[j], NewLine: newOffset + j + 1})
+			j++
+		}
+	}
+	return result
+}
+
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+func snapshotDiaryEntryHistory(db *gorm.DB, entryID uint) error {
+	var count int64
+	if err := db.Model(&DiaryEntryRevision{}).Where("diary_entry_id = ?", entryID).Count(&count).Error; err != nil {
+		return fmt.Errorf("count diary entry revisions: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var stored DiaryEntry
+	if err := db.Unscoped().First(&stored, entryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("load diary entry for history: %w", err)
+	}
+	return recordDiaryEntryRevision(db, &stored)
+}
+
+func recordDiaryEntryRevision(db *gorm.DB, entry *DiaryEntry) error {
+	var latest DiaryEntryRevision
+	err := db.Where("diary_entry_id = ?", entry.ID).Order("revision DESC").Limit(1).Find(&latest).Error
+	if err != nil {
+		return fmt.Errorf("load latest diary entry revision: %w", err)
+	}
+	if latest.ID != 0 && latest.Title == entry.Title && latest.Markdown == entry.Markdown && latest.OccurredAt.Equal(entry.OccurredAt) {
+		return nil
+	}
+
+	revision := DiaryEntryRevision{
+		DiaryEntryID: entry.ID,
+		Revision:     latest.Revision + 1,
+		Title:        entry.Title,
+		Markdown:     entry.Markdown,
+		OccurredAt:   entry.OccurredAt,
+		CreatedAt:    entry.UpdatedAt,
+	}
+	if err := db.Create(&revision).Error; err != nil {
+		return fmt.Errorf("save diary entry revision: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package journal_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestDiffLines(t *testing.T) {
+	got := journal.DiffLines("title\nkeep\nold\ntail\n", "title\nnew\nkeep\ntail")
+	want := []journal.DiffLine{
+		{Operation: journal.DiffOperationEqual, Text: "title", OldLine: 1, NewLine: 1},
+		{Operation: journal.DiffOperationInsert, Text: "new", NewLine: 2},
+		{Operation: journal.DiffOperationEqual, Text: "keep", OldLine: 2, NewLine: 3},
+		{Operation: journal.DiffOperationDelete, Text: "old", OldLine: 3},
+		{Operation: journal.DiffOperationEqual, Text: "tail", OldLine: 4, NewLine: 4},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("DiffLines() = %+v, want %+v", got, want)
+	}
+
+	if got := journal.DiffLines("", "added"); !slices.Equal(got, []journal.DiffLine{{Operation: journal.DiffOperationInsert, Text: "added", NewLine: 1}}) {
+		t.Fatalf("DiffLines() from empty = %+v", got)
+	}
+	if got := journal.DiffLines("same", "same"); len(got) != 1 || got[0].Operation != journal.DiffOperationEqual {
+		t.Fatalf("DiffLines() unchanged = %+v", got)
+	}
+}
+
+func TestServiceDiaryEntryRevisions(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	other := createJournalUser(t, environment, "other")
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	calm := saveMoodRecord(t, environment, owner.ID, "calm", occurredAt)
+	tired := saveMoodRecord(t, environment, owner.ID, "tired", occurredAt)
+
+	entry, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest(fmt.Sprintf("first\n[[mood:%d]]", calm.ID), &occurredAt))
+	if err != nil {
+		t.Fatalf("CreateDiaryEntry() error = %v", err)
+	}
+	if _, err := environment.service.UpdateDiaryEntry(t.Context(), owner.ID, entry.ID, diaryRequest(fmt.Sprintf("second\n[[mood:%d]]", tired.ID), &occurredAt)); err != nil {
+		t.Fatalf("UpdateDiaryEntry() error = %v", err)
+	}
+	if _, err := environment.service.UpdateDiaryEntry(t.Context(), owner.ID, entry.ID, diaryRequest(fmt.Sprintf("second\n[[mood:%d]]", tired.ID), &occurredAt)); err != nil {
+		t.Fatalf("unchanged UpdateDiaryEntry() error = %v", err)
+	}
+
+	page, err := environment.service.ListDiaryEntryRevisions(t.Context(), owner.ID, entry.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("ListDiaryEntryRevisions() error = %v", err)
+	}
+	if page.TotalCount != 2 || len(page.Items) != 2 || page.Items[0].Revision != 2 || page.Items[1].Revision != 1 {
+		t.Fatalf("revisions = %+v total %d, want revisions 2 and 1", page.Items, page.TotalCount)
+	}
+	if _, err := environment.service.ListDiaryEntryRevisions(t.Context(), other.ID, entry.ID, 10, 0); !errors.Is(err, core.ErrItemNotFound) {
+		t.Fatalf("foreign ListDiaryEntryRevisions() error = %v, want ErrItemNotFound", err)
+	}
+
+	diff, err := environment.service.DiffDiaryEntryRevisions(t.Context(), owner.ID, entry.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("DiffDiaryEntryRevisions() error = %v", err)
+	}
+	if len(diff.Lines) != 4 || diff.Lines[0].Operation != journal.DiffOperationDelete || diff.Lines[0].Text != "first" {
+		t.Fatalf("diff lines = %+v, want first two lines replaced", diff.Lines)
+	}
+
+	restored, err := environment.service.RestoreDiaryEntryRevision(t.Context(), owner.ID, entry.ID, 1)
+	if err != nil {
+		t.Fatalf("RestoreDiaryEntryRevision() error = %v", err)
+	}
+	if restored.Markdown != fmt.Sprintf("first\n[[mood:%d]]", calm.ID) || !slices.Equal(moodRecordIDs(restored.MoodRecords), []uint{calm.ID}) {
+		t.Fatalf("restored entry = %q linked to %v, want first revision linked to %d", restored.Markdown, moodRecordIDs(restored.MoodRecords), calm.ID)
+	}
+	latest, err := environment.service.GetDiaryEntryRevision(t.Context(), owner.ID, entry.ID, 3)
+	if err != nil || latest.Markdown != restored.Markdown {
+		t.Fatalf("revision after restore = %+v, %v", latest, err)
+	}
+
+	if _, err := environment.service.DeleteMoodRecord(t.Context(), owner.ID, tired.ID); err != nil {
+		t.Fatalf("delete mood record: %v", err)
+	}
+	if _, err := environment.service.RestoreDiaryEntryRevision(t.Context(), owner.ID, entry.ID, 2); !errors.Is(err, core.ErrInvalidItem) {
+		t.Fatalf("RestoreDiaryEntryRevision() with deleted mood error = %v, want ErrInvalidItem", err)
+	}
+	if _, err := environment.service.GetDiaryEntryRevision(t.Context(), owner.ID, entry.ID, 9); !errors.Is(err, core.ErrItemNotFound) {
+		t.Fatalf("GetDiaryEntryRevision() missing error = %v, want ErrItemNotFound", err)
+	}
+}
+
+func TestServiceDiaryEntryRevisionsKeepContentWrittenBeforeHistory(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	legacy := &journal.DiaryEntry{UserID: owner.ID, Markdown: "written before history", OccurredAt: occurredAt}
+	if err := environment.database.Create(legacy).Error; err != nil {
+		t.Fatalf("create legacy diary entry: %v", err)
+	}
+
+	if _, err := environment.service.UpdateDiaryEntry(t.Context(), owner.ID, legacy.ID, diaryRequest("edited", &occurredAt)); err != nil {
+		t.Fatalf("UpdateDiaryEntry() error = %v", err)
+	}
+	original, err := environment.service.GetDiaryEntryRevision(t.Context(), owner.ID, legacy.ID, 1)
+	if err != nil || original.Markdown != "written before history" {
+		t.Fatalf("first revision = %+v, %v, want the content from before the update", original, err)
+	}
+}
+
+func TestDiaryEntryRevisionsHTTPContract(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	entry, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("one", &occurredAt))
+	if err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+	if _, err := environment.service.UpdateDiaryEntry(t.Context(), owner.ID, entry.ID, diaryRequest("two", &occurredAt)); err != nil {
+		t.Fatalf("update diary entry: %v", err)
+	}
+	base := fmt.Sprintf("/api/journal/diary-entries/%d/revisions", entry.ID)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{method: http.MethodGet, path: base, want: http.StatusOK},
+		{method: http.MethodGet, path: base + "/1", want: http.StatusOK},
+		{method: http.MethodGet, path: base + "/9", want: http.StatusNotFound},
+		{method: http.MethodGet, path: base + "/first", want: http.StatusBadRequest},
+		{method: http.MethodGet, path: base + "/diff?from=1&to=2", want: http.StatusOK},
+		{method: http.MethodGet, path: base + "/diff?from=1", want: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/api/journal/diary-entries/999/revisions", want: http.StatusNotFound},
+		{method: http.MethodPost, path: base + "/1/restore", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		response := serveJournalJSON(t, e, tt.method, tt.path, nil, ownerCookie)
+		if response.Code != tt.want {
+			t.Fatalf("%s %s status = %d, want %d", tt.method, tt.path, response.Code, tt.want)
+		}
+	}
+
+	response := serveJournalJSON(t, e, http.MethodGet, base, nil, ownerCookie)
+	var page core.Page[journal.DiaryEntryRevision]
+	decodeJournalResponse(t, response, &page)
+	if page.TotalCount != 3 || page.Items[0].Markdown != "one" {
+		t.Fatalf("revisions after restore = %+v, want three with the restored text first", page)
+	}
+}
@@ -0,0 +1,135 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	searchIndexTable      = "journal_search"
+	searchHighlightStart  = "\x02"
+	searchHighlightEnd    = "\x03"
+	searchSnippetEllipsis = "..."
+	searchSnippetTokens   = 24
+)
+
+type SearchRecordType string
+
+const (
+	SearchRecordTypeMoodRecord SearchRecordType = "mood_record"
+	SearchRecordTypeDiaryEntry SearchRecordType = "diary_entry"
+)
+
+type SearchResult struct {
+	RecordType SearchRecordType
+	RecordID   uint
+	Title      string
+	Snippet    string
+	Score      float64
+	MoodRecord *MoodRecord `gorm:"-"`
+	DiaryEntry *DiaryEntry `gorm:"-"`
+}
+
+var searchTermPattern = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+
+func BuildSearchQuery(text string) (string, error) {
+	terms := searchTermPattern.FindAllString(text, -1)
+	if len(terms) == 0 {
+		return "", errors.New("search query has no searchable terms")
+	}
+
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+term+`"*`)
+	}
+	return strings.Join(quoted, " "), nil
+}
+
+func postgresSearchQuery(query string) string {
+	terms := searchTermPattern.FindAllString(query, -1)
+	for index, term := range terms {
+		terms[index] = term + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+func ParseSearchRecordType(value string) (SearchRecordType, error) {
+	switch SearchRecordType(value) {
+	case SearchRecordTypeMoodRecord, SearchRecordTypeDiaryEntry:
+		return SearchRecordType(value), nil
+	default:
+		return "", fmt.Errorf("unknown search record type %q", value)
+	}
+}
+
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+func searchRowID(recordType SearchRecordType, recordID uint) int64 {
+	rowID := int64(recordID) << 1
+	if recordType == SearchRecordTypeDiaryEntry {
+		rowID |= 1
+	}
+	return rowID
+}
+
+func indexMoodRecord(db *gorm.DB, entry *MoodRecord) error {
+	return replaceSearchDocument(db, SearchRecordTypeMoodRecord, entry.ID, entry.UserID,
+		strings.TrimSpace(entry.Feeling),
+		whitespacePattern.ReplaceAllString(strings.TrimSpace(entry.Note), " "),
+	)
+}
+
+func indexDiaryEntry(db *gorm.DB, entry *DiaryEntry) error {
+	return replaceSearchDocument(db, SearchRecordTypeDiaryEntry, entry.ID, entry.UserID,
+		strings.TrimSpace(entry.Title),
+		markdownPlainText(entry.Markdown),
+	)
+}
+
+func replaceSearchDocument(db *gorm.DB, recordType SearchRecordType, recordID, userID uint, title, body string) error {
+	if err := removeSearchDocuments(db, recordType, []uint{recordID}); err != nil {
+		return err
+	}
+	var err error
+	if isPostgres(db) {
+		err = db.Exec(
+			"INSERT INTO "+searchIndexTable+" (record_type, record_id, user_id, title, body) VALUES (?, ?, ?, ?, ?)",
+			recordType, recordID, userID, title, body,
+		).Error
+	} else {
+		err = db.Exec(
+			"INSERT INTO "+searchIndexTable+" (rowid, record_type, record_id, user_id, title, body) VALUES (?, ?, ?, ?, ?, ?)",
+			searchRowID(recordType, recordID), recordType, recordID, userID, title, body,
+		).Error
+	}
+	if err != nil {
+		return fmt.Errorf("index %s %d: %w", recordType, recordID, err)
+	}
+	return nil
+}
+
+func removeSearchDocuments(db *gorm.DB, recordType SearchRecordType, recordIDs []uint) error {
+	var err error
+	if isPostgres(db) {
+		err = db.Exec(
+			"DELETE FROM "+searchIndexTable+" WHERE record_type = ? AND record_id IN ?",
+			recordType, recordIDs,
+		).Error
+	} else {
+		rowIDs := make([]int64, len(recordIDs))
+		for index, recordID := range recordIDs {
+			rowIDs[index] = searchRowID(recordType, recordID)
+		}
+		err = db.Exec("DELETE FROM "+searchIndexTable+" WHERE rowid IN ?", rowIDs).Error
+	}
+	if err != nil {
+		return fmt.Errorf("remove %s records from search index: %w", recordType, err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package journal_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestBuildSearchQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "single term", text: "calm", want: `"calm"*`},
+		{name: "operators are quoted", text: `quiet OR "NEAR(x)" -morning`, want: `"quiet"* "OR"* "NEAR"* "x"* "morning"*`},
+		{name: "unicode letters", text: "  тихое утро ", want: `"тихое"* "утро"*`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := journal.BuildSearchQuery(tt.text)
+			if err != nil {
+				t.Fatalf("BuildSearchQuery() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("BuildSearchQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := journal.BuildSearchQuery(` "*" `); err == nil {
+		t.Fatal("BuildSearchQuery() error = nil for a query without terms")
+	}
+}
+
+func TestServiceSearchRanksAndScopesResults(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	other := createJournalUser(t, environment, "other")
+	occurredAt := time.Date(2026, time.January, 2, 9, 0, 0, 0, time.UTC)
+
+	mood, err := environment.service.CreateMoodRecord(t.Context(), owner.ID, journal.MoodEditRecordRequest{
+		Feeling: "anxious",
+		Note:    "before the harbour meeting",
+	})
+	if err != nil {
+		t.Fatalf("create mood record: %v", err)
+	}
+	titled, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, journal.DiaryEditEntryRequest{
+		Title:      "Harbour walk",
+		Markdown:   fmt.Sprintf("# Morning\n\nWalked along the **harbour** feeling [[mood:%d|uneasy]].", mood.ID),
+		OccurredAt: &occurredAt,
+	})
+	if err != nil {
+		t.Fatalf("create titled diary entry: %v", err)
+	}
+	untitled, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("A long day, the harbour was far away.", &occurredAt))
+	if err != nil {
+		t.Fatalf("create untitled diary entry: %v", err)
+	}
+	deleted, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("Deleted harbour notes", &occurredAt))
+	if err != nil {
+		t.Fatalf("create deleted diary entry: %v", err)
+	}
+	if _, err := environment.service.DeleteDiaryEntry(t.Context(), owner.ID, deleted.ID); err != nil {
+		t.Fatalf("delete diary entry: %v", err)
+	}
+	if _, err := environment.service.CreateDiaryEntry(t.Context(), other.ID, diaryRequest("Foreign harbour", &occurredAt)); err != nil {
+		t.Fatalf("create foreign diary entry: %v", err)
+	}
+
+	page, err := environment.service.Search(t.Context(), owner.ID, "harb", "", 10, 0, false)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if page.TotalCount != 3 || len(page.Items) != 3 {
+		t.Fatalf("Search() returned %d items total %d, want 3 and 3", len(page.Items), page.TotalCount)
+	}
+	if first := page.Items[0]; first.RecordType != journal.SearchRecordTypeDiaryEntry || first.RecordID != titled.ID {
+		t.Fatalf("top result = %s %d, want titled diary entry %d", first.RecordType, first.RecordID, titled.ID)
+	}
+	for _, result := range page.Items {
+		if result.MoodRecord == nil && result.DiaryEntry == nil {
+			t.Fatalf("result %s %d has no loaded record", result.RecordType, result.RecordID)
+		}
+	}
+
+	response := journal.NewSearchResultPageResponse(page)
+	snippet := searchFragmentsText(response.Items[0].Snippet)
+	if strings.ContainsAny(snippet, "#*[]") || !strings.Contains(snippet, "feeling uneasy") {
+		t.Fatalf("top snippet = %q, want preview text without Markdown syntax", snippet)
+	}
+	if !hasHighlightedFragment(response.Items[0].Title, "Harbour") || !hasHighlightedFragment(response.Items[0].Snippet, "harbour") {
+		t.Fatal("top result does not highlight the matched term")
+	}
+
+	moodPage, err := environment.service.Search(t.Context(), owner.ID, "harbour", journal.SearchRecordTypeMoodRecord, 10, 0, false)
+	if err != nil {
+		t.Fatalf("Search() mood records error = %v", err)
+	}
+	if moodPage.TotalCount != 1 || moodPage.Items[0].RecordID != mood.ID || moodPage.Items[0].MoodRecord == nil {
+		t.Fatal("mood-only search did not return the mood record")
+	}
+
+	deletedPage, err := environment.service.Search(t.Context(), owner.ID, "harbour", "", 10, 0, true)
+	if err != nil {
+		t.Fatalf("Search() deleted error = %v", err)
+	}
+	if deletedPage.TotalCount != 1 || deletedPage.Items[0].RecordID != deleted.ID {
+		t.Fatal("deleted search did not return only the deleted diary entry")
+	}
+
+	secondPage, err := environment.service.Search(t.Context(), owner.ID, "harbour", "", 1, 1, false)
+	if err != nil {
+		t.Fatalf("Search() second page error = %v", err)
+	}
+	if secondPage.TotalCount != 3 || len(secondPage.Items) != 1 {
+		t.Fatalf("second page length = %d total = %d, want 1 and 3", len(secondPage.Items), secondPage.TotalCount)
+	}
+
+	if _, err := environment.service.UpdateDiaryEntry(t.Context(), owner.ID, untitled.ID, diaryRequest("Lighthouse only", &occurredAt)); err != nil {
+		t.Fatalf("update diary entry: %v", err)
+	}
+	updatedPage, err := environment.service.Search(t.Context(), owner.ID, "lighthouse", "", 10, 0, false)
+	if err != nil {
+		t.Fatalf("Search() after update error = %v", err)
+	}
+	if updatedPage.TotalCount != 1 || updatedPage.Items[0].RecordID != untitled.ID {
+		t.Fatal("search index was not updated with the new diary text")
+	}
+	var documents int64
+	err = environment.database.Table("journal_search").
+		Where("record_type = ? AND record_id = ?", journal.SearchRecordTypeDiaryEntry, untitled.ID).
+		Count(&documents).Error
+	if err != nil || documents != 1 {
+		t.Fatalf("search documents after update = %d, %v, want 1", documents, err)
+	}
+
+	if _, err := environment.service.Search(t.Context(), owner.ID, " ** ", "", 10, 0, false); !errors.Is(err, core.ErrInvalidItem) {
+		t.Fatalf("Search() without terms error = %v, want ErrInvalidItem", err)
+	}
+}
+
+func TestSearchHTTPContract(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	if _, err := environment.service.CreateMoodRecord(t.Context(), owner.ID, journal.MoodEditRecordRequest{Feeling: "calm", Emoji: "🙂"}); err != nil {
+		t.Fatalf("create mood record: %v", err)
+	}
+
+	for _, path := range []string{
+		"/api/journal/search",
+		"/api/journal/search?q=%22%22",
+		"/api/journal/search?q=calm&type=everything",
+	} {
+		response := serveJournalJSON(t, e, http.MethodGet, path, nil, ownerCookie)
+		if response.Code != http.StatusBadRequest {
+			t.Fatalf("GET %s status = %d, want %d", path, response.Code, http.StatusBadRequest)
+		}
+	}
+
+	response := serveJournalJSON(t, e, http.MethodGet, "/api/journal/search?q=calm&type=mood_record", nil, ownerCookie)
+	if response.Code != http.StatusOK {
+		t.Fatalf("search status = %d, want %d", response.Code, http.StatusOK)
+	}
+	var page core.Page[journal.SearchResultResponse]
+	decodeJournalResponse(t, response, &page)
+	if page.TotalCount != 1 || len(page.Items) != 1 {
+		t.Fatalf("search page length = %d total = %d, want 1 and 1", len(page.Items), page.TotalCount)
+	}
+	if item := page.Items[0]; item.Type != journal.SearchRecordTypeMoodRecord || item.Emoji != "🙂" || !hasHighlightedFragment(item.Title, "calm") {
+		t.Fatal("search response does not contain the highlighted mood record")
+	}
+}
+
+func searchFragmentsText(fragments []journal.SearchTextFragment) string {
+	var text strings.Builder
+	for _, fragment := range fragments {
+		text.WriteString(fragment.Text)
+	}
+	return text.String()
+}
+
+func hasHighlightedFragment(fragments []journal.SearchTextFragment, text string) bool {
+	for _, fragment := range fragments {
+		if fragment.Highlighted && fragment.Text == text {
+			return true
+		}
+	}
+	return false
+}
@@ -2,26 +2,50 @@ package journal
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/metrics"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
 )
 
 type Service struct {
-	repo *Repository
+	repo         *Repository
+	storage      Storage
+	config       Config
+	renderedHTML *renderedHTMLCache
+	pendingFiles *[]string
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, storage Storage, config Config) *Service {
+	return &Service{
+		repo:         repo,
+		storage:      storage,
+		config:       config,
+		renderedHTML: newRenderedHTMLCache(renderedHTMLCacheSize),
+	}
 }
 
-func (s *Service) ListMoodRecords(ctx context.Context, userID uint, limit, offset int, deleted bool) (core.Page[MoodRecord], error) {
-	filter := NewMoodRecordFilter().WithUserID(userID)
-	if deleted {
-		filter = filter.WithDeletedMode(core.DeletedModeDeletedOnly)
+func (s *Service) inTransaction(repo *Repository) *Service {
+	return &Service{
+		repo:         repo,
+		storage:      s.storage,
+		config:       s.config,
+		renderedHTML: s.renderedHTML,
+		pendingFiles: new([]string),
 	}
+}
+
+func (s *Service) ListMoodRecords(ctx context.Context, userID uint, filter *MoodRecordFilter, limit, offset int) (core.Page[MoodRecord], error) {
+	ctx, span := tracing.Start(ctx, "journal.ListMoodRecords")
+	defer span.End()
+
+	filter = filter.WithUserID(userID)
 
 	entries, err := s.repo.ListMoodRecords(ctx, filter, limit, offset)
 	if err != nil {
@@ -37,26 +61,95 @@ func (s *Service) ListMoodRecords(ctx context.Context, userID uint, limit, offse
 	return core.Page[MoodRecord]{Items: entries, TotalCount: totalCount}, nil
 }
 
+func (s *Service) ListMoodRecordsByCursor(ctx context.Context, userID uint, filter *MoodRecordFilter, cursor string, limit int, includeTotal bool) (core.CursorPage[MoodRecord], error) {
+	ctx, span := tracing.Start(ctx, "journal.ListMoodRecordsByCursor")
+	defer span.End()
+
+	filter = filter.WithUserID(userID)
+	after, err := DecodeCursor[MoodRecordCursor](cursor)
+	if err != nil {
+		return core.CursorPage[MoodRecord]{}, err
+	}
+
+	entries, err := s.repo.ListMoodRecordsAfter(ctx, filter, after, limit+1)
+	if err != nil {
+		return core.CursorPage[MoodRecord]{}, err
+	}
+	page := core.CursorPage[MoodRecord]{Items: entries}
+	if len(entries) > limit {
+		page.Items = entries[:limit]
+		page.NextCursor = EncodeCursor(NewMoodRecordCursor(&page.Items[limit-1]))
+	}
+	if page.Items == nil {
+		page.Items = []MoodRecord{}
+	}
+	if includeTotal {
+		totalCount, err := s.repo.CountMoodRecords(ctx, filter)
+		if err != nil {
+			return core.CursorPage[MoodRecord]{}, err
+		}
+		page.TotalCount = &totalCount
+	}
+	return page, nil
+}
+
+func (s *Service) GetMoodStats(ctx context.Context, userID uint, filter *MoodRecordFilter, interval MoodStatsInterval, location *time.Location) (*MoodStats, error) {
+	ctx, span := tracing.Start(ctx, "journal.GetMoodStats")
+	defer span.End()
+
+	rows, err := s.repo.ListMoodStatsRows(ctx, filter.WithUserID(userID))
+	if err != nil {
+		return nil, err
+	}
+	return NewMoodStats(rows, interval, location, time.Now()), nil
+}
+
 func (s *Service) GetMoodRecord(ctx context.Context, userID, id uint) (*MoodRecord, error) {
+	ctx, span := tracing.Start(ctx, "journal.GetMoodRecord")
+	defer span.End()
+
 	filter := NewMoodRecordFilter().WithUserID(userID).WithID(id).WithDeletedMode(core.DeletedModeAll)
 	return s.repo.GetMoodRecord(ctx, filter)
 }
 
 func (s *Service) CreateMoodRecord(ctx context.Context, userID uint, req MoodEditRecordRequest) (*MoodRecord, error) {
-	return s.repo.SaveMoodRecord(ctx, &MoodRecord{
+	ctx, span := tracing.Start(ctx, "journal.CreateMoodRecord")
+	defer span.End()
+
+	tags, err := s.resolveTags(ctx, userID, req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.repo.SaveMoodRecord(ctx, &MoodRecord{
 		UserID:  userID,
 		Feeling: req.Feeling,
 		Emoji:   req.Emoji,
 		Note:    req.Note,
+		Tags:    tags,
 	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.CountJournalRecordsCreated(string(SearchRecordTypeMoodRecord), 1)
+	return record, nil
 }
 
 func (s *Service) UpdateMoodRecord(ctx context.Context, userID, id uint, req MoodEditRecordRequest) (*MoodRecord, error) {
+	ctx, span := tracing.Start(ctx, "journal.UpdateMoodRecord")
+	defer span.End()
+
 	filter := NewMoodRecordFilter().WithUserID(userID).WithID(id)
 	entry, err := s.repo.GetMoodRecord(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
+	if req.Tags != nil {
+		entry.Tags, err = s.resolveTags(ctx, userID, req.Tags)
+		if err != nil {
+			return nil, err
+		}
+	}
 	entry.Feeling = req.Feeling
 	entry.Emoji = req.Emoji
 	entry.Note = req.Note
@@ -64,11 +157,17 @@ func (s *Service) UpdateMoodRecord(ctx context.Context, userID, id uint, req Moo
 }
 
 func (s *Service) DeleteMoodRecord(ctx context.Context, userID, id uint) (*MoodRecord, error) {
+	ctx, span := tracing.Start(ctx, "journal.DeleteMoodRecord")
+	defer span.End()
+
 	filter := NewMoodRecordFilter().WithUserID(userID).WithID(id)
 	return s.repo.DeleteMoodRecord(ctx, filter)
 }
 
 func (s *Service) RestoreMoodRecord(ctx context.Context, userID, id uint) (*MoodRecord, error) {
+	ctx, span := tracing.Start(ctx, "journal.RestoreMoodRecord")
+	defer span.End()
+
 	filter := NewMoodRecordFilter().WithUserID(userID).WithID(id).WithDeletedMode(core.DeletedModeDeletedOnly)
 	entry, err := s.repo.GetMoodRecord(ctx, filter)
 	if err != nil {
@@ -78,11 +177,11 @@ func (s *Service) RestoreMoodRecord(ctx context.Context, userID, id uint) (*Mood
 	return s.repo.SaveMoodRecord(ctx, entry)
 }
 
-func (s *Service) ListDiaryEntries(ctx context.Context, userID uint, limit, offset int, deleted bool) (core.Page[DiaryEntry], error) {
-	filter := NewDiaryEntryFilter().WithUserID(userID)
-	if deleted {
-		filter = filter.WithDeletedMode(core.DeletedModeDeletedOnly)
-	}
+func (s *Service) ListDiaryEntries(ctx context.Context, userID uint, filter *DiaryEntryFilter, limit, offset int) (core.Page[DiaryEntry], error) {
+	ctx, span := tracing.Start(ctx, "journal.ListDiaryEntries")
+	defer span.End()
+
+	filter = filter.WithUserID(userID)
 
 	entries, err := s.repo.ListDiaryEntries(ctx, filter, limit, offset)
 	if err != nil {
@@ -98,12 +197,63 @@ func (s *Service) ListDiaryEntries(ctx context.Context, userID uint, limit, offs
 	return core.Page[DiaryEntry]{Items: entries, TotalCount: totalCount}, nil
 }
 
+func (s *Service) ListDiaryEntriesByCursor(ctx context.Context, userID uint, filter *DiaryEntryFilter, cursor string, limit int, includeTotal bool) (core.CursorPage[DiaryEntry], error) {
+	ctx, span := tracing.Start(ctx, "journal.ListDiaryEntriesByCursor")
+	defer span.End()
+
+	filter = filter.WithUserID(userID)
+	after, err := DecodeCursor[DiaryEntryCursor](cursor)
+	if err != nil {
+		return core.CursorPage[DiaryEntry]{}, err
+	}
+
+	entries, err := s.repo.ListDiaryEntriesAfter(ctx, filter, after, limit+1)
+	if err != nil {
+		return core.CursorPage[DiaryEntry]{}, err
+	}
+	page := core.CursorPage[DiaryEntry]{Items: entries}
+	if len(entries) > limit {
+		page.Items = entries[:limit]
+		page.NextCursor = EncodeCursor(NewDiaryEntryCursor(&page.Items[limit-1]))
+	}
+	if page.Items == nil {
+		page.Items = []DiaryEntry{}
+	}
+	if includeTotal {
+		totalCount, err := s.repo.CountDiaryEntries(ctx, filter)
+		if err != nil {
+			return core.CursorPage[DiaryEntry]{}, err
+		}
+		page.TotalCount = &totalCount
+	}
+	return page, nil
+}
+
 func (s *Service) GetDiaryEntry(ctx context.Context, userID, id uint) (*DiaryEntry, error) {
+	ctx, span := tracing.Start(ctx, "journal.GetDiaryEntry")
+	defer span.End()
+
 	filter := NewDiaryEntryFilter().WithUserID(userID).WithID(id).WithDeletedMode(core.DeletedModeAll)
 	return s.repo.GetDiaryEntry(ctx, filter)
 }
 
+func (s *Service) RenderDiaryEntryHTML(entry *DiaryEntry) (string, error) {
+	if html, ok := s.renderedHTML.get(entry.ID, entry.UpdatedAt); ok {
+		return html, nil
+	}
+
+	html, err := RenderMarkdownHTML(entry.Markdown)
+	if err != nil {
+		return "", err
+	}
+	s.renderedHTML.put(entry.ID, entry.UpdatedAt, html)
+	return html, nil
+}
+
 func (s *Service) CreateDiaryEntry(ctx context.Context, userID uint, req DiaryEditEntryRequest) (*DiaryEntry, error) {
+	ctx, span := tracing.Start(ctx, "journal.CreateDiaryEntry")
+	defer span.End()
+
 	title, markdown, occurredAt, err := normalizeDiaryRequest(req)
 	if err != nil {
 		return nil, err
@@ -113,17 +263,38 @@ func (s *Service) CreateDiaryEntry(ctx context.Context, userID uint, req DiaryEd
 	if err != nil {
 		return nil, err
 	}
+	links, err := s.resolveDiaryEntryLinks(ctx, userID, 0, markdown)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDiaryAttachments(ctx, userID, 0, markdown); err != nil {
+		return nil, err
+	}
+	tags, err := s.resolveDiaryTags(ctx, userID, markdown, req.Tags)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.repo.SaveDiaryEntry(ctx, &DiaryEntry{
+	entry, err := s.repo.SaveDiaryEntry(ctx, &DiaryEntry{
 		UserID:      userID,
 		Title:       title,
 		Markdown:    markdown,
 		OccurredAt:  occurredAt,
 		MoodRecords: moodRecords,
+		Tags:        tags,
+		Links:       links,
 	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.CountJournalRecordsCreated(string(SearchRecordTypeDiaryEntry), 1)
+	return entry, nil
 }
 
 func (s *Service) UpdateDiaryEntry(ctx context.Context, userID, id uint, req DiaryEditEntryRequest) (*DiaryEntry, error) {
+	ctx, span := tracing.Start(ctx, "journal.UpdateDiaryEntry")
+	defer span.End()
+
 	filter := NewDiaryEntryFilter().WithUserID(userID).WithID(id)
 	entry, err := s.repo.GetDiaryEntry(ctx, filter)
 	if err != nil {
@@ -138,20 +309,43 @@ func (s *Service) UpdateDiaryEntry(ctx context.Context, userID, id uint, req Dia
 	if err != nil {
 		return nil, err
 	}
+	links, err := s.resolveDiaryEntryLinks(ctx, userID, entry.ID, markdown)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDiaryAttachments(ctx, userID, entry.ID, markdown); err != nil {
+		return nil, err
+	}
+	names := req.Tags
+	if names == nil {
+		names = explicitDiaryTagNames(entry)
+	}
+	tags, err := s.resolveDiaryTags(ctx, userID, markdown, names)
+	if err != nil {
+		return nil, err
+	}
 
 	entry.Title = title
 	entry.Markdown = markdown
 	entry.OccurredAt = occurredAt
 	entry.MoodRecords = moodRecords
+	entry.Tags = tags
+	entry.Links = links
 	return s.repo.SaveDiaryEntry(ctx, entry)
 }
 
 func (s *Service) DeleteDiaryEntry(ctx context.Context, userID, id uint) (*DiaryEntry, error) {
+	ctx, span := tracing.Start(ctx, "journal.DeleteDiaryEntry")
+	defer span.End()
+
 	filter := NewDiaryEntryFilter().WithUserID(userID).WithID(id)
 	return s.repo.DeleteDiaryEntry(ctx, filter)
 }
 
 func (s *Service) RestoreDiaryEntry(ctx context.Context, userID, id uint) (*DiaryEntry, error) {
+	ctx, span := tracing.Start(ctx, "journal.RestoreDiaryEntry")
+	defer span.End()
+
 	filter := NewDiaryEntryFilter().WithUserID(userID).WithID(id).WithDeletedMode(core.DeletedModeDeletedOnly)
 	entry, err := s.repo.GetDiaryEntry(ctx, filter)
 	if err != nil {
@@ -161,11 +355,563 @@ func (s *Service) RestoreDiaryEntry(ctx context.Context, userID, id uint) (*Diar
 	if err != nil {
 		return nil, err
 	}
+	links, err := s.resolveDiaryEntryLinks(ctx, userID, entry.ID, entry.Markdown)
+	if err != nil {
+		return nil, err
+	}
 	entry.MoodRecords = moodRecords
+	entry.Links = links
 	entry.DeletedAt.Valid = false
 	return s.repo.SaveDiaryEntry(ctx, entry)
 }
 
+func (s *Service) ListDiaryEntryRevisions(ctx context.Context, userID, id uint, limit, offset int) (core.Page[DiaryEntryRevision], error) {
+	ctx, span := tracing.Start(ctx, "journal.ListDiaryEntryRevisions")
+	defer span.End()
+
+	if _, err := s.GetDiaryEntry(ctx, userID, id); err != nil {
+		return core.Page[DiaryEntryRevision]{}, err
+	}
+
+	revisions, err := s.repo.ListDiaryEntryRevisions(ctx, id, limit, offset)
+	if err != nil {
+		return core.Page[DiaryEntryRevision]{}, err
+	}
+	totalCount, err := s.repo.CountDiaryEntryRevisions(ctx, id)
+	if err != nil {
+		return core.Page[DiaryEntryRevision]{}, err
+	}
+	if revisions == nil {
+		revisions = []DiaryEntryRevision{}
+	}
+	return core.Page[DiaryEntryRevision]{Items: revisions, TotalCount: totalCount}, nil
+}
+
+func (s *Service) GetDiaryEntryRevision(ctx context.Context, userID, id uint, revision int) (*DiaryEntryRevision, error) {
+	ctx, span := tracing.Start(ctx, "journal.GetDiaryEntryRevision")
+	defer span.End()
+
+	if _, err := s.GetDiaryEntry(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDiaryEntryRevision(ctx, id, revision)
+}
+
+func (s *Service) DiffDiaryEntryRevisions(ctx context.Context, userID, id uint, from, to int) (*DiaryEntryRevisionDiff, error) {
+	ctx, span := tracing.Start(ctx, "journal.DiffDiaryEntryRevisions")
+	defer span.End()
+
+	fromRevision, err := s.GetDiaryEntryRevision(ctx, userID, id, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := s.repo.GetDiaryEntryRevision(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+	diff := NewDiaryEntryRevisionDiff(fromRevision, toRevision)
+	return &diff, nil
+}
+
+func (s *Service) RestoreDiaryEntryRevision(ctx context.Context, userID, id uint, revision int) (*DiaryEntry, error) {
+	ctx, span := tracing.Start(ctx, "journal.RestoreDiaryEntryRevision")
+	defer span.End()
+
+	stored, err := s.GetDiaryEntryRevision(ctx, userID, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	return s.UpdateDiaryEntry(ctx, userID, id, DiaryEditEntryRequest{
+		Title:      stored.Title,
+		Markdown:   stored.Markdown,
+		OccurredAt: &stored.OccurredAt,
+	})
+}
+
+func (s *Service) ListTags(ctx context.Context, userID uint, limit, offset int) (core.Page[TagUsage], error) {
+	ctx, span := tracing.Start(ctx, "journal.ListTags")
+	defer span.End()
+
+	filter := NewTagFilter().WithUserID(userID)
+
+	tags, err := s.repo.ListTags(ctx, filter, limit, offset)
+	if err != nil {
+		return core.Page[TagUsage]{}, err
+	}
+	totalCount, err := s.repo.CountTags(ctx, filter)
+	if err != nil {
+		return core.Page[TagUsage]{}, err
+	}
+	if tags == nil {
+		tags = []TagUsage{}
+	}
+	return core.Page[TagUsage]{Items: tags, TotalCount: totalCount}, nil
+}
+
+func (s *Service) GetTag(ctx context.Context, userID, id uint) (*TagUsage, error) {
+	ctx, span := tracing.Start(ctx, "journal.GetTag")
+	defer span.End()
+
+	return s.repo.GetTag(ctx, NewTagFilter().WithUserID(userID).WithID(id))
+}
+
+func (s *Service) CreateTag(ctx context.Context, userID uint, req TagEditRequest) (*TagUsage, error) {
+	ctx, span := tracing.Start(ctx, "journal.CreateTag")
+	defer span.End()
+
+	name, err := NormalizeTagName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := s.repo.SaveTag(ctx, &Tag{UserID: userID, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	return &TagUsage{Tag: *tag}, nil
+}
+
+func (s *Service) RenameTag(ctx context.Context, userID, id uint, req TagEditRequest) (*TagUsage, error) {
+	ctx, span := tracing.Start(ctx, "journal.RenameTag")
+	defer span.End()
+
+	name, err := NormalizeTagName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithTx(ctx, func(repo *Repository) error {
+		usage, err := repo.GetTag(ctx, NewTagFilter().WithUserID(userID).WithID(id))
+		if err != nil {
+			return err
+		}
+		tag := usage.Tag
+		if tag.Name == name {
+			return nil
+		}
+
+		rename := func(markdown string) string { return RewriteHashtag(markdown, tag.Name, name) }
+		if err := rewriteDiaryHashtags(ctx, repo, tag.ID, rename); err != nil {
+			return err
+		}
+		if err := repo.TouchTaggedRecords(ctx, tag.ID); err != nil {
+			return err
+		}
+		tag.Name = name
+		_, err = repo.SaveTag(ctx, &tag)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTag(ctx, userID, id)
+}
+
+func (s *Service) MergeTag(ctx context.Context, userID, id uint, req TagMergeRequest) (*TagUsage, error) {
+	ctx, span := tracing.Start(ctx, "journal.MergeTag")
+	defer span.End()
+
+	if req.TargetID == id {
+		return nil, fmt.Errorf("%w: a tag cannot be merged into itself", core.ErrInvalidItem)
+	}
+
+	err := s.repo.WithTx(ctx, func(repo *Repository) error {
+		source, err := repo.GetTag(ctx, NewTagFilter().WithUserID(userID).WithID(id))
+		if err != nil {
+			return err
+		}
+		target, err := repo.GetTag(ctx, NewTagFilter().WithUserID(userID).WithID(req.TargetID))
+		if err != nil {
+			return err
+		}
+
+		merge := func(markdown string) string { return RewriteHashtag(markdown, source.Name, target.Name) }
+		if err := rewriteDiaryHashtags(ctx, repo, source.ID, merge); err != nil {
+			return err
+		}
+		return repo.MergeTags(ctx, &source.Tag, &target.Tag)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTag(ctx, userID, req.TargetID)
+}
+
+func (s *Service) DeleteTag(ctx context.Context, userID, id uint) (*TagUsage, error) {
+	ctx, span := tracing.Start(ctx, "journal.DeleteTag")
+	defer span.End()
+
+	var tag *TagUsage
+	err := s.repo.WithTx(ctx, func(repo *Repository) error {
+		var err error
+		tag, err = repo.GetTag(ctx, NewTagFilter().WithUserID(userID).WithID(id))
+		if err != nil {
+			return err
+		}
+		strip := func(markdown string) string { return StripHashtag(markdown, tag.Name) }
+		if err := rewriteDiaryHashtags(ctx, repo, tag.ID, strip); err != nil {
+			return err
+		}
+		return repo.DeleteTag(ctx, &tag.Tag)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func rewriteDiaryHashtags(ctx context.Context, repo *Repository, tagID uint, rewrite func(string) string) error {
+	entries, err := repo.ListTaggedDiaryEntries(ctx, tagID)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		markdown := rewrite(entries[i].Markdown)
+		if markdown == entries[i].Markdown {
+			continue
+		}
+		if err := repo.UpdateDiaryEntryMarkdown(ctx, &entries[i], markdown); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) Search(ctx context.Context, userID uint, text string, recordType SearchRecordType, limit, offset int, deleted bool) (core.Page[SearchResult], error) {
+	ctx, span := tracing.Start(ctx, "journal.Search")
+	defer span.End()
+
+	query, err := BuildSearchQuery(text)
+	if err != nil {
+		return core.Page[SearchResult]{}, fmt.Errorf("%w: %w", core.ErrInvalidItem, err)
+	}
+
+	filter := NewSearchFilter(query).WithUserID(userID)
+	if recordType != "" {
+		filter = filter.WithRecordType(recordType)
+	}
+	if deleted {
+		filter = filter.WithDeletedMode(core.DeletedModeDeletedOnly)
+	}
+
+	results, err := s.repo.Search(ctx, filter, limit, offset)
+	if err != nil {
+		return core.Page[SearchResult]{}, err
+	}
+	totalCount, err := s.repo.CountSearchResults(ctx, filter)
+	if err != nil {
+		return core.Page[SearchResult]{}, err
+	}
+	if results == nil {
+		results = []SearchResult{}
+	}
+	return core.Page[SearchResult]{Items: results, TotalCount: totalCount}, nil
+}
+
+func (s *Service) ExportJournal(ctx context.Context, userID uint, format ExportFormat, includeDeleted bool, exportedAt time.Time, w io.Writer) error {
+	ctx, span := tracing.Start(ctx, "journal.ExportJournal")
+	defer span.End()
+
+	deletedMode := core.DeletedModeNonDeleted
+	if includeDeleted {
+		deletedMode = core.DeletedModeAll
+	}
+
+	writer, err := newExportWriter(format, w, exportedAt)
+	if err != nil {
+		return err
+	}
+	moodFilter := NewMoodRecordFilter().WithUserID(userID).WithDeletedMode(deletedMode)
+	err = s.repo.EachMoodRecordBatch(ctx, moodFilter, exportBatchSize, func(records []MoodRecord) error {
+		for i := range records {
+			if err := writer.WriteMoodRecord(NewExportMoodRecord(&records[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	diaryFilter := NewDiaryEntryFilter().WithUserID(userID).WithDeletedMode(deletedMode)
+	err = s.repo.EachDiaryEntryBatch(ctx, diaryFilter, exportBatchSize, func(entries []DiaryEntry) error {
+		for i := range entries {
+			if err := writer.WriteDiaryEntry(NewExportDiaryEntry(&entries[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+type importDeletion struct {
+	id        uint
+	deletedAt time.Time
+}
+
+func (s *Service) ImportJournal(ctx context.Context, userID uint, document *ImportDocument, dryRun bool) (*ImportReport, error) {
+	ctx, span := tracing.Start(ctx, "journal.ImportJournal")
+	defer span.End()
+
+	report := newImportReport(dryRun)
+	err := s.repo.WithTx(ctx, func(repo *Repository) error {
+		txService := s.inTransaction(repo)
+		moodDeletions, err := txService.importMoodRecords(ctx, userID, document.MoodRecords, report)
+		if err != nil {
+			return err
+		}
+		diaryDeletions, err := txService.importDiaryEntries(ctx, userID, document.DiaryEntries, report)
+		if err != nil {
+			return err
+		}
+		for _, deletion := range diaryDeletions {
+			if err := repo.MarkDiaryEntryDeleted(ctx, deletion.id, deletion.deletedAt); err != nil {
+				return err
+			}
+		}
+		for _, deletion := range moodDeletions {
+			if err := repo.MarkMoodRecordDeleted(ctx, deletion.id, deletion.deletedAt); err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			return errImportDryRun
+		}
+		if len(report.BrokenReferences) > 0 {
+			return ErrImportBrokenReferences
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportDryRun) {
+		return report, err
+	}
+	if !dryRun {
+		metrics.CountJournalRecordsCreated(string(SearchRecordTypeMoodRecord), report.MoodRecords.Created)
+		metrics.CountJournalRecordsCreated(string(SearchRecordTypeDiaryEntry), report.DiaryEntries.Created)
+	}
+	return report, nil
+}
+
+func (s *Service) importMoodRecords(ctx context.Context, userID uint, records []ExportMoodRecord, report *ImportReport) ([]importDeletion, error) {
+	var deletions []importDeletion
+	for _, item := range records {
+		if _, ok := report.MoodRecordIDs[item.ID]; ok && item.ID != 0 {
+			report.MoodRecords.Skipped++
+			report.Conflicts = append(report.Conflicts, ImportIssue{
+				Type:    SearchRecordTypeMoodRecord,
+				ID:      item.ID,
+				Message: "duplicate mood record ID in import",
+			})
+			continue
+		}
+
+		feeling := strings.TrimSpace(item.Feeling)
+		if feeling == "" {
+			return nil, fmt.Errorf("%w: mood record %d: feeling is required", core.ErrInvalidItem, item.ID)
+		}
+		createdAt := item.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		record := &MoodRecord{
+			UserID:    userID,
+			Feeling:   feeling,
+			Emoji:     item.Emoji,
+			Note:      item.Note,
+			CreatedAt: createdAt.UTC().Truncate(time.Microsecond),
+		}
+
+		existing, err := s.repo.FindMatchingMoodRecord(ctx, record, item.DeletedAt.Valid)
+		if err == nil {
+			report.MoodRecordIDs[item.ID] = existing.ID
+			report.MoodRecords.Skipped++
+			report.Conflicts = append(report.Conflicts, ImportIssue{
+				Type:       SearchRecordTypeMoodRecord,
+				ID:         item.ID,
+				ExistingID: existing.ID,
+				Message:    "matches an existing mood record",
+			})
+			continue
+		}
+		if !errors.Is(err, core.ErrItemNotFound) {
+			return nil, err
+		}
+
+		record.Tags, err = s.resolveTags(ctx, userID, item.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("mood record %d: %w", item.ID, err)
+		}
+		saved, err := s.repo.SaveMoodRecord(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+		report.MoodRecordIDs[item.ID] = saved.ID
+		report.MoodRecords.Created++
+		if item.DeletedAt.Valid {
+			deletions = append(deletions, importDeletion{id: saved.ID, deletedAt: item.DeletedAt.Time})
+		}
+	}
+	return deletions, nil
+}
+
+type importedDiaryEntry struct {
+	item    ExportDiaryEntry
+	entry   *DiaryEntry
+	skipped bool
+}
+
+func (s *Service) importDiaryEntries(ctx context.Context, userID uint, entries []ExportDiaryEntry, report *ImportReport) ([]importDeletion, error) {
+	var imported []*importedDiaryEntry
+	for _, item := range entries {
+		rewritten, missing := RewriteMoodRecordLinks(item.Markdown, report.MoodRecordIDs)
+		if len(missing) > 0 {
+			report.DiaryEntries.Skipped++
+			for _, id := range missing {
+				report.BrokenReferences = append(report.BrokenReferences, ImportIssue{
+					Type:         SearchRecordTypeDiaryEntry,
+					ID:           item.ID,
+					MoodRecordID: id,
+					Message:      "links to a mood record that is not part of the import",
+				})
+			}
+			continue
+		}
+
+		title, markdown, occurredAt, err := normalizeDiaryRequest(DiaryEditEntryRequest{
+			Title:      item.Title,
+			Markdown:   rewritten,
+			OccurredAt: &item.OccurredAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("diary entry %d: %w", item.ID, err)
+		}
+		entry := &DiaryEntry{
+			UserID:     userID,
+			Title:      title,
+			Markdown:   markdown,
+			OccurredAt: occurredAt,
+			CreatedAt:  item.CreatedAt.UTC().Truncate(time.Microsecond),
+		}
+
+		existing, err := s.repo.FindMatchingDiaryEntry(ctx, entry, item.DeletedAt.Valid)
+		if err == nil {
+			report.DiaryEntryIDs[item.ID] = existing.ID
+			report.DiaryEntries.Skipped++
+			report.Conflicts = append(report.Conflicts, ImportIssue{
+				Type:       SearchRecordTypeDiaryEntry,
+				ID:         item.ID,
+				ExistingID: existing.ID,
+				Message:    "matches an existing diary entry",
+			})
+			continue
+		}
+		if !errors.Is(err, core.ErrItemNotFound) {
+			return nil, err
+		}
+
+		attachmentIDs, err := ExtractAttachmentIDs(markdown)
+		if err != nil {
+			return nil, fmt.Errorf("diary entry %d: %w: invalid attachment references", item.ID, core.ErrInvalidItem)
+		}
+		if len(attachmentIDs) > 0 {
+			report.DiaryEntries.Skipped++
+			for _, id := range attachmentIDs {
+				report.BrokenReferences = append(report.BrokenReferences, ImportIssue{
+					Type:         SearchRecordTypeDiaryEntry,
+					ID:           item.ID,
+					AttachmentID: id,
+					Message:      "references an attachment that is not part of the import",
+				})
+			}
+			continue
+		}
+		imported = append(imported, &importedDiaryEntry{item: item, entry: entry})
+	}
+
+	ids, err := s.repo.ReserveDiaryEntryIDs(ctx, len(imported))
+	if err != nil {
+		return nil, err
+	}
+	for index, candidate := range imported {
+		candidate.entry.ID = ids[index]
+		report.DiaryEntryIDs[candidate.item.ID] = candidate.entry.ID
+	}
+	skipDiaryEntriesWithMissingLinks(imported, report)
+
+	var deletions []importDeletion
+	for _, candidate := range imported {
+		if candidate.skipped {
+			continue
+		}
+		entry := candidate.entry
+		entry.Markdown, _ = RewriteDiaryEntryLinks(entry.Markdown, report.DiaryEntryIDs)
+		entry.MoodRecords, err = s.resolveDiaryMoodRecords(ctx, userID, entry.Markdown)
+		if err != nil {
+			return nil, fmt.Errorf("diary entry %d: %w", candidate.item.ID, err)
+		}
+		entry.Tags, err = s.resolveDiaryTags(ctx, userID, entry.Markdown, candidate.item.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("diary entry %d: %w", candidate.item.ID, err)
+		}
+		if _, err := s.repo.CreateDiaryEntry(ctx, entry); err != nil {
+			return nil, err
+		}
+		report.DiaryEntries.Created++
+		if candidate.item.DeletedAt.Valid {
+			deletions = append(deletions, importDeletion{id: entry.ID, deletedAt: candidate.item.DeletedAt.Time})
+		}
+	}
+
+	for _, candidate := range imported {
+		if candidate.skipped {
+			continue
+		}
+		links, err := s.resolveDiaryEntryLinks(ctx, userID, candidate.entry.ID, candidate.entry.Markdown)
+		if err != nil {
+			return nil, fmt.Errorf("diary entry %d: %w", candidate.item.ID, err)
+		}
+		if len(links) == 0 {
+			continue
+		}
+		if err := s.repo.AddDiaryEntryLinks(ctx, candidate.entry, links); err != nil {
+			return nil, err
+		}
+	}
+	return deletions, nil
+}
+
+func skipDiaryEntriesWithMissingLinks(imported []*importedDiaryEntry, report *ImportReport) {
+	for changed := true; changed; {
+		changed = false
+		for _, candidate := range imported {
+			if candidate.skipped {
+				continue
+			}
+			_, missing := RewriteDiaryEntryLinks(candidate.entry.Markdown, report.DiaryEntryIDs)
+			if len(missing) == 0 {
+				continue
+			}
+			candidate.skipped = true
+			changed = true
+			delete(report.DiaryEntryIDs, candidate.item.ID)
+			report.DiaryEntries.Skipped++
+			for _, id := range missing {
+				report.BrokenReferences = append(report.BrokenReferences, ImportIssue{
+					Type:         SearchRecordTypeDiaryEntry,
+					ID:           candidate.item.ID,
+					DiaryEntryID: id,
+					Message:      "links to a diary entry that is not part of the import",
+				})
+			}
+		}
+	}
+}
+
 func normalizeDiaryRequest(req DiaryEditEntryRequest) (string, string, time.Time, error) {
 	title := strings.TrimSpace(req.Title)
 	markdown := strings.TrimSpace(req.Markdown)
@@ -177,7 +923,7 @@ func normalizeDiaryRequest(req DiaryEditEntryRequest) (string, string, time.Time
 		return "", "", time.Time{}, fmt.Errorf("%w: occurred_at is required", core.ErrInvalidItem)
 	}
 
-	occurredAt := req.OccurredAt.UTC()
+	occurredAt := req.OccurredAt.UTC().Truncate(time.Microsecond)
 	if occurredAt.After(time.Now().UTC()) {
 		return "", "", time.Time{}, fmt.Errorf("%w: occurred_at cannot be in the future", core.ErrInvalidItem)
 	}
@@ -185,7 +931,66 @@ func normalizeDiaryRequest(req DiaryEditEntryRequest) (string, string, time.Time
 	return title, markdown, occurredAt, nil
 }
 
+func (s *Service) resolveTags(ctx context.Context, userID uint, names []string) ([]Tag, error) {
+	normalized, err := normalizeTagNames(names)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindOrCreateTags(ctx, userID, normalized)
+}
+
+func (s *Service) resolveDiaryTags(ctx context.Context, userID uint, markdown string, names []string) ([]Tag, error) {
+	return s.resolveTags(ctx, userID, append(ExtractHashtags(markdown), names...))
+}
+
+func (s *Service) resolveDiaryEntryLinks(ctx context.Context, userID, entryID uint, markdown string) ([]DiaryEntry, error) {
+	ids, err := ExtractDiaryEntryIDs(markdown)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid diary entry references", core.ErrInvalidItem)
+	}
+	if entryID != 0 && slices.Contains(ids, entryID) {
+		return nil, fmt.Errorf("%w: a diary entry cannot link to itself", core.ErrInvalidItem)
+	}
+
+	entries, err := s.repo.ListDiaryEntriesByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) != len(ids) {
+		return nil, fmt.Errorf("%w: one or more referenced diary entries do not exist", core.ErrInvalidItem)
+	}
+
+	return entries, nil
+}
+
+func (s *Service) checkDiaryAttachments(ctx context.Context, userID, entryID uint, markdown string) error {
+	ids, err := ExtractAttachmentIDs(markdown)
+	if err != nil {
+		return fmt.Errorf("%w: invalid attachment references", core.ErrInvalidItem)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if entryID == 0 {
+		return fmt.Errorf("%w: attachments can only be referenced once the diary entry exists", core.ErrInvalidItem)
+	}
+
+	filter := NewAttachmentFilter().WithUserID(userID).WithDiaryEntryID(entryID)
+	count, err := s.repo.CountAttachmentsByIDs(ctx, filter, ids)
+	if err != nil {
+		return err
+	}
+	if count != int64(len(ids)) {
+		return fmt.Errorf("%w: one or more referenced attachments do not exist on this diary entry", core.ErrInvalidItem)
+	}
+	return nil
+}
+
 func (s *Service) resolveDiaryMoodRecords(ctx context.Context, userID uint, markdown string) ([]MoodRecord, error) {
+	ctx, span := tracing.Start(ctx, "journal.resolveDiaryMoodRecords")
+	defer span.End()
+
 	ids, err := ExtractMoodRecordIDs(markdown)
 	if err != nil {
 		return nil, fmt.Errorf("%w: invalid mood references", core.ErrInvalidItem)
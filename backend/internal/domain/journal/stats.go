@@ -0,0 +1,189 @@
+package journal
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type MoodStatsInterval string
+
+const (
+	MoodStatsIntervalDay   MoodStatsInterval = "day"
+	MoodStatsIntervalWeek  MoodStatsInterval = "week"
+	MoodStatsIntervalMonth MoodStatsInterval = "month"
+)
+
+type MoodStatsRow struct {
+	Feeling   string
+	Emoji     string
+	CreatedAt time.Time
+	Linked    bool
+}
+
+type MoodStats struct {
+	TotalCount  int64             `json:"total_count"`
+	LinkedCount int64             `json:"linked_count"`
+	LinkedShare float64           `json:"linked_share"`
+	Feelings    []MoodStatsCount  `json:"feelings"`
+	Emojis      []MoodStatsCount  `json:"emojis"`
+	Interval    MoodStatsInterval `json:"interval"`
+	Timezone    string            `json:"timezone"`
+	Series      []MoodStatsBucket `json:"series"`
+	Streaks     MoodStatsStreaks  `json:"streaks"`
+}
+
+type MoodStatsCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type MoodStatsBucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+type MoodStatsStreaks struct {
+	Current      int    `json:"current"`
+	Longest      int    `json:"longest"`
+	LastLoggedOn string `json:"last_logged_on,omitempty"`
+}
+
+func ParseMoodStatsInterval(value string) (MoodStatsInterval, error) {
+	switch MoodStatsInterval(value) {
+	case MoodStatsIntervalDay, MoodStatsIntervalWeek, MoodStatsIntervalMonth:
+		return MoodStatsInterval(value), nil
+	default:
+		return "", fmt.Errorf("unknown stats interval %q", value)
+	}
+}
+
+func NewMoodStats(rows []MoodStatsRow, interval MoodStatsInterval, location *time.Location, now time.Time) *MoodStats {
+	stats := &MoodStats{
+		TotalCount: int64(len(rows)),
+		Interval:   interval,
+		Timezone:   location.String(),
+		Feelings:   []MoodStatsCount{},
+		Emojis:     []MoodStatsCount{},
+		Series:     []MoodStatsBucket{},
+	}
+
+	feelings := make(map[string]int64)
+	emojis := make(map[string]int64)
+	buckets := make(map[time.Time]int64)
+	var days []time.Time
+	for _, row := range rows {
+		if row.Linked {
+			stats.LinkedCount++
+		}
+		if feeling := strings.ToLower(strings.TrimSpace(row.Feeling)); feeling != "" {
+			feelings[feeling]++
+		}
+		if row.Emoji != "" {
+			emojis[row.Emoji]++
+		}
+
+		local := row.CreatedAt.In(location)
+		buckets[statsBucketStart(local, interval)]++
+		day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		if len(days) == 0 || !days[len(days)-1].Equal(day) {
+			days = append(days, day)
+		}
+	}
+	if stats.TotalCount > 0 {
+		stats.LinkedShare = float64(stats.LinkedCount) / float64(stats.TotalCount)
+	}
+
+	stats.Feelings = sortedStatsCounts(feelings)
+	stats.Emojis = sortedStatsCounts(emojis)
+	stats.Series = filledStatsSeries(buckets, interval)
+	stats.Streaks = statsStreaks(days, now.In(location))
+	return stats
+}
+
+func statsBucketStart(local time.Time, interval MoodStatsInterval) time.Time {
+	year, month, day := local.Date()
+	switch interval {
+	case MoodStatsIntervalWeek:
+		daysSinceMonday := (int(local.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, local.Location())
+	case MoodStatsIntervalMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, local.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, local.Location())
+	}
+}
+
+func nextStatsBucket(start time.Time, interval MoodStatsInterval) time.Time {
+	year, month, day := start.Date()
+	switch interval {
+	case MoodStatsIntervalWeek:
+		return time.Date(year, month, day+7, 0, 0, 0, 0, start.Location())
+	case MoodStatsIntervalMonth:
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, start.Location())
+	default:
+		return time.Date(year, month, day+1, 0, 0, 0, 0, start.Location())
+	}
+}
+
+func sortedStatsCounts(counts map[string]int64) []MoodStatsCount {
+	result := make([]MoodStatsCount, 0, len(counts))
+	for value, count := range counts {
+		result = append(result, MoodStatsCount{Value: value, Count: count})
+	}
+	slices.SortFunc(result, func(a, b MoodStatsCount) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return strings.Compare(a.Value, b.Value)
+	})
+	return result
+}
+
+func filledStatsSeries(buckets map[time.Time]int64, interval MoodStatsInterval) []MoodStatsBucket {
+	if len(buckets) == 0 {
+		return []MoodStatsBucket{}
+	}
+
+	var first, last time.Time
+	for start := range buckets {
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if last.IsZero() || start.After(last) {
+			last = start
+		}
+	}
+
+	var series []MoodStatsBucket
+	for start := first; !start.After(last); start = nextStatsBucket(start, interval) {
+		series = append(series, MoodStatsBucket{Start: start, Count: buckets[start]})
+	}
+	return series
+}
+
+func statsStreaks(days []time.Time, localNow time.Time) MoodStatsStreaks {
+	if len(days) == 0 {
+		return MoodStatsStreaks{}
+	}
+
+	streaks := MoodStatsStreaks{Longest: 1, LastLoggedOn: days[len(days)-1].Format(time.DateOnly)}
+	run := 1
+	for i := 1; i < len(days); i++ {
+		if days[i-1].AddDate(0, 0, 1).Equal(days[i]) {
+			run++
+		} else {
+			run = 1
+		}
+		streaks.Longest = max(streaks.Longest, run)
+	}
+
+	today := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, time.UTC)
+	last := days[len(days)-1]
+	if last.Equal(today) || last.Equal(today.AddDate(0, 0, -1)) {
+		streaks.Current = run
+	}
+	return streaks
+}
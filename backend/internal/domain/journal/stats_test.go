@@ -0,0 +1,122 @@
+package journal_test
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestNewMoodStats(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	rows := []journal.MoodStatsRow{
+		{Feeling: "Calm", Emoji: "🙂", CreatedAt: time.Date(2026, time.March, 2, 3, 0, 0, 0, time.UTC), Linked: true},
+		{Feeling: "calm", CreatedAt: time.Date(2026, time.March, 2, 15, 0, 0, 0, time.UTC)},
+		{Feeling: "tired", Emoji: "😴", CreatedAt: time.Date(2026, time.March, 3, 15, 0, 0, 0, time.UTC)},
+		{Feeling: "calm", Emoji: "🙂", CreatedAt: time.Date(2026, time.March, 17, 15, 0, 0, 0, time.UTC)},
+	}
+	now := time.Date(2026, time.March, 18, 12, 0, 0, 0, time.UTC)
+
+	stats := journal.NewMoodStats(rows, journal.MoodStatsIntervalDay, location, now)
+	if stats.TotalCount != 4 || stats.LinkedCount != 1 || stats.LinkedShare != 0.25 {
+		t.Fatalf("totals = %d/%d share %v, want 4/1 share 0.25", stats.TotalCount, stats.LinkedCount, stats.LinkedShare)
+	}
+	wantFeelings := []journal.MoodStatsCount{{Value: "calm", Count: 3}, {Value: "tired", Count: 1}}
+	if !slices.Equal(stats.Feelings, wantFeelings) {
+		t.Errorf("feelings = %v, want %v", stats.Feelings, wantFeelings)
+	}
+	wantEmojis := []journal.MoodStatsCount{{Value: "🙂", Count: 2}, {Value: "😴", Count: 1}}
+	if !slices.Equal(stats.Emojis, wantEmojis) {
+		t.Errorf("emojis = %v, want %v", stats.Emojis, wantEmojis)
+	}
+
+	if len(stats.Series) != 17 {
+		t.Fatalf("daily series length = %d, want 17", len(stats.Series))
+	}
+	first := stats.Series[0]
+	if !first.Start.Equal(time.Date(2026, time.March, 1, 0, 0, 0, 0, location)) || first.Count != 1 {
+		t.Errorf("first bucket = %v/%d, want local March 1 with 1 record", first.Start, first.Count)
+	}
+	if stats.Series[1].Count != 1 || stats.Series[2].Count != 1 || stats.Series[3].Count != 0 {
+		t.Errorf("early bucket counts = %d %d %d, want 1 1 0", stats.Series[1].Count, stats.Series[2].Count, stats.Series[3].Count)
+	}
+	if stats.Streaks.Longest != 3 || stats.Streaks.Current != 1 || stats.Streaks.LastLoggedOn != "2026-03-17" {
+		t.Errorf("streaks = %+v, want longest 3, current 1, last 2026-03-17", stats.Streaks)
+	}
+
+	weekly := journal.NewMoodStats(rows, journal.MoodStatsIntervalWeek, location, now)
+	var weeklyCounts []int64
+	for _, bucket := range weekly.Series {
+		if bucket.Start.Weekday() != time.Monday {
+			t.Errorf("weekly bucket starts on %s, want Monday", bucket.Start.Weekday())
+		}
+		weeklyCounts = append(weeklyCounts, bucket.Count)
+	}
+	if !slices.Equal(weeklyCounts, []int64{1, 2, 0, 1}) {
+		t.Errorf("weekly counts = %v, want [1 2 0 1]", weeklyCounts)
+	}
+
+	lapsed := journal.NewMoodStats(rows, journal.MoodStatsIntervalMonth, location, now.AddDate(0, 0, 2))
+	if lapsed.Streaks.Current != 0 || len(lapsed.Series) != 1 || lapsed.Series[0].Count != 4 {
+		t.Errorf("lapsed monthly stats = %+v series %v, want no current streak and one bucket of 4", lapsed.Streaks, lapsed.Series)
+	}
+}
+
+func TestMoodStatsHTTPContract(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	other := createJournalUser(t, environment, "other")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+
+	march := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	saveMoodRecord(t, environment, owner.ID, "early", march.AddDate(0, -1, 0))
+	linked := saveMoodRecord(t, environment, owner.ID, "calm", march)
+	saveMoodRecord(t, environment, owner.ID, "calm", march.Add(time.Hour))
+	saveMoodRecord(t, environment, other.ID, "foreign", march)
+	occurredAt := march.Add(2 * time.Hour)
+	if _, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest(fmt.Sprintf("[[mood:%d]]", linked.ID), &occurredAt)); err != nil {
+		t.Fatalf("create linking diary entry: %v", err)
+	}
+
+	for _, path := range []string{
+		"/api/journal/stats?tz=Mars/Olympus",
+		"/api/journal/stats?interval=year",
+		"/api/journal/stats?from=2026-04-01&to=2026-03-01",
+	} {
+		response := serveJournalJSON(t, e, http.MethodGet, path, nil, ownerCookie)
+		if response.Code != http.StatusBadRequest {
+			t.Fatalf("GET %s status = %d, want %d", path, response.Code, http.StatusBadRequest)
+		}
+	}
+	if response := serveJournalJSON(t, e, http.MethodGet, "/api/journal/stats", nil); response.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous stats status = %d, want %d", response.Code, http.StatusUnauthorized)
+	}
+
+	response := serveJournalJSON(t, e, http.MethodGet, "/api/journal/stats?from=2026-03-01&to=2026-03-31&tz=Europe/Berlin&interval=month", nil, ownerCookie)
+	if response.Code != http.StatusOK {
+		t.Fatalf("stats status = %d, want %d", response.Code, http.StatusOK)
+	}
+	var stats journal.MoodStats
+	decodeJournalResponse(t, response, &stats)
+	if stats.TotalCount != 2 || stats.LinkedCount != 1 || stats.LinkedShare != 0.5 {
+		t.Fatalf("stats totals = %d/%d share %v, want 2/1 share 0.5", stats.TotalCount, stats.LinkedCount, stats.LinkedShare)
+	}
+	if stats.Timezone != "Europe/Berlin" || stats.Interval != journal.MoodStatsIntervalMonth {
+		t.Errorf("stats timezone/interval = %s/%s, want Europe/Berlin/month", stats.Timezone, stats.Interval)
+	}
+	if len(stats.Feelings) != 1 || stats.Feelings[0] != (journal.MoodStatsCount{Value: "calm", Count: 2}) {
+		t.Errorf("stats feelings = %v, want only calm x2", stats.Feelings)
+	}
+	if len(stats.Series) != 1 || stats.Series[0].Count != 2 {
+		t.Errorf("stats series = %v, want one bucket of 2", stats.Series)
+	}
+}
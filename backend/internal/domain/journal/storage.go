@@ -0,0 +1,92 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+)
+
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type LocalStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{root: root}
+}
+
+func (s *LocalStorage) Put(_ context.Context, key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, fmt.Errorf("create storage directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, fmt.Errorf("create stored file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	written, err := io.Copy(file, r)
+	if err != nil {
+		file.Close()
+		return written, fmt.Errorf("write stored file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return written, fmt.Errorf("close stored file: %w", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return written, fmt.Errorf("move stored file: %w", err)
+	}
+	return written, nil
+}
+
+func (s *LocalStorage) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: stored file not found", core.ErrItemNotFound)
+		}
+		return nil, fmt.Errorf("open stored file: %w", err)
+	}
+	return file, nil
+}
+
+func (s *LocalStorage) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("delete stored file: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	if !filepath.IsLocal(key) || strings.HasPrefix(filepath.Base(key), ".") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,69 @@
+package journal
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+)
+
+const maxTagNameLength = 64
+
+var tagNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_]+(?:-[\p{L}\p{N}_]+)*$`)
+
+type TagUsage struct {
+	Tag
+	MoodRecordCount int64 `json:"mood_record_count"`
+	DiaryEntryCount int64 `json:"diary_entry_count"`
+}
+
+func NormalizeTagName(name string) (string, error) {
+	normalized := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if !tagNamePattern.MatchString(normalized) || strings.IndexFunc(normalized, unicode.IsLetter) < 0 {
+		return "", fmt.Errorf("%w: tag %q must contain a letter and only letters, digits, underscores or inner hyphens", core.ErrInvalidItem, name)
+	}
+	if utf8.RuneCountInString(normalized) > maxTagNameLength {
+		return "", fmt.Errorf("%w: tag %q is longer than %d characters", core.ErrInvalidItem, name, maxTagNameLength)
+	}
+	return normalized, nil
+}
+
+func normalizeTagNames(names []string) ([]string, error) {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		tag, err := NormalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, tag)
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
+
+func explicitDiaryTagNames(entry *DiaryEntry) []string {
+	hashtags := ExtractHashtags(entry.Markdown)
+	names := make([]string, 0, len(entry.Tags))
+	for _, tag := range entry.Tags {
+		if _, found := slices.BinarySearch(hashtags, tag.Name); !found {
+			names = append(names, tag.Name)
+		}
+	}
+	return names
+}
+
+func tagNames(tags []Tag) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
@@ -0,0 +1,218 @@
+package journal_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestNormalizeTagName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: " #Self-Care ", want: "self-care"},
+		{name: "Ünïcode_2", want: "ünïcode_2"},
+		{name: "2024", wantErr: true},
+		{name: "two words", wantErr: true},
+		{name: "trailing-", wantErr: true},
+		{name: "#", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := journal.NormalizeTagName(tt.name)
+		if tt.wantErr {
+			if !errors.Is(err, core.ErrInvalidItem) {
+				t.Fatalf("NormalizeTagName(%q) error = %v, want ErrInvalidItem", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Fatalf("NormalizeTagName(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestServiceTags(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	other := createJournalUser(t, environment, "other")
+	ctx := t.Context()
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	mood, err := environment.service.CreateMoodRecord(ctx, owner.ID, journal.MoodEditRecordRequest{Feeling: "calm", Tags: []string{"Work", "#sleep", "work"}})
+	if err != nil {
+		t.Fatalf("CreateMoodRecord() error = %v", err)
+	}
+	if got := tagNamesOf(mood.Tags); !slices.Equal(got, []string{"sleep", "work"}) {
+		t.Fatalf("mood record tags = %v, want [sleep work]", got)
+	}
+
+	request := diaryRequest("#work day", &occurredAt)
+	request.Tags = []string{"Travel"}
+	entry, err := environment.service.CreateDiaryEntry(ctx, owner.ID, request)
+	if err != nil {
+		t.Fatalf("CreateDiaryEntry() error = %v", err)
+	}
+	entry, err = environment.service.UpdateDiaryEntry(ctx, owner.ID, entry.ID, diaryRequest("#work day, then #gym", &occurredAt))
+	if err != nil {
+		t.Fatalf("UpdateDiaryEntry() error = %v", err)
+	}
+	if got := tagNamesOf(entry.Tags); !slices.Equal(got, []string{"gym", "travel", "work"}) {
+		t.Fatalf("diary entry tags = %v, want hashtags plus the explicit travel tag", got)
+	}
+
+	page, err := environment.service.ListTags(ctx, owner.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if page.TotalCount != 4 || page.Items[3].Name != "work" || page.Items[3].MoodRecordCount != 1 || page.Items[3].DiaryEntryCount != 1 {
+		t.Fatalf("ListTags() = %+v, want four tags with work used once by each record type", page)
+	}
+	moods, err := environment.service.ListMoodRecords(ctx, owner.ID, journal.NewMoodRecordFilter().WithTag("work"), 10, 0)
+	if err != nil || moods.TotalCount != 1 {
+		t.Fatalf("ListMoodRecords(tag=work) = %+v, %v, want one record", moods, err)
+	}
+	entries, err := environment.service.ListDiaryEntries(ctx, owner.ID, journal.NewDiaryEntryFilter().WithTag("sleep"), 10, 0)
+	if err != nil || entries.TotalCount != 0 {
+		t.Fatalf("ListDiaryEntries(tag=sleep) = %+v, %v, want no entries", entries, err)
+	}
+
+	work := findTag(t, page.Items, "work")
+	renamed, err := environment.service.RenameTag(ctx, owner.ID, work.ID, journal.TagEditRequest{Name: "Job"})
+	if err != nil || renamed.Name != "job" {
+		t.Fatalf("RenameTag() = %+v, %v, want job", renamed, err)
+	}
+	gym := findTag(t, page.Items, "gym")
+	merged, err := environment.service.MergeTag(ctx, owner.ID, gym.ID, journal.TagMergeRequest{TargetID: work.ID})
+	if err != nil || merged.DiaryEntryCount != 1 || merged.MoodRecordCount != 1 {
+		t.Fatalf("MergeTag() = %+v, %v, want job used once by each record type", merged, err)
+	}
+	entry, err = environment.service.GetDiaryEntry(ctx, owner.ID, entry.ID)
+	if err != nil {
+		t.Fatalf("GetDiaryEntry() error = %v", err)
+	}
+	if entry.Markdown != "#job day, then #job" || !slices.Equal(tagNamesOf(entry.Tags), []string{"job", "travel"}) {
+		t.Fatalf("diary entry after rename and merge = %q tagged %v", entry.Markdown, tagNamesOf(entry.Tags))
+	}
+	if _, err := environment.service.GetTag(ctx, owner.ID, gym.ID); !errors.Is(err, core.ErrItemNotFound) {
+		t.Fatalf("GetTag() merged source error = %v, want ErrItemNotFound", err)
+	}
+
+	if _, err := environment.service.CreateTag(ctx, owner.ID, journal.TagEditRequest{Name: "#JOB"}); !errors.Is(err, journal.ErrTagExists) {
+		t.Fatalf("CreateTag() duplicate error = %v, want ErrTagExists", err)
+	}
+	sleep := findTag(t, page.Items, "sleep")
+	if _, err := environment.service.RenameTag(ctx, owner.ID, sleep.ID, journal.TagEditRequest{Name: "job"}); !errors.Is(err, journal.ErrTagExists) {
+		t.Fatalf("RenameTag() onto existing tag error = %v, want ErrTagExists", err)
+	}
+	if _, err := environment.service.CreateTag(ctx, other.ID, journal.TagEditRequest{Name: "job"}); err != nil {
+		t.Fatalf("CreateTag() for another user error = %v", err)
+	}
+	if _, err := environment.service.GetTag(ctx, other.ID, work.ID); !errors.Is(err, core.ErrItemNotFound) {
+		t.Fatalf("foreign GetTag() error = %v, want ErrItemNotFound", err)
+	}
+
+	travel := findTag(t, page.Items, "travel")
+	if _, err := environment.service.DeleteTag(ctx, owner.ID, travel.ID); err != nil {
+		t.Fatalf("DeleteTag() error = %v", err)
+	}
+	entry, err = environment.service.GetDiaryEntry(ctx, owner.ID, entry.ID)
+	if err != nil || !slices.Equal(tagNamesOf(entry.Tags), []string{"job"}) {
+		t.Fatalf("diary entry tags after delete = %v, %v, want [job]", tagNamesOf(entry.Tags), err)
+	}
+
+	if _, err := environment.service.DeleteTag(ctx, owner.ID, work.ID); err != nil {
+		t.Fatalf("DeleteTag() used in markdown error = %v", err)
+	}
+	entry, err = environment.service.GetDiaryEntry(ctx, owner.ID, entry.ID)
+	if err != nil {
+		t.Fatalf("GetDiaryEntry() error = %v", err)
+	}
+	entry, err = environment.service.UpdateDiaryEntry(ctx, owner.ID, entry.ID, diaryRequest(entry.Markdown, &occurredAt))
+	if err != nil {
+		t.Fatalf("UpdateDiaryEntry() after delete error = %v", err)
+	}
+	if entry.Markdown != "job day, then job" || len(entry.Tags) != 0 {
+		t.Fatalf("diary entry after deleting a hashtag = %q tagged %v", entry.Markdown, tagNamesOf(entry.Tags))
+	}
+	if _, err := environment.service.GetTag(ctx, owner.ID, work.ID); !errors.Is(err, core.ErrItemNotFound) {
+		t.Fatalf("GetTag() deleted tag error = %v, want ErrItemNotFound", err)
+	}
+}
+
+func TestTagsHTTPContract(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+
+	created := serveJournalJSON(t, e, http.MethodPost, "/api/journal/tags", journal.TagEditRequest{Name: "Work"}, ownerCookie)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("POST /api/journal/tags status = %d, want %d", created.Code, http.StatusCreated)
+	}
+	var work journal.TagUsage
+	decodeJournalResponse(t, created, &work)
+	other, err := environment.service.CreateTag(t.Context(), owner.ID, journal.TagEditRequest{Name: "rest"})
+	if err != nil {
+		t.Fatalf("create tag: %v", err)
+	}
+	base := fmt.Sprintf("/api/journal/tags/%d", work.ID)
+
+	tests := []struct {
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{method: http.MethodGet, path: "/api/journal/tags", want: http.StatusOK},
+		{method: http.MethodGet, path: base, want: http.StatusOK},
+		{method: http.MethodGet, path: "/api/journal/tags/999", want: http.StatusNotFound},
+		{method: http.MethodPost, path: "/api/journal/tags", body: journal.TagEditRequest{Name: "work"}, want: http.StatusConflict},
+		{method: http.MethodPost, path: "/api/journal/tags", body: journal.TagEditRequest{Name: "two words"}, want: http.StatusBadRequest},
+		{method: http.MethodPut, path: base, body: journal.TagEditRequest{Name: "rest"}, want: http.StatusConflict},
+		{method: http.MethodPut, path: base, body: journal.TagEditRequest{Name: "job"}, want: http.StatusOK},
+		{method: http.MethodPost, path: base + "/merge", body: journal.TagMergeRequest{TargetID: work.ID}, want: http.StatusBadRequest},
+		{method: http.MethodPost, path: base + "/merge", body: journal.TagMergeRequest{TargetID: 999}, want: http.StatusNotFound},
+		{method: http.MethodGet, path: "/api/journal/mood-records?tag=job", want: http.StatusOK},
+		{method: http.MethodGet, path: "/api/journal/diary-entries?tag=%23%23", want: http.StatusBadRequest},
+		{method: http.MethodPost, path: fmt.Sprintf("/api/journal/tags/%d/merge", other.ID), body: journal.TagMergeRequest{TargetID: work.ID}, want: http.StatusOK},
+		{method: http.MethodDelete, path: base, want: http.StatusOK},
+		{method: http.MethodDelete, path: base, want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		response := serveJournalJSON(t, e, tt.method, tt.path, tt.body, ownerCookie)
+		if response.Code != tt.want {
+			t.Fatalf("%s %s status = %d, want %d", tt.method, tt.path, response.Code, tt.want)
+		}
+	}
+}
+
+func tagNamesOf(tags []journal.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func findTag(t *testing.T, tags []journal.TagUsage, name string) journal.TagUsage {
+	t.Helper()
+
+	for _, tag := range tags {
+		if tag.Name == name {
+			return tag
+		}
+	}
+	t.Fatalf("tag %q not found in %+v", name, tags)
+	return journal.TagUsage{}
+}
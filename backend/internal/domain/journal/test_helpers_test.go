@@ -11,6 +11,11 @@ import (
 	"gorm.io/gorm"
 )
 
+var testJournalConfig = journal.Config{
+	AttachmentMaxSize: 1024,
+	AttachmentQuota:   2048,
+}
+
 type journalTestEnvironment struct {
 	database   *gorm.DB
 	repository *journal.Repository
@@ -26,7 +31,7 @@ func newJournalTestEnvironment(t *testing.T) *journalTestEnvironment {
 	return &journalTestEnvironment{
 		database:   database,
 		repository: repository,
-		service:    journal.NewService(repository),
+		service:    journal.NewService(repository, journal.NewLocalStorage(t.TempDir()), testJournalConfig),
 	}
 }
 
@@ -1,15 +1,19 @@
 package journal
 
 import (
+	"strings"
 	"time"
 
+	"github.com/azaviyalov/null3/backend/internal/core"
+
 	"gorm.io/gorm"
 )
 
 type MoodEditRecordRequest struct {
-	Feeling string `json:"feeling" validate:"required"`
-	Emoji   string `json:"emoji,omitempty"`
-	Note    string `json:"note,omitempty"`
+	Feeling string   `json:"feeling" validate:"required"`
+	Emoji   string   `json:"emoji,omitempty"`
+	Note    string   `json:"note,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
 }
 
 type MoodRecordResponse struct {
@@ -21,6 +25,7 @@ type MoodRecordResponse struct {
 	CreatedAt       time.Time                `json:"created_at"`
 	UpdatedAt       time.Time                `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt           `json:"deleted_at"`
+	Tags            []Tag                    `json:"tags,omitempty"`
 	DiaryEntryLinks []DiaryEntryLinkResponse `json:"diary_entry_links,omitempty"`
 }
 
@@ -34,6 +39,7 @@ func NewMoodRecordResponse(entry *MoodRecord) MoodRecordResponse {
 		CreatedAt:       entry.CreatedAt,
 		UpdatedAt:       entry.UpdatedAt,
 		DeletedAt:       entry.DeletedAt,
+		Tags:            entry.Tags,
 		DiaryEntryLinks: NewDiaryEntryLinkResponses(entry.DiaryEntries),
 	}
 }
@@ -71,19 +77,25 @@ type DiaryEditEntryRequest struct {
 	Title      string     `json:"title,omitempty"`
 	Markdown   string     `json:"markdown" validate:"required"`
 	OccurredAt *time.Time `json:"occurred_at" validate:"required"`
+	Tags       []string   `json:"tags,omitempty"`
 }
 
 type DiaryEntryResponse struct {
-	ID                    uint                 `json:"id"`
-	UserID                uint                 `json:"user_id"`
-	Title                 string               `json:"title,omitempty"`
-	Markdown              string               `json:"markdown"`
-	Preview               string               `json:"preview,omitempty"`
-	OccurredAt            time.Time            `json:"occurred_at"`
-	CreatedAt             time.Time            `json:"created_at"`
-	UpdatedAt             time.Time            `json:"updated_at"`
-	DeletedAt             gorm.DeletedAt       `json:"deleted_at"`
-	ReferencedMoodRecords []MoodRecordResponse `json:"referenced_mood_records,omitempty"`
+	ID                    uint                     `json:"id"`
+	UserID                uint                     `json:"user_id"`
+	Title                 string                   `json:"title,omitempty"`
+	Markdown              string                   `json:"markdown"`
+	Preview               string                   `json:"preview,omitempty"`
+	HTML                  string                   `json:"html,omitempty"`
+	OccurredAt            time.Time                `json:"occurred_at"`
+	CreatedAt             time.Time                `json:"created_at"`
+	UpdatedAt             time.Time                `json:"updated_at"`
+	DeletedAt             gorm.DeletedAt           `json:"deleted_at"`
+	Tags                  []Tag                    `json:"tags,omitempty"`
+	ReferencedMoodRecords []MoodRecordResponse     `json:"referenced_mood_records,omitempty"`
+	Links                 []DiaryEntryLinkResponse `json:"links,omitempty"`
+	Backlinks             []DiaryEntryLinkResponse `json:"backlinks,omitempty"`
+	Attachments           []Attachment             `json:"attachments,omitempty"`
 }
 
 func NewDiaryEntryResponse(entry *DiaryEntry) DiaryEntryResponse {
@@ -97,7 +109,11 @@ func NewDiaryEntryResponse(entry *DiaryEntry) DiaryEntryResponse {
 		CreatedAt:             entry.CreatedAt,
 		UpdatedAt:             entry.UpdatedAt,
 		DeletedAt:             entry.DeletedAt,
+		Tags:                  entry.Tags,
 		ReferencedMoodRecords: NewReferencedMoodRecordResponses(entry.MoodRecords),
+		Links:                 NewDiaryEntryLinkResponses(entry.Links),
+		Backlinks:             NewDiaryEntryLinkResponses(entry.Backlinks),
+		Attachments:           entry.Attachments,
 	}
 }
 
@@ -122,3 +138,86 @@ func NewReferencedMoodRecordResponses(entries []MoodRecord) []MoodRecordResponse
 
 	return result
 }
+
+type TagEditRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type TagMergeRequest struct {
+	TargetID uint `json:"target_id" validate:"required"`
+}
+
+type TrashPurgeResponse struct {
+	Purged int64 `json:"purged"`
+}
+
+type SearchTextFragment struct {
+	Text        string `json:"text"`
+	Highlighted bool   `json:"highlighted,omitempty"`
+}
+
+type SearchResultResponse struct {
+	Type       SearchRecordType     `json:"type"`
+	ID         uint                 `json:"id"`
+	Title      []SearchTextFragment `json:"title,omitempty"`
+	Snippet    []SearchTextFragment `json:"snippet,omitempty"`
+	Emoji      string               `json:"emoji,omitempty"`
+	Score      float64              `json:"score"`
+	OccurredAt time.Time            `json:"occurred_at"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt       `json:"deleted_at"`
+}
+
+func NewSearchResultPageResponse(page core.Page[SearchResult]) core.Page[SearchResultResponse] {
+	items := make([]SearchResultResponse, 0, len(page.Items))
+	for _, result := range page.Items {
+		item := SearchResultResponse{
+			Type:    result.RecordType,
+			ID:      result.RecordID,
+			Title:   newSearchTextFragments(result.Title),
+			Snippet: newSearchTextFragments(result.Snippet),
+			Score:   -result.Score,
+		}
+		switch {
+		case result.MoodRecord != nil:
+			item.Emoji = result.MoodRecord.Emoji
+			item.OccurredAt = result.MoodRecord.CreatedAt
+			item.CreatedAt = result.MoodRecord.CreatedAt
+			item.UpdatedAt = result.MoodRecord.UpdatedAt
+			item.DeletedAt = result.MoodRecord.DeletedAt
+		case result.DiaryEntry != nil:
+			item.OccurredAt = result.DiaryEntry.OccurredAt
+			item.CreatedAt = result.DiaryEntry.CreatedAt
+			item.UpdatedAt = result.DiaryEntry.UpdatedAt
+			item.DeletedAt = result.DiaryEntry.DeletedAt
+		}
+		items = append(items, item)
+	}
+	return core.Page[SearchResultResponse]{Items: items, TotalCount: page.TotalCount}
+}
+
+func newSearchTextFragments(marked string) []SearchTextFragment {
+	var fragments []SearchTextFragment
+	for marked != "" {
+		start := strings.Index(marked, searchHighlightStart)
+		if start < 0 {
+			fragments = append(fragments, SearchTextFragment{Text: marked})
+			break
+		}
+		if start > 0 {
+			fragments = append(fragments, SearchTextFragment{Text: marked[:start]})
+		}
+		marked = marked[start+len(searchHighlightStart):]
+
+		end := strings.Index(marked, searchHighlightEnd)
+		if end < 0 {
+			end = len(marked)
+		}
+		if end > 0 {
+			fragments = append(fragments, SearchTextFragment{Text: marked[:end], Highlighted: true})
+		}
+		marked = strings.TrimPrefix(marked[end:], searchHighlightEnd)
+	}
+	return fragments
+}
@@ -0,0 +1,113 @@
+package journal
+
+import (
+	"context"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/logging"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
+)
+
+const trashExpiryInterval = time.Hour
+
+type TrashPurge struct {
+	MoodRecords  int64 `json:"mood_records"`
+	DiaryEntries int64 `json:"diary_entries"`
+}
+
+func (s *Service) PurgeMoodRecord(ctx context.Context, userID, id uint) (*MoodRecord, error) {
+	ctx, span := tracing.Start(ctx, "journal.PurgeMoodRecord")
+	defer span.End()
+
+	filter := NewMoodRecordFilter().WithUserID(userID).WithID(id).WithDeletedMode(core.DeletedModeAll)
+	entry, err := s.repo.GetMoodRecord(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.PurgeMoodRecords(ctx, filter); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *Service) PurgeDiaryEntry(ctx context.Context, userID, id uint) (*DiaryEntry, error) {
+	ctx, span := tracing.Start(ctx, "journal.PurgeDiaryEntry")
+	defer span.End()
+
+	filter := NewDiaryEntryFilter().WithUserID(userID).WithID(id).WithDeletedMode(core.DeletedModeAll)
+	entry, err := s.repo.GetDiaryEntry(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.purgeDiaryEntries(ctx, filter); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *Service) EmptyMoodRecordTrash(ctx context.Context, userID uint) (int64, error) {
+	ctx, span := tracing.Start(ctx, "journal.EmptyMoodRecordTrash")
+	defer span.End()
+
+	filter := NewMoodRecordFilter().WithUserID(userID).WithDeletedMode(core.DeletedModeDeletedOnly)
+	return s.repo.PurgeMoodRecords(ctx, filter)
+}
+
+func (s *Service) EmptyDiaryEntryTrash(ctx context.Context, userID uint) (int64, error) {
+	ctx, span := tracing.Start(ctx, "journal.EmptyDiaryEntryTrash")
+	defer span.End()
+
+	filter := NewDiaryEntryFilter().WithUserID(userID).WithDeletedMode(core.DeletedModeDeletedOnly)
+	return s.purgeDiaryEntries(ctx, filter)
+}
+
+func (s *Service) PurgeExpiredTrash(ctx context.Context, deletedBefore time.Time) (*TrashPurge, error) {
+	ctx, span := tracing.Start(ctx, "journal.PurgeExpiredTrash")
+	defer span.End()
+
+	diaryFilter := NewDiaryEntryFilter().WithDeletedMode(core.DeletedModeDeletedOnly).WithDeletedBefore(deletedBefore)
+	diaryEntries, err := s.purgeDiaryEntries(ctx, diaryFilter)
+	if err != nil {
+		return nil, err
+	}
+	moodFilter := NewMoodRecordFilter().WithDeletedMode(core.DeletedModeDeletedOnly).WithDeletedBefore(deletedBefore)
+	moodRecords, err := s.repo.PurgeMoodRecords(ctx, moodFilter)
+	if err != nil {
+		return nil, err
+	}
+	return &TrashPurge{MoodRecords: moodRecords, DiaryEntries: diaryEntries}, nil
+}
+
+func (s *Service) RunTrashExpiry(ctx context.Context) {
+	ticker := time.NewTicker(trashExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.PurgeExpiredTrash(ctx, time.Now().Add(-s.config.TrashRetention))
+		switch {
+		case err != nil && ctx.Err() == nil:
+			logging.FromContext(ctx).Error("failed to purge expired trash", "error", err)
+		case err == nil && purged.MoodRecords+purged.DiaryEntries > 0:
+			logging.FromContext(ctx).Info("purged expired trash", "mood_records", purged.MoodRecords, "diary_entries", purged.DiaryEntries)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) purgeDiaryEntries(ctx context.Context, filter *DiaryEntryFilter) (int64, error) {
+	ids, attachments, err := s.repo.PurgeDiaryEntries(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	s.renderedHTML.forget(ids)
+	for _, attachment := range attachments {
+		s.deleteStoredFile(ctx, attachment.StorageKey)
+	}
+	return int64(len(ids)), nil
+}
@@ -0,0 +1,181 @@
+package journal_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/domain/journal"
+	"github.com/azaviyalov/null3/backend/internal/testutil"
+)
+
+func TestServicePurgeExpiredTrash(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	ctx := t.Context()
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	mood, err := environment.service.CreateMoodRecord(ctx, owner.ID, journal.MoodEditRecordRequest{Feeling: "calm", Tags: []string{"rest"}})
+	if err != nil {
+		t.Fatalf("create mood record: %v", err)
+	}
+	expired, err := environment.service.CreateDiaryEntry(ctx, owner.ID, diaryRequest(fmt.Sprintf("#rest after [[mood:%d]]", mood.ID), &occurredAt))
+	if err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+	photo, err := environment.service.CreateAttachment(ctx, owner.ID, expired.ID, "lake.png", int64(len(pngHeader)), strings.NewReader(string(pngHeader)))
+	if err != nil {
+		t.Fatalf("create attachment: %v", err)
+	}
+	linking, err := environment.service.CreateDiaryEntry(ctx, owner.ID, diaryRequest(fmt.Sprintf("see [[diary:%d]]", expired.ID), &occurredAt))
+	if err != nil {
+		t.Fatalf("create linking diary entry: %v", err)
+	}
+	recent, err := environment.service.CreateDiaryEntry(ctx, owner.ID, diaryRequest("recently deleted", &occurredAt))
+	if err != nil {
+		t.Fatalf("create recent diary entry: %v", err)
+	}
+	for _, id := range []uint{expired.ID, recent.ID} {
+		if _, err := environment.service.DeleteDiaryEntry(ctx, owner.ID, id); err != nil {
+			t.Fatalf("delete diary entry %d: %v", id, err)
+		}
+	}
+	if _, err := environment.service.DeleteMoodRecord(ctx, owner.ID, mood.ID); err != nil {
+		t.Fatalf("delete mood record: %v", err)
+	}
+	longAgo := time.Now().Add(-48 * time.Hour)
+	if err := environment.database.Exec("UPDATE diary_entries SET deleted_at = ? WHERE id = ?", longAgo, expired.ID).Error; err != nil {
+		t.Fatalf("age diary entry: %v", err)
+	}
+	if err := environment.database.Exec("UPDATE mood_records SET deleted_at = ? WHERE id = ?", longAgo, mood.ID).Error; err != nil {
+		t.Fatalf("age mood record: %v", err)
+	}
+
+	purged, err := environment.service.PurgeExpiredTrash(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeExpiredTrash() error = %v", err)
+	}
+	if purged.MoodRecords != 1 || purged.DiaryEntries != 1 {
+		t.Fatalf("PurgeExpiredTrash() = %+v, want one record of each type", purged)
+	}
+	if _, err := environment.service.GetDiaryEntry(ctx, owner.ID, expired.ID); !errors.Is(err, core.ErrItemNotFound) {
+		t.Fatalf("GetDiaryEntry() after purge error = %v, want ErrItemNotFound", err)
+	}
+	if _, err := environment.service.GetDiaryEntry(ctx, owner.ID, recent.ID); err != nil {
+		t.Fatalf("GetDiaryEntry() for recently deleted entry error = %v", err)
+	}
+	if _, _, err := environment.service.OpenAttachment(ctx, owner.ID, photo.ID); !errors.Is(err, core.ErrItemNotFound) {
+		t.Fatalf("OpenAttachment() after purge error = %v, want ErrItemNotFound", err)
+	}
+	for _, table := range []string{"mood_record_diary_entries", "mood_record_tags", "diary_entry_tags", "diary_entry_links", "attachments"} {
+		assertTableRows(t, environment, table, 0)
+	}
+	var revisions int64
+	if err := environment.database.Table("diary_entry_revisions").Where("diary_entry_id = ?", expired.ID).Count(&revisions).Error; err != nil || revisions != 0 {
+		t.Fatalf("revisions after purge = %d, %v, want none", revisions, err)
+	}
+	var indexed int64
+	err = environment.database.Table("journal_search").
+		Where("(record_type = ? AND record_id = ?) OR (record_type = ? AND record_id = ?)", journal.SearchRecordTypeDiaryEntry, expired.ID, journal.SearchRecordTypeMoodRecord, mood.ID).
+		Count(&indexed).Error
+	if err != nil || indexed != 0 {
+		t.Fatalf("search documents after purge = %d, %v, want none", indexed, err)
+	}
+	loaded, err := environment.service.GetDiaryEntry(ctx, owner.ID, linking.ID)
+	if err != nil || len(loaded.Links) != 0 {
+		t.Fatalf("linking entry after purge = %+v, %v, want no links", loaded, err)
+	}
+}
+
+func TestServiceEmptyTrash(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	other := createJournalUser(t, environment, "other")
+	ctx := t.Context()
+	createdAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	deleted := saveMoodRecord(t, environment, owner.ID, "tired", createdAt)
+	kept := saveMoodRecord(t, environment, owner.ID, "calm", createdAt)
+	foreign := saveMoodRecord(t, environment, other.ID, "busy", createdAt)
+	for _, record := range []*journal.MoodRecord{deleted, foreign} {
+		if _, err := environment.service.DeleteMoodRecord(ctx, record.UserID, record.ID); err != nil {
+			t.Fatalf("delete mood record: %v", err)
+		}
+	}
+
+	purged, err := environment.service.EmptyMoodRecordTrash(ctx, owner.ID)
+	if err != nil || purged != 1 {
+		t.Fatalf("EmptyMoodRecordTrash() = %d, %v, want 1", purged, err)
+	}
+	if _, err := environment.service.GetMoodRecord(ctx, other.ID, foreign.ID); err != nil {
+		t.Fatalf("other user's trash was touched: %v", err)
+	}
+	if _, err := environment.service.PurgeMoodRecord(ctx, owner.ID, kept.ID); err != nil {
+		t.Fatalf("PurgeMoodRecord() on an active record error = %v", err)
+	}
+	assertMoodRecordCount(t, environment, owner.ID, 0)
+	if purged, err := environment.service.EmptyDiaryEntryTrash(ctx, owner.ID); err != nil || purged != 0 {
+		t.Fatalf("EmptyDiaryEntryTrash() = %d, %v, want nothing to purge", purged, err)
+	}
+}
+
+func TestTrashHTTPContract(t *testing.T) {
+	testutil.SkipIntegration(t)
+	environment := newJournalTestEnvironment(t)
+	owner := createJournalUser(t, environment, "owner")
+	e, tokenService := newJournalTestServer(t, environment)
+	ownerCookie := journalUserCookie(t, tokenService, owner.ID)
+	occurredAt := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	record := saveMoodRecord(t, environment, owner.ID, "calm", occurredAt)
+	entry, err := environment.service.CreateDiaryEntry(t.Context(), owner.ID, diaryRequest("gone", &occurredAt))
+	if err != nil {
+		t.Fatalf("create diary entry: %v", err)
+	}
+	if _, err := environment.service.DeleteDiaryEntry(t.Context(), owner.ID, entry.ID); err != nil {
+		t.Fatalf("delete diary entry: %v", err)
+	}
+	recordPath := fmt.Sprintf("/api/journal/mood-records/%d", record.ID)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{method: http.MethodDelete, path: recordPath + "?permanent=maybe", want: http.StatusBadRequest},
+		{method: http.MethodDelete, path: recordPath + "?permanent=true", want: http.StatusOK},
+		{method: http.MethodGet, path: recordPath, want: http.StatusNotFound},
+		{method: http.MethodDelete, path: recordPath + "?permanent=true", want: http.StatusNotFound},
+		{method: http.MethodDelete, path: "/api/journal/mood-records/trash", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		response := serveJournalJSON(t, e, tt.method, tt.path, nil, ownerCookie)
+		if response.Code != tt.want {
+			t.Fatalf("%s %s status = %d, want %d", tt.method, tt.path, response.Code, tt.want)
+		}
+	}
+
+	response := serveJournalJSON(t, e, http.MethodDelete, "/api/journal/diary-entries/trash", nil, ownerCookie)
+	var purged journal.TrashPurgeResponse
+	decodeJournalResponse(t, response, &purged)
+	if response.Code != http.StatusOK || purged.Purged != 1 {
+		t.Fatalf("DELETE /api/journal/diary-entries/trash = %d %+v, want one purged entry", response.Code, purged)
+	}
+}
+
+func assertTableRows(t *testing.T, environment *journalTestEnvironment, table string, want int64) {
+	t.Helper()
+
+	var count int64
+	if err := environment.database.Table(table).Count(&count).Error; err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	if count != want {
+		t.Fatalf("%s rows = %d, want %d", table, count, want)
+	}
+}
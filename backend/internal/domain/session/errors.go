@@ -1,6 +1,9 @@
 package session
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrJWTGenerationFailed        = errors.New("failed to generate JWT")
@@ -10,3 +13,5 @@ var (
 	ErrRefreshTokenInvalid        = errors.New("invalid refresh token")
 	ErrRefreshTokenCreationFailed = errors.New("failed to create refresh token")
 )
+
+var ErrRefreshTokenReused = fmt.Errorf("%w: refresh token was already used", ErrRefreshTokenInvalid)
@@ -3,6 +3,7 @@ package session
 import (
 	"context"
 
+	"github.com/azaviyalov/null3/backend/internal/core/logging"
 	"github.com/labstack/echo/v4"
 )
 
@@ -21,6 +22,7 @@ func UserJWTMiddleware(service *Service, validateUser func(context.Context, uint
 				return echo.ErrUnauthorized.WithInternal(err)
 			}
 			setUserID(c, userID)
+			logging.AddRequestAttrs(c, "user_id", userID)
 			return next(c)
 		}
 	}
@@ -1,19 +1,49 @@
 package session_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/azaviyalov/null3/backend/internal/core/logging"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"github.com/labstack/echo/v4"
 )
 
+func TestUserJWTMiddlewareAddsUserToRequestLogger(t *testing.T) {
+	output := &bytes.Buffer{}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(output, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	service := session.NewService(nil, nil, session.Config{JWTSecret: testJWTSecret, JWTExpiration: time.Hour})
+	token, err := service.GenerateUserAccessToken(42)
+	if err != nil {
+		t.Fatalf("GenerateUserAccessToken() error = %v", err)
+	}
+	e := echo.New()
+	e.GET("/private", func(c echo.Context) error {
+		logging.FromContext(c.Request().Context()).Warn("handler warning")
+		return c.NoContent(http.StatusNoContent)
+	}, session.UserJWTMiddleware(service, acceptUser))
+	request := httptest.NewRequest(http.MethodGet, "/private", nil)
+	request.AddCookie(&http.Cookie{Name: session.UserCookieName, Value: token})
+
+	e.ServeHTTP(httptest.NewRecorder(), request)
+
+	if !strings.Contains(output.String(), "user_id=42") {
+		t.Fatalf("log output = %q, want user_id=42", output.String())
+	}
+}
+
 func TestUserJWTMiddleware(t *testing.T) {
-	service := session.NewService(nil, session.Config{JWTSecret: testJWTSecret, JWTExpiration: time.Hour})
+	service := session.NewService(nil, nil, session.Config{JWTSecret: testJWTSecret, JWTExpiration: time.Hour})
 	userToken, err := service.GenerateUserAccessToken(42)
 	if err != nil {
 		t.Fatalf("GenerateUserAccessToken() error = %v", err)
@@ -67,7 +97,7 @@ func TestUserJWTMiddleware(t *testing.T) {
 }
 
 func TestAdminJWTMiddleware(t *testing.T) {
-	service := session.NewService(nil, session.Config{JWTSecret: testJWTSecret, JWTExpiration: time.Hour})
+	service := session.NewService(nil, nil, session.Config{JWTSecret: testJWTSecret, JWTExpiration: time.Hour})
 	adminToken, err := service.GenerateAdminAccessToken(time.Hour)
 	if err != nil {
 		t.Fatalf("GenerateAdminAccessToken() error = %v", err)
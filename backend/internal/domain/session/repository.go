@@ -37,6 +37,14 @@ func (r *Repository) SaveRefreshToken(ctx context.Context, token *RefreshToken)
 	return token, nil
 }
 
+func (r *Repository) ConsumeRefreshToken(ctx context.Context, token *RefreshToken) (bool, error) {
+	result := r.db.WithContext(ctx).Delete(token)
+	if result.Error != nil {
+		return false, fmt.Errorf("consume refresh token: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
 func (r *Repository) DeleteRefreshToken(ctx context.Context, token *RefreshToken) error {
 	if err := r.db.WithContext(ctx).Delete(token).Error; err != nil {
 		return fmt.Errorf("delete refresh token: %w", err)
@@ -12,12 +12,18 @@ import (
 	"time"
 
 	"github.com/azaviyalov/null3/backend/internal/core"
+	"github.com/azaviyalov/null3/backend/internal/core/logging"
+	"github.com/azaviyalov/null3/backend/internal/core/tracing"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 const (
-	userScope  = "user"
-	adminScope = "admin"
+	userScope         = "user"
+	adminScope        = "admin"
+	secondFactorScope = "second_factor"
+
+	secondFactorTokenTTL = 5 * time.Minute
 )
 
 type accessTokenClaims struct {
@@ -27,12 +33,14 @@ type accessTokenClaims struct {
 
 type Service struct {
 	repo   *Repository
+	audit  *audit.Service
 	config Config
 }
 
-func NewService(repo *Repository, config Config) *Service {
+func NewService(repo *Repository, auditService *audit.Service, config Config) *Service {
 	return &Service{
 		repo:   repo,
+		audit:  auditService,
 		config: config,
 	}
 }
@@ -45,6 +53,15 @@ func (s *Service) GenerateAdminAccessToken(expiration time.Duration) (string, er
 	return s.generateAccessToken("admin", adminScope, expiration)
 }
 
+func (s *Service) GenerateSecondFactorToken(userID uint) (string, time.Time, error) {
+	expiresAt := time.Now().Add(secondFactorTokenTTL)
+	token, err := s.generateAccessToken(strconv.FormatUint(uint64(userID), 10), secondFactorScope, secondFactorTokenTTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
 func (s *Service) generateAccessToken(subject, scope string, expiration time.Duration) (string, error) {
 	now := time.Now()
 	tokenClaims := accessTokenClaims{
@@ -87,12 +104,20 @@ func (s *Service) parseAccessTokenClaims(tokenStr string) (*accessTokenClaims, e
 }
 
 func (s *Service) ParseUserAccessToken(tokenStr string) (uint, error) {
+	return s.parseUserToken(tokenStr, userScope)
+}
+
+func (s *Service) ParseSecondFactorToken(tokenStr string) (uint, error) {
+	return s.parseUserToken(tokenStr, secondFactorScope)
+}
+
+func (s *Service) parseUserToken(tokenStr, scope string) (uint, error) {
 	tokenClaims, err := s.parseAccessTokenClaims(tokenStr)
 	if err != nil {
 		return 0, err
 	}
-	if tokenClaims.Scope != userScope {
-		return 0, fmt.Errorf("%w: user scope required", ErrJWTInvalidClaims)
+	if tokenClaims.Scope != scope {
+		return 0, fmt.Errorf("%w: %s scope required", ErrJWTInvalidClaims, scope)
 	}
 	userID, err := strconv.ParseUint(tokenClaims.Subject, 10, 64)
 	if err != nil {
@@ -117,10 +142,16 @@ func (s *Service) ValidateAdminAccessToken(tokenStr string) error {
 }
 
 func (s *Service) CreateRefreshToken(ctx context.Context, userID uint) (*RefreshToken, error) {
+	ctx, span := tracing.Start(ctx, "session.CreateRefreshToken")
+	defer span.End()
+
 	return s.CreateRefreshTokenWithRepo(ctx, s.repo, userID)
 }
 
 func (s *Service) CreateRefreshTokenWithRepo(ctx context.Context, repo *Repository, userID uint) (*RefreshToken, error) {
+	ctx, span := tracing.Start(ctx, "session.CreateRefreshTokenWithRepo")
+	defer span.End()
+
 	now := time.Now()
 
 	tokenString, err := generateRandomToken()
@@ -145,6 +176,9 @@ func (s *Service) CreateRefreshTokenWithRepo(ctx context.Context, repo *Reposito
 }
 
 func (s *Service) InvalidateRefreshToken(ctx context.Context, tokenString string) error {
+	ctx, span := tracing.Start(ctx, "session.InvalidateRefreshToken")
+	defer span.End()
+
 	token, err := s.repo.GetRefreshToken(ctx, tokenString)
 	if err != nil {
 		if errors.Is(err, core.ErrItemNotFound) {
@@ -153,10 +187,53 @@ func (s *Service) InvalidateRefreshToken(ctx context.Context, tokenString string
 		return err
 	}
 
-	return s.repo.DeleteRefreshToken(ctx, token)
+	if err := s.repo.DeleteRefreshToken(ctx, token); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, audit.Event{Type: audit.TypeLogout, Actor: audit.ActorUser, UserID: &token.UserID})
+	return nil
+}
+
+func (s *Service) ConsumeRefreshToken(ctx context.Context, tokenString string) (*RefreshToken, error) {
+	ctx, span := tracing.Start(ctx, "session.ConsumeRefreshToken")
+	defer span.End()
+
+	token, err := s.repo.GetRefreshToken(ctx, tokenString)
+	if err != nil {
+		if errors.Is(err, core.ErrItemNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	if token.ExpiresAt.Before(time.Now()) {
+		if err := s.repo.DeleteRefreshToken(ctx, token); err != nil {
+			logging.FromContext(ctx).Warn("failed to delete expired refresh token", "user_id", token.UserID, "error", err)
+		}
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	consumed, err := s.repo.ConsumeRefreshToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		logging.FromContext(ctx).Warn("refresh token reused", "user_id", token.UserID)
+		s.audit.Record(ctx, audit.Event{
+			Type:    audit.TypeRefreshTokenReused,
+			Outcome: audit.OutcomeFailure,
+			Actor:   audit.ActorUser,
+			UserID:  &token.UserID,
+		})
+		return nil, ErrRefreshTokenReused
+	}
+	return token, nil
 }
 
 func (s *Service) DeleteExpiredRefreshTokens(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "session.DeleteExpiredRefreshTokens")
+	defer span.End()
+
 	return s.repo.DeleteExpiredRefreshTokens(ctx)
 }
 
@@ -2,12 +2,16 @@ package session_test
 
 import (
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/azaviyalov/null3/backend/internal/core/server"
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"github.com/azaviyalov/null3/backend/internal/testutil"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 func TestServiceGeneratesScopedAccessTokens(t *testing.T) {
@@ -15,7 +19,7 @@ func TestServiceGeneratesScopedAccessTokens(t *testing.T) {
 		JWTSecret:     testJWTSecret,
 		JWTExpiration: time.Hour,
 	}
-	service := session.NewService(nil, config)
+	service := session.NewService(nil, nil, config)
 
 	tests := []struct {
 		name         string
@@ -78,7 +82,7 @@ func TestServiceGeneratesScopedAccessTokens(t *testing.T) {
 }
 
 func TestServiceValidatesAccessTokenClaims(t *testing.T) {
-	service := session.NewService(nil, session.Config{JWTSecret: testJWTSecret})
+	service := session.NewService(nil, nil, session.Config{JWTSecret: testJWTSecret})
 	now := time.Now()
 	validUserClaims := jwt.MapClaims{
 		"iss":   "null3",
@@ -125,7 +129,7 @@ func TestServiceValidatesAccessTokenClaims(t *testing.T) {
 }
 
 func TestServiceValidatesAdminAccessToken(t *testing.T) {
-	service := session.NewService(nil, session.Config{JWTSecret: testJWTSecret})
+	service := session.NewService(nil, nil, session.Config{JWTSecret: testJWTSecret})
 	now := time.Now()
 	validClaims := jwt.MapClaims{"iss": "null3", "sub": "admin", "scope": "admin", "exp": now.Add(time.Hour).Unix()}
 
@@ -232,3 +236,60 @@ func cloneClaims(claims jwt.MapClaims) jwt.MapClaims {
 	}
 	return copy
 }
+
+func TestServiceConsumeRefreshTokenRecordsConcurrentReuse(t *testing.T) {
+	testutil.SkipIntegration(t)
+	testutil.DiscardLogs(t)
+	environment := newSessionTestEnvironment(t)
+	ctx := server.WithClient(t.Context(), server.Client{IP: "198.51.100.7", UserAgent: "reuse-test"})
+
+	first, err := environment.service.CreateRefreshToken(ctx, 41)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	other, err := environment.service.CreateRefreshToken(ctx, 41)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() second error = %v", err)
+	}
+
+	var interleaved atomic.Bool
+	var concurrentErr error
+	err = environment.database.Callback().Query().After("gorm:query").Register("test:interleave_refresh", func(tx *gorm.DB) {
+		if tx.Statement.Table == "refresh_tokens" && interleaved.CompareAndSwap(false, true) {
+			_, concurrentErr = environment.service.ConsumeRefreshToken(ctx, first.Value)
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	_, err = environment.service.ConsumeRefreshToken(ctx, first.Value)
+	if concurrentErr != nil {
+		t.Fatalf("concurrent ConsumeRefreshToken() error = %v", concurrentErr)
+	}
+	if !errors.Is(err, session.ErrRefreshTokenReused) || !errors.Is(err, session.ErrRefreshTokenInvalid) {
+		t.Fatalf("reused ConsumeRefreshToken() error = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := environment.service.ConsumeRefreshToken(ctx, first.Value); !errors.Is(err, session.ErrRefreshTokenInvalid) || errors.Is(err, session.ErrRefreshTokenReused) {
+		t.Fatalf("consumed token error = %v, want ErrRefreshTokenInvalid", err)
+	}
+	consumed, err := environment.service.ConsumeRefreshToken(ctx, other.Value)
+	if err != nil {
+		t.Fatalf("sibling token after reuse error = %v", err)
+	}
+	if consumed.UserID != 41 {
+		t.Fatalf("ConsumeRefreshToken() = %+v, want token for user 41", consumed)
+	}
+
+	var events []audit.Event
+	if err := environment.database.Where("type = ?", audit.TypeRefreshTokenReused).Find(&events).Error; err != nil {
+		t.Fatalf("list audit events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("reuse audit events = %d, want 1", len(events))
+	}
+	event := events[0]
+	if event.Outcome != audit.OutcomeFailure || event.UserID == nil || *event.UserID != 41 || event.IP != "198.51.100.7" || event.UserAgent != "reuse-test" {
+		t.Errorf("reuse audit event = %+v", event)
+	}
+}
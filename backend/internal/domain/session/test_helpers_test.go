@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/azaviyalov/null3/backend/internal/domain/audit"
 	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"github.com/azaviyalov/null3/backend/internal/testutil"
 	"gorm.io/gorm"
@@ -33,7 +34,7 @@ func newSessionTestEnvironment(t *testing.T) *sessionTestEnvironment {
 	return &sessionTestEnvironment{
 		database:   database,
 		repository: repository,
-		service:    session.NewService(repository, config),
+		service:    session.NewService(repository, audit.NewService(audit.NewRepository(database)), config),
 		config:     config,
 	}
 }
@@ -1,13 +1,14 @@
 package testutil
 
 import (
+	"crypto/rand"
+	"net/url"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/azaviyalov/null3/backend/internal/core/db"
-	"github.com/azaviyalov/null3/backend/internal/domain/account"
-	"github.com/azaviyalov/null3/backend/internal/domain/journal"
-	"github.com/azaviyalov/null3/backend/internal/domain/session"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -15,8 +16,60 @@ import (
 func NewDatabase(t testing.TB, filename string) *gorm.DB {
 	t.Helper()
 
+	filename, params, _ := strings.Cut(filename, "?")
 	databaseURL := "file:" + filepath.Join(t.TempDir(), filename) + "?_fk=1"
-	database, err := db.Connect(db.Config{DatabaseURL: databaseURL})
+	if params != "" {
+		databaseURL += "&" + params
+	}
+	if postgresURL := os.Getenv("TEST_DATABASE_URL"); postgresURL != "" {
+		databaseURL = newPostgresSchema(t, postgresURL)
+	}
+	database := connect(t, databaseURL)
+
+	if err := db.Migrate(t.Context(), database); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	return database
+}
+
+func newPostgresSchema(t testing.TB, postgresURL string) string {
+	t.Helper()
+
+	if dialect, err := db.Dialect(postgresURL); err != nil || dialect != db.DialectPostgres {
+		t.Fatalf("TEST_DATABASE_URL must be a postgres:// URL, got %q", postgresURL)
+	}
+	parsed, err := url.Parse(postgresURL)
+	if err != nil {
+		t.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+
+	schema := "test_" + strings.ToLower(rand.Text())
+	admin := connect(t, postgresURL)
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Errorf("drop test schema: %v", err)
+		}
+	})
+
+	query := parsed.Query()
+	query.Set("search_path", schema)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+func connect(t testing.TB, databaseURL string) *gorm.DB {
+	t.Helper()
+
+	config, err := db.GetConfig()
+	if err != nil {
+		t.Fatalf("get database configuration: %v", err)
+	}
+	config.DatabaseURL = databaseURL
+	database, err := db.Connect(config)
 	if err != nil {
 		t.Fatalf("connect to test database: %v", err)
 	}
@@ -31,17 +84,5 @@ func NewDatabase(t testing.TB, filename string) *gorm.DB {
 			t.Errorf("close SQL database: %v", err)
 		}
 	})
-
-	if err := db.AutoMigrate(database,
-		&journal.MoodRecord{},
-		&journal.DiaryEntry{},
-		&account.User{},
-		&session.RefreshToken{},
-		&account.PasswordResetToken{},
-		&account.Invite{},
-	); err != nil {
-		t.Fatalf("migrate test database: %v", err)
-	}
-
 	return database
 }